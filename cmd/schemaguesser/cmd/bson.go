@@ -33,6 +33,7 @@ var bsonCmd = &cobra.Command{
 			fmt.Println("This command doesn't work with the 'use_dumps' switch. Please remove it.")
 			return
 		}
+		initQueryOptionsOrPanic()
 		client, err := mongoHelper.Connect(mongoHelper.ConStr)
 		if err != nil {
 			msg := fmt.Sprintf("Failed to connect to db: %v", err)
@@ -52,6 +53,10 @@ var bsonCmd = &cobra.Command{
 	},
 }
 
+func init() {
+	addQueryFlags(bsonCmd)
+}
+
 func bsonForOneCollection(client *mongo.Client, dbName string, collName string, doRecover bool, initProgressBar bool) {
 	defer func() {
 		if doRecover {
@@ -75,7 +80,7 @@ func bsonForOneCollection(client *mongo.Client, dbName string, collName string,
 		ctx = context.Background()
 	}
 
-	if dumpCount, err := mongoHelper.DumpCollectionToFile(ctx, outputFile, client, dbName, collName, itemCount, useAggregation, mongoV44); err != nil {
+	if dumpCount, err := mongoHelper.DumpCollectionToFile(ctx, outputFile, client, dbName, collName, itemCount, useAggregation, mongoV44, queryOptions); err != nil {
 		panic(err)
 	} else {
 		var timeoutInfo *meta.TimeoutInfo
@@ -119,7 +124,7 @@ func bsonForOneCollection_old(client *mongo.Client, dbName string, collName stri
 
 	startTime := time.Now()
 
-	err = mongoHelper.QueryCollection(client, dbName, collName, int(itemCount), useAggregation, mongoV44, func(data bson.Raw) error {
+	err = mongoHelper.QueryCollection(client, dbName, collName, int(itemCount), useAggregation, mongoV44, queryOptions, func(data bson.Raw) error {
 		utils.DumpBsonCollectionData(data, outputFile)
 		utils.DumpBsonCollectionData([]byte("\n"), outputFile)
 		return nil // TODO
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	linkshelper "okieoth/schemaguesser/internal/pkg/linksHelper"
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+	"okieoth/schemaguesser/internal/pkg/schema"
+
+	"github.com/spf13/cobra"
+)
+
+var dictionaryFormat string
+var withIndexes bool
+
+var dictionaryCmd = &cobra.Command{
+	Use:   "dictionary",
+	Short: "creates a browsable data dictionary out of before persisted schemas and links",
+	Long:  "With this command you can create a data dictionary with one page per collection and an index page, in markdown or html. The collections are read from the 'schema-raw.json' files of 'get schema --print_raw_schema_base' and the references from the 'db_references.json' of 'get links'. Only for the indexes a database connection is needed",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := schema.CheckDictionaryFormat(dictionaryFormat); err != nil {
+			panic(err)
+		}
+		schemas, err := schema.LoadPersistedSchemas(schemaDir)
+		if err != nil {
+			panic(err)
+		}
+		schemas = slices.DeleteFunc(schemas, func(s schema.PersistedSchema) bool {
+			return slices.Contains(blacklist, s.Database) || slices.Contains(blacklist, s.Collection) ||
+				((databaseName != "all") && (databaseName != s.Database)) ||
+				((collectionName != "all") && (collectionName != s.Collection))
+		})
+		colRefs := make([]linkshelper.ColRefs, 0)
+		if linksFile != "" {
+			colRefs, err = linkshelper.LoadColRefs(linksFile)
+			if err != nil {
+				panic(err)
+			}
+		}
+		if len(schemas) == 0 {
+			log.Printf("No schema files found in: %s\n", schemaDir)
+			return
+		}
+
+		indexes := make(map[string][]string)
+		if withIndexes {
+			client, err := mongoHelper.Connect(mongoHelper.ConStr)
+			if err != nil {
+				msg := fmt.Sprintf("Failed to connect to db: %v", err)
+				panic(msg)
+			}
+			defer mongoHelper.CloseConnection(client)
+			for _, s := range schemas {
+				collIndexes, err := mongoHelper.ListIndexes(client, s.Database, s.Collection)
+				if err != nil {
+					log.Printf("[%s:%s] Error while reading indexes: %v\n", s.Database, s.Collection, err)
+					continue
+				}
+				indexes[s.Database+"."+s.Collection] = collIndexes
+			}
+		}
+
+		collections := schema.NewDictCollections(dictionaryFormat, schemas, indexes, colRefs)
+		for _, c := range collections {
+			if err := schema.WriteDictionaryPage(dictionaryFormat, c, outputDir); err != nil {
+				log.Printf("[%s:%s] error while writing the dictionary page: %v\n", c.Database, c.Collection, err)
+			}
+		}
+		input := schema.NewDictIndexInput("Data dictionary", collections)
+		if err := schema.WriteDictionaryIndex(dictionaryFormat, &input, outputDir); err != nil {
+			log.Printf("Error while writing the dictionary index: %v\n", err)
+			return
+		}
+		log.Printf("Data dictionary with %d collections of %d databases created\n", len(collections), len(input.Databases))
+	},
+}
+
+func init() {
+	dictionaryCmd.Flags().StringVar(&schemaDir, "schema_dir", "", "Directory with the 'schema-raw.json' files, that were created with 'get schema --print_raw_schema_base'")
+	dictionaryCmd.Flags().StringVar(&linksFile, "links_file", "", "Optional 'db_references.json' file, that was created by 'get links'. Without it the pages contain no references")
+	dictionaryCmd.Flags().StringVar(&dictionaryFormat, "dict_format", schema.DICT_FORMAT_MARKDOWN, fmt.Sprintf("Format of the data dictionary, possible values: %v", schema.DictionaryFormats))
+	dictionaryCmd.Flags().BoolVar(&withIndexes, "with_indexes", false, "If set, the indexes of the collections are read from the database")
+	dictionaryCmd.MarkFlagRequired("schema_dir")
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"okieoth/schemaguesser/internal/pkg/schema"
+
+	"github.com/spf13/cobra"
+)
+
+var diffFormat string
+var diffOutput string
+var diffBlacklist []string
+var failOnChanges bool
+
+var diffCmd = &cobra.Command{
+	Use:   "diff old_dir new_dir",
+	Short: "compares the persisted schemas of two runs",
+	Long:  "Loads the 'schema-raw.json' files of two runs of 'get schema --print_raw_schema_base' and reports added and removed collections, added and removed attributes, type changes and presence changes. No database connection is needed",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := schema.CheckDiffFormat(diffFormat); err != nil {
+			panic(err)
+		}
+		oldSchemas := loadSchemasForDiffOrPanic(args[0])
+		newSchemas := loadSchemasForDiffOrPanic(args[1])
+		diff := schema.NewSchemaDiff(oldSchemas, newSchemas)
+
+		var report string
+		if diffFormat == schema.DIFF_FORMAT_JSON {
+			var err error
+			if report, err = diff.Json(); err != nil {
+				panic(err)
+			}
+		} else {
+			report = diff.Text()
+		}
+		if diffOutput == "stdout" {
+			fmt.Print(report)
+		} else {
+			if err := os.WriteFile(diffOutput, []byte(report), 0644); err != nil {
+				panic(fmt.Sprintf("Error while writing the diff to %s: %v", diffOutput, err))
+			}
+		}
+		if failOnChanges && diff.HasChanges() {
+			os.Exit(1)
+		}
+	},
+}
+
+func loadSchemasForDiffOrPanic(schemaDir string) []schema.PersistedSchema {
+	if _, err := os.Stat(schemaDir); err != nil {
+		panic(fmt.Sprintf("Can't access schema dir: %v", err))
+	}
+	schemas, err := schema.LoadPersistedSchemas(schemaDir)
+	if err != nil {
+		panic(err)
+	}
+	if len(schemas) == 0 {
+		panic(fmt.Sprintf("No schema files found in: %s", filepath.Clean(schemaDir)))
+	}
+	return slices.DeleteFunc(schemas, func(s schema.PersistedSchema) bool {
+		return slices.Contains(diffBlacklist, s.Database) || slices.Contains(diffBlacklist, s.Collection)
+	})
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffFormat, "diff_format", schema.DIFF_FORMAT_TEXT, fmt.Sprintf("Format of the report, possible values: %v", schema.DiffFormats))
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", "stdout", "File to write the report to")
+	diffCmd.Flags().StringSliceVarP(&diffBlacklist, "blacklist", "b", []string{}, "Blacklist names of databases and collections to skip")
+	diffCmd.Flags().Float64Var(&schema.DiffPresenceThreshold, "presence_threshold", schema.DiffPresenceThreshold, "Changes of the presence ratio of attributes are only reported, if they are greater than this value")
+	diffCmd.Flags().BoolVar(&failOnChanges, "fail_on_changes", false, "If set, the program exits with code 1 when differences were found")
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	linkshelper "okieoth/schemaguesser/internal/pkg/linksHelper"
+	"okieoth/schemaguesser/internal/pkg/schema"
+
+	"github.com/spf13/cobra"
+)
+
+var schemaDir string
+var linksFile string
+var erDiagramFormat string
+var acrossDatabases bool
+
+var erCmd = &cobra.Command{
+	Use:   "er",
+	Short: "creates entity-relationship diagrams out of before persisted schemas and links",
+	Long:  "With this command you can create one entity-relationship diagram per database, or one over all databases. The collections are read from the 'schema-raw.json' files of 'get schema --print_raw_schema_base' and the relations from the 'db_references.json' of 'get links'. No database connection is needed",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := schema.CheckErDiagramFormat(erDiagramFormat); err != nil {
+			panic(err)
+		}
+		schemas, err := schema.LoadPersistedSchemas(schemaDir)
+		if err != nil {
+			panic(err)
+		}
+		schemas = slices.DeleteFunc(schemas, func(s schema.PersistedSchema) bool {
+			return slices.Contains(blacklist, s.Database) || slices.Contains(blacklist, s.Collection)
+		})
+		colRefs := make([]linkshelper.ColRefs, 0)
+		if linksFile != "" {
+			colRefs, err = linkshelper.LoadColRefs(linksFile)
+			if err != nil {
+				panic(err)
+			}
+		}
+		if len(schemas) == 0 {
+			log.Printf("No schema files found in: %s\n", schemaDir)
+			return
+		}
+
+		if acrossDatabases {
+			title := "Collections of all databases"
+			input := schema.NewErDiagramInput(title, "", schemas, colRefs)
+			if err := schema.WriteErDiagram(erDiagramFormat, "all", &input, outputDir); err != nil {
+				log.Printf("Error while writing the ER diagram: %v\n", err)
+			}
+			return
+		}
+		databases := make([]string, 0)
+		for _, s := range schemas {
+			if !slices.Contains(databases, s.Database) && ((databaseName == "all") || (databaseName == s.Database)) {
+				databases = append(databases, s.Database)
+			}
+		}
+		for _, db := range databases {
+			dbSchemas := slices.DeleteFunc(slices.Clone(schemas), func(s schema.PersistedSchema) bool {
+				return s.Database != db
+			})
+			title := fmt.Sprintf("Collections of database: %s", db)
+			input := schema.NewErDiagramInput(title, db, dbSchemas, colRefs)
+			if err := schema.WriteErDiagram(erDiagramFormat, db, &input, outputDir); err != nil {
+				log.Printf("[%s] error while writing the ER diagram: %v\n", db, err)
+				continue
+			}
+			log.Printf("[%s] ER diagram with %d collections and %d relations created\n", db, len(input.Entities), len(input.Relations))
+		}
+	},
+}
+
+func init() {
+	erCmd.Flags().StringVar(&schemaDir, "schema_dir", "", "Directory with the 'schema-raw.json' files, that were created with 'get schema --print_raw_schema_base'")
+	erCmd.Flags().StringVar(&linksFile, "links_file", "", "Optional 'db_references.json' file, that was created by 'get links'. Without it the diagrams contain no relations")
+	erCmd.Flags().StringVar(&erDiagramFormat, "diagram_format", schema.ER_FORMAT_PUML, fmt.Sprintf("Format of the diagrams, possible values: %v", schema.ErDiagramFormats))
+	erCmd.Flags().BoolVar(&acrossDatabases, "across_dbs", false, "If set, one diagram over all databases is created instead of one diagram per database")
+	erCmd.Flags().BoolVar(&schema.ErAllAttributes, "all_attribs", false, "If set, all top level attributes of the collections are shown. Per default only '_id' and the attributes of the relations are shown")
+	erCmd.MarkFlagRequired("schema_dir")
+}
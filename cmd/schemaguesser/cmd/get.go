@@ -36,6 +36,17 @@ var useDumps bool
 
 var dumpDir string
 
+var sampling string
+
+var filter string
+
+var projection string
+
+var sortOrder string
+
+// options for the collection queries, initialized by initQueryOptionsOrPanic
+var queryOptions mongoHelper.QueryOptions
+
 var getCmd = &cobra.Command{
 	Use:   "get",
 	Short: "Retrieve information out of mongodb",
@@ -52,6 +63,9 @@ func init() {
 	getCmd.AddCommand(jsonCmd)
 	getCmd.AddCommand(keyValuesCmd)
 	getCmd.AddCommand(linksCmd)
+	getCmd.AddCommand(validatorCmd)
+	getCmd.AddCommand(erCmd)
+	getCmd.AddCommand(dictionaryCmd)
 
 	getCmd.PersistentFlags().StringVarP(&databaseName, "database", "d", "all", "Database to query existing collections")
 
@@ -116,13 +130,37 @@ func queryCollection(client *mongo.Client, dbName string, collName string, callb
 		if dumpDir == "" {
 			panic(fmt.Sprintf("queryCollection - [%s:%s] no 'dump_dir' flag given, so no idea from where to get the data", dbName, collName))
 		}
+		if (sampling != "") && (sampling != mongoHelper.SAMPLING_FIRST) {
+			log.Printf("[%s:%s] sampling '%s' is ignored for dumps", dbName, collName, sampling)
+		}
+		if (filter != "") || (projection != "") || (sortOrder != "") {
+			log.Printf("[%s:%s] filter, projection and sort are ignored for dumps", dbName, collName)
+		}
 		importFile := utils.GetFileName(dumpDir, "bson", dbName, collName)
 		return getCollectionFromLocalFile(importFile, callback)
 	} else {
 		if client == nil {
 			panic("mongo client not initialized to query databases")
 		}
-		return mongoHelper.QueryCollection(client, dbName, collName, int(itemCount), useAggregation, mongoV44, callback)
+		return mongoHelper.QueryCollection(client, dbName, collName, int(itemCount), useAggregation, mongoV44, queryOptions, callback)
+	}
+}
+
+func addSamplingFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&sampling, "sampling", mongoHelper.SAMPLING_FIRST, "Strategy to select the sample documents: 'first' (natural order), 'random' ($sample), 'latest' (sorted by _id desc) or 'spread' (evenly spaced ranges over _id)")
+}
+
+func addQueryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&filter, "filter", "", "Query filter in MongoDB Extended JSON, e.g. '{\"type\": \"invoice\"}'")
+	cmd.Flags().StringVar(&projection, "projection", "", "Projection in MongoDB Extended JSON, e.g. '{\"payload\": 0}'")
+	cmd.Flags().StringVar(&sortOrder, "sort", "", "Sort order in MongoDB Extended JSON, e.g. '{\"created\": -1}'")
+}
+
+func initQueryOptionsOrPanic() {
+	var err error
+	queryOptions, err = mongoHelper.NewQueryOptions(sampling, filter, projection, sortOrder)
+	if err != nil {
+		panic(err)
 	}
 }
 
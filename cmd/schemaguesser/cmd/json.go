@@ -30,6 +30,7 @@ var jsonCmd = &cobra.Command{
 	Short: "dump bson content converted to JSON",
 	Long:  "With this command you can dump raw content as converted JSON of one or more mongodb collections. The usecase is comparing collection content in an editor for instance.",
 	Run: func(cmd *cobra.Command, args []string) {
+		initQueryOptionsOrPanic()
 		var client *mongo.Client
 		var err error
 		if !useDumps {
@@ -54,6 +55,10 @@ var jsonCmd = &cobra.Command{
 	},
 }
 
+func init() {
+	addQueryFlags(jsonCmd)
+}
+
 func replaceUuidValues(version byte, jsonStr string) (string, error) {
 	re := regexp.MustCompile(fmt.Sprintf(`"Subtype":\s*%d,\s*"Data":"([A-Za-z0-9+/=]+)"`, version))
 
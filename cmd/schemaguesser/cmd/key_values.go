@@ -21,6 +21,8 @@ import (
 
 func init() {
 	keyValuesCmd.Flags().BoolVar(&mongoHelper.KeepNullUuids, "keep_null_uuids", false, "If this flag is enabled, then '00000000-0000-0000-0000-000000000000' values are included in the approach. By default they are skipped.")
+	addSamplingFlag(keyValuesCmd)
+	addQueryFlags(keyValuesCmd)
 }
 
 var keyValuesCmd = &cobra.Command{
@@ -28,6 +30,7 @@ var keyValuesCmd = &cobra.Command{
 	Short: "dump the values of assumed key field to a text file",
 	Long:  "With this command you can dump the data of considered key fields from the collections. Potential key fields are '_id', UUIDs or string in the UUID format. The received data are stored in a folder structure by database and collection. Every collection folder contains then the files with the field data (new line separated)",
 	Run: func(cmd *cobra.Command, args []string) {
+		initQueryOptionsOrPanic()
 		var client *mongo.Client
 		var err error
 		if !useDumps {
@@ -22,6 +22,23 @@ var schemaCmd = &cobra.Command{
 	Short: "functions around the schemas",
 	Long:  "With this command you can create schemas out of mongodb collection",
 	Run: func(cmd *cobra.Command, args []string) {
+		initQueryOptionsOrPanic()
+		initNamingOrPanic()
+		initTemplateOrPanic()
+		if err := schema.CheckSchemaDraft(schema.SchemaDraft); err != nil {
+			panic(err)
+		}
+		if err := schema.CheckOutputFormat(schema.OutputFormat); err != nil {
+			panic(err)
+		}
+		if printOpenApi {
+			if err := checkOpenApiFlags(cmd); err != nil {
+				panic(err)
+			}
+			// OpenAPI 3.1 uses the JSON schema dialect of draft 2020-12
+			schema.SchemaDraft = schema.DRAFT_2020_12
+			openApiCollector = schema.NewOpenApiCollector()
+		}
 		var client *mongo.Client
 		var err error
 		if !useDumps {
@@ -42,6 +59,11 @@ var schemaCmd = &cobra.Command{
 				printSchemaForOneCollection(client, databaseName, collectionName, false, true)
 			}
 		}
+		if openApiCollector != nil {
+			if err := openApiCollector.Print(outputDir); err != nil {
+				log.Printf("Error while writing the OpenAPI documents: %v\n", err)
+			}
+		}
 	},
 }
 
@@ -54,6 +76,16 @@ var keyUuidString bool
 var useZeroKeyUuid bool
 var persistSchemaBase bool
 var writePlantUml bool
+var writeMermaid bool
+var writeGraphviz bool
+var discriminator string
+var namingFile string
+var namingOverrides schema.NamingOverrides
+var templateFile string
+var templateExt string
+var customTemplate *schema.CustomTemplate
+var printOpenApi bool
+var openApiCollector *schema.OpenApiCollector
 
 func init() {
 	schemaCmd.Flags().BoolVar(&includeCount, "include_count", false, "If set it includes the current number of elements of the collection into schema comments")
@@ -62,12 +94,78 @@ func init() {
 	schemaCmd.Flags().StringVar(&persistKeyValuesDir, "key_values_dir", "", "Optional output dir to store the files with the key values. If 'persist_key_values' is set and this flag is empty, then the output dir is used")
 	schemaCmd.Flags().BoolVar(&persistSchemaBase, "print_raw_schema_base", false, "If set then then the internal structure to detect the schemas is persisted too. This information is needed to search later for model dependencies over multiple collections")
 	schemaCmd.Flags().BoolVar(&writePlantUml, "print_puml", false, "If set then a plantuml class diagram for the type is exported too")
+	schemaCmd.Flags().BoolVar(&writeMermaid, "print_mermaid", false, "If set then a mermaid class diagram for the type is exported too, it can be embedded in GitHub or GitLab markdown")
+	schemaCmd.Flags().BoolVar(&writeGraphviz, "print_dot", false, "If set then a graphviz (DOT) class diagram for the type is exported too")
+	schemaCmd.Flags().BoolVar(&schema.StatsAsExtensions, "stats_as_extensions", false, "If set the observed min/max values, lengths and item counts are written as 'x-observed-*' extensions instead of JSON schema keywords (minimum, maxLength, ...). Observed date ranges are always written as extensions")
+	addInferenceFlags(schemaCmd)
+
+	schemaCmd.Flags().StringVar(&schema.SchemaDraft, "schema_draft", schema.DRAFT_07, fmt.Sprintf("JSON schema draft of the created schemas, possible values: %v. With 2020-12 '$defs', '$id', 'prefixItems' for tuples and 'unevaluatedProperties' are used", schema.SchemaDrafts))
+	schemaCmd.Flags().StringVar(&schema.SchemaIdBase, "schema_id_base", "", "Optional base URI for the '$id' of 2020-12 schemas, e.g. 'https://example.com/schemas'. Without it an URN like 'urn:mongodb:db:collection' is used")
+	schemaCmd.Flags().StringVar(&schema.OutputFormat, "format", schema.FORMAT_JSON_SCHEMA, fmt.Sprintf("Output format of the created model, possible values: %v. 'go' creates go structs with bson and json tags, 'ts' typescript interfaces, 'proto' protobuf messages and 'avro' avro record schemas", schema.OutputFormats))
+	schemaCmd.Flags().StringVar(&schema.GoPackage, "go_package", "model", "Package name of the created go code, used with '--format go'")
+	schemaCmd.Flags().StringVar(&schema.ProtoPackage, "proto_package", "model", "Package of the created protobuf messages, used with '--format proto'")
+	schemaCmd.Flags().StringVar(&schema.AvroNamespace, "avro_namespace", "", "Namespace of the created avro records, used with '--format avro'. If not set the database name is used")
+	schemaCmd.Flags().BoolVar(&schema.TsZod, "zod", false, "If set, zod schemas are created additionally to the typescript interfaces, used with '--format ts'")
+	schemaCmd.Flags().BoolVar(&schema.TsBsonTypes, "ts_bson_types", false, "If set, the typescript types use Date and the classes of the 'bson' package (ObjectId, Decimal128, ...) like the node driver returns them, instead of the JSON representation. Used with '--format ts'")
+	schemaCmd.Flags().StringVar(&templateFile, "template", "", "Optional go template file that is rendered for every collection additionally to the schema, e.g. to create wiki pages or code. The template gets the same data as the plantuml template")
+	schemaCmd.Flags().StringVar(&templateExt, "template_ext", "", "File extension of the output of 'template'. If not set it's taken from the template file name, e.g. 'wiki.md.tmpl' creates '.md' files")
+	schemaCmd.Flags().BoolVar(&printOpenApi, "openapi", false, "If set, one OpenAPI 3.1 document per database is written instead of the single model files. It contains the types of all handled collections as components, types with the same structure are contained only once. It uses JSON schema draft 2020-12 and can't be combined with other values of '--format' and '--schema_draft'")
 
 	schemaCmd.Flags().BoolVar(&keyUuid, "uuid_keys", false, "If set, binary uuid fields are considered as key, too")
 	schemaCmd.Flags().BoolVar(&keyUuid, "uuid_str_keys", false, "If set, uuids in string format (e.g. '056bcf58-e17e-42ba-8186-f25ffbde8b35') are considered as key, too")
 	schemaCmd.Flags().BoolVar(&keyUuid, "zero_uuid_keys", false, "Per default zero uuids (e.g. '00000000-0000-0000-0000-000000000000') are ignored, use the switch to integrate them as values when found")
 }
 
+// the OpenAPI document replaces the model files and always uses JSON schema draft 2020-12, so other
+// explicitly requested output formats or drafts can't be fulfilled
+func checkOpenApiFlags(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("format") && (schema.OutputFormat != schema.FORMAT_JSON_SCHEMA) {
+		return fmt.Errorf("'--openapi' can't be combined with '--format %s', the OpenAPI document contains JSON schemas", schema.OutputFormat)
+	}
+	if cmd.Flags().Changed("schema_draft") && (schema.SchemaDraft != schema.DRAFT_2020_12) {
+		return fmt.Errorf("'--openapi' can't be combined with '--schema_draft %s', OpenAPI 3.1 uses draft %s", schema.SchemaDraft, schema.DRAFT_2020_12)
+	}
+	return nil
+}
+
+// flags that control how the schema is guessed from the sample documents
+func addInferenceFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&mongoHelper.MaxDistinctValues, "enum_max_values", 10, "String and integer attributes with not more distinct values than this are considered as enums. 0 disables the enum detection")
+	cmd.Flags().Float64Var(&mongoHelper.StringFormatMatchRatio, "string_format_ratio", 1.0, "Min ratio of string values that needs to match a format (uuid, email, uri, ipv4, ipv6, date, date-time) before the format is set in the schema. 0 disables the format detection")
+	cmd.Flags().Int64Var(&schema.EnumMinSamples, "enum_min_samples", 50, "Min number of values that needs to be observed for an attribute, before it's considered as enum")
+	cmd.Flags().Float64Var(&schema.TypeSimilarity, "type_similarity", 1.0, "Min ratio of shared attributes to all attributes of two complex types, before they are merged to one type. Attributes that aren't shared become optional. 1.0 merges only types with the same attributes")
+	cmd.Flags().IntVar(&schema.DictMinKeys, "dict_min_keys", 20, "Min number of keys of a sub-document type, before it's considered as dictionary because every document uses only a few of its keys. 0 disables this check")
+	cmd.Flags().StringVar(&discriminator, "discriminator", "", "Name of a top level attribute that distinguishes the entity kinds in the collection, for every value a own type is created. 'auto' tries to detect a low-cardinality string attribute")
+	cmd.Flags().IntVar(&mongoHelper.MaxDiscriminatorValues, "discriminator_max_values", 50, "Max number of distinct values of an attribute that is detected with '--discriminator auto', it's independent of '--enum_max_values'")
+	cmd.Flags().StringVar(&schema.TypeNaming, "type_naming", schema.NAMING_PATH, fmt.Sprintf("Strategy to name the complex types, possible values: %v. 'path' creates stable names out of the attribute paths of the types, 'short' names the types after their attributes and numbers name clashes in the order of the documents", schema.NamingModes))
+	cmd.Flags().StringVar(&namingFile, "naming_file", "", "Optional YAML or JSON file that maps attribute paths like 'orders.items.price' to a type name, title and description")
+	addSamplingFlag(cmd)
+	addQueryFlags(cmd)
+}
+
+func initNamingOrPanic() {
+	if err := schema.CheckTypeNaming(schema.TypeNaming); err != nil {
+		panic(err)
+	}
+	if namingFile != "" {
+		var err error
+		namingOverrides, err = schema.LoadNamingOverrides(namingFile)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func initTemplateOrPanic() {
+	if templateFile != "" {
+		var err error
+		customTemplate, err = schema.LoadCustomTemplate(templateFile, templateExt)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
 func getDocumentCount(client *mongo.Client, dbName string, collName string, mt *mongoHelper.ComplexType) {
 	startTime := time.Now()
 	defer func() {
@@ -108,12 +206,58 @@ func printSchemaForOneCollection(client *mongo.Client, dbName string, collName s
 		descr := fmt.Sprintf("Schema for %s:%s", dbName, collName)
 		progressbar.Init(1, descr)
 	}
+	mainType, otherComplexTypes, ok := guessSchemaForOneCollection(client, dbName, collName)
+	if !ok {
+		return
+	}
+	startTime := time.Now()
+	if openApiCollector != nil {
+		openApiCollector.Add(dbName, collName, mainType, otherComplexTypes)
+	} else {
+		if err := schema.PrintModel(dbName, collName, mainType, otherComplexTypes, outputDir); err != nil {
+			log.Printf("[%s:%s] error while writing the model: %v\n", dbName, collName, err)
+		}
+	}
+	if persistSchemaBase {
+		if err := schema.PersistSchemaBase(dbName, collName, mainType, otherComplexTypes, outputDir); err != nil {
+			log.Printf("[%s:%s] error while writing the raw schema: %v\n", dbName, collName, err)
+		}
+	}
+	if writePlantUml {
+		if err := schema.WritePlantUml(dbName, collName, mainType, otherComplexTypes, outputDir); err != nil {
+			log.Printf("[%s:%s] error while writing the PlantUml diagram: %v\n", dbName, collName, err)
+		}
+	}
+	if writeMermaid {
+		if err := schema.WriteMermaid(dbName, collName, mainType, otherComplexTypes, outputDir); err != nil {
+			log.Printf("[%s:%s] error while writing the Mermaid diagram: %v\n", dbName, collName, err)
+		}
+	}
+	if writeGraphviz {
+		if err := schema.WriteGraphviz(dbName, collName, mainType, otherComplexTypes, outputDir); err != nil {
+			log.Printf("[%s:%s] error while writing the Graphviz diagram: %v\n", dbName, collName, err)
+		}
+	}
+	if customTemplate != nil {
+		if err := schema.PrintCustomTemplate(customTemplate, dbName, collName, mainType, otherComplexTypes, outputDir); err != nil {
+			log.Printf("[%s:%s] error while writing the custom template: %v\n", dbName, collName, err)
+		}
+	}
+	log.Printf("[%s:%s] Schema printed in %v\n", dbName, collName, time.Since(startTime))
+}
+
+// Queries the sample documents of the collection and guesses the main type and the other complex types
+// out of them. The last return value is false, if the collection contains no data.
+func guessSchemaForOneCollection(client *mongo.Client, dbName string, collName string) (*mongoHelper.ComplexType, []mongoHelper.ComplexType, bool) {
 	otherComplexTypes := make([]mongoHelper.ComplexType, 0)
 	var mainType mongoHelper.ComplexType
 
 	if includeCount {
 		getDocumentCount(client, dbName, collName, &mainType)
 	}
+	if !useDumps {
+		mainType.Sampling = sampling
+	}
 
 	bsonRaw := make([]bson.Raw, 0)
 	i := 0
@@ -129,29 +273,42 @@ func printSchemaForOneCollection(client *mongo.Client, dbName string, collName s
 			panic(msg)
 		}
 		startTime := time.Now()
-		for _, b := range bsonRaw {
-			otherComplexTypes, err = mongoHelper.ProcessBson(b, collName, &mainType, otherComplexTypes)
+		discriminatorToUse := discriminator
+		if discriminatorToUse == "auto" {
+			discriminatorToUse = mongoHelper.GuessDiscriminator(bsonRaw)
+			if discriminatorToUse == "" {
+				log.Printf("[%s:%s] no discriminator found, no top level string attribute exists in all documents with 2 to %d distinct values, that splits the documents in groups with different attributes\n",
+					dbName, collName, mongoHelper.MaxDiscriminatorValues)
+			} else {
+				log.Printf("[%s:%s] guessed discriminator: '%s'\n", dbName, collName, discriminatorToUse)
+			}
+		}
+		if discriminatorToUse != "" {
+			otherComplexTypes, err = mongoHelper.ProcessBsonWithDiscriminator(bsonRaw, collName, discriminatorToUse, &mainType, otherComplexTypes)
 			if err != nil {
 				log.Printf("Error while processing bson for schema: %v", err)
 			}
+		} else {
+			for _, b := range bsonRaw {
+				otherComplexTypes, err = mongoHelper.ProcessBson(b, collName, &mainType, otherComplexTypes)
+				if err != nil {
+					log.Printf("Error while processing bson for schema: %v", err)
+				}
+			}
 		}
 		log.Printf("[%s:%s] Mongodb data processed for collection in %v\n", dbName, collName, time.Since(startTime))
 
 		otherComplexTypes = schema.ReduceTypes(&mainType, otherComplexTypes)
-		otherComplexTypes = schema.GuessDicts(otherComplexTypes)
+		otherComplexTypes = schema.GuessDicts(&mainType, otherComplexTypes)
 		// ... after identifying dicts, we still can have double types
 		otherComplexTypes = schema.ReduceDoubleTypesByName(otherComplexTypes)
-		schema.PrintSchema(dbName, collName, &mainType, otherComplexTypes, outputDir)
-		if persistSchemaBase {
-			schema.PersistSchemaBase(dbName, collName, &mainType, otherComplexTypes, outputDir)
-		}
-		if writePlantUml {
-			schema.WritePlantUml(dbName, collName, &mainType, otherComplexTypes, outputDir)
-		}
-		log.Printf("[%s:%s] Schema printed in %v\n", dbName, collName, time.Since(startTime))
-	} else {
-		log.Printf("No data for database: %s, collection: %s\n", dbName, collName)
+		schema.GuessEnums(&mainType, otherComplexTypes)
+		schema.ApplyNaming(collName, &mainType, otherComplexTypes, namingOverrides)
+		log.Printf("[%s:%s] Schema guessed in %v\n", dbName, collName, time.Since(startTime))
+		return &mainType, otherComplexTypes, true
 	}
+	log.Printf("No data for database: %s, collection: %s\n", dbName, collName)
+	return nil, nil, false
 }
 
 func printSchemasForAllCollections(client *mongo.Client, dbName string, initProgressBar bool) {
@@ -6,8 +6,11 @@ import (
 	"testing"
 
 	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+	"okieoth/schemaguesser/internal/pkg/schema"
 	testhelper "okieoth/schemaguesser/internal/pkg/testHelper"
 	"okieoth/schemaguesser/internal/pkg/utils"
+
+	"github.com/spf13/cobra"
 )
 
 func Test_getDocumentCount_IT(t *testing.T) {
@@ -222,3 +225,33 @@ func Test_printSchemaForAllCollections(t *testing.T) {
 	}
 	_, _ = testhelper.CheckFilesNonZero(outputDir, expected, t)
 }
+
+func Test_checkOpenApiFlags(t *testing.T) {
+	defer func(format, draft string) {
+		schema.OutputFormat, schema.SchemaDraft = format, draft
+	}(schema.OutputFormat, schema.SchemaDraft)
+
+	tests := []struct {
+		args    []string
+		wantErr bool
+	}{
+		{[]string{}, false},
+		{[]string{"--format", "json"}, false},
+		{[]string{"--schema_draft", schema.DRAFT_2020_12}, false},
+		{[]string{"--format", "go"}, true},
+		{[]string{"--schema_draft", schema.DRAFT_07}, true},
+	}
+	for _, test := range tests {
+		cmd := &cobra.Command{}
+		cmd.Flags().StringVar(&schema.OutputFormat, "format", schema.FORMAT_JSON_SCHEMA, "")
+		cmd.Flags().StringVar(&schema.SchemaDraft, "schema_draft", schema.DRAFT_07, "")
+		if err := cmd.Flags().Parse(test.args); err != nil {
+			t.Errorf("Failed to parse the flags %v: %v", test.args, err)
+			return
+		}
+		err := checkOpenApiFlags(cmd)
+		if (err != nil) != test.wantErr {
+			t.Errorf("Unexpected result for the flags %v: %v", test.args, err)
+		}
+	}
+}
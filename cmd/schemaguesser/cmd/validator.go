@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+	"okieoth/schemaguesser/internal/pkg/progressbar"
+	"okieoth/schemaguesser/internal/pkg/schema"
+
+	"github.com/spf13/cobra"
+)
+
+var applyValidator bool
+var validationLevel string
+var validationAction string
+
+var validatorCmd = &cobra.Command{
+	Use:   "validator",
+	Short: "creates MongoDB validators out of the guessed schemas",
+	Long:  "With this command you can create '$jsonSchema' validators with 'bsonType' keywords for collections. The validators are printed and can optionally be applied to the collections with the 'collMod' command",
+	Run: func(cmd *cobra.Command, args []string) {
+		initQueryOptionsOrPanic()
+		initNamingOrPanic()
+		if err := schema.CheckValidationOptions(validationLevel, validationAction); err != nil {
+			panic(err)
+		}
+		var client *mongo.Client
+		var err error
+		// the validators are applied to the database, also if the samples are taken from dumps
+		if !useDumps || applyValidator {
+			client, err = mongoHelper.Connect(mongoHelper.ConStr)
+			if err != nil {
+				msg := fmt.Sprintf("Failed to connect to db: %v", err)
+				panic(msg)
+			}
+			defer mongoHelper.CloseConnection(client)
+		}
+
+		if databaseName == "all" {
+			validatorsForAllDatabases(client)
+		} else {
+			if collectionName == "all" {
+				validatorsForAllCollections(client, databaseName, true)
+			} else {
+				progressbar.Init(1, fmt.Sprintf("Validator for %s:%s", databaseName, collectionName))
+				validatorForOneCollection(client, databaseName, collectionName, false)
+			}
+		}
+	},
+}
+
+func init() {
+	validatorCmd.Flags().BoolVar(&applyValidator, "apply", false, "If set the validators are applied to the collections with the 'collMod' command")
+	validatorCmd.Flags().StringVar(&validationLevel, "validation_level", "moderate", fmt.Sprintf("Validation level that is set together with the validator, possible values: %v", schema.ValidationLevels))
+	validatorCmd.Flags().StringVar(&validationAction, "validation_action", "warn", fmt.Sprintf("Validation action that is set together with the validator, possible values: %v", schema.ValidationActions))
+	addInferenceFlags(validatorCmd)
+}
+
+func validatorForOneCollection(client *mongo.Client, dbName string, collName string, doRecover bool) {
+	defer func() {
+		if doRecover {
+			if r := recover(); r != nil {
+				log.Printf("Recovered while handling collection (db: %s, collection: %s): %v", dbName, collName, r)
+			}
+		}
+		progressbar.ProgressOne()
+	}()
+	mainType, otherComplexTypes, ok := guessSchemaForOneCollection(client, dbName, collName)
+	if !ok {
+		return
+	}
+	validator := schema.NewValidator(mainType, otherComplexTypes)
+	if err := schema.PrintValidator(dbName, collName, validator, outputDir); err != nil {
+		log.Printf("[%s:%s] error while writing the validator: %v\n", dbName, collName, err)
+	}
+	if applyValidator {
+		if err := mongoHelper.ApplyValidator(client, dbName, collName, validator, validationLevel, validationAction); err != nil {
+			msg := fmt.Sprintf("[%s:%s] error while applying the validator: %v", dbName, collName, err)
+			panic(msg)
+		}
+		log.Printf("[%s:%s] validator applied (level: %s, action: %s)\n", dbName, collName, validationLevel, validationAction)
+	}
+}
+
+// the collections are handled one after another, to not run several 'collMod' commands in parallel
+func validatorsForAllCollections(client *mongo.Client, dbName string, initProgressBar bool) {
+	collections := getAllCollectionsOrPanic(client, dumpDir, useDumps, dbName)
+	collections = removeBlacklisted(collections, blacklist)
+	if initProgressBar {
+		progressbar.Init(int64(len(collections)), "Validators for all collections")
+	}
+	for _, coll := range collections {
+		validatorForOneCollection(client, dbName, coll, true)
+	}
+}
+
+func validatorsForAllDatabases(client *mongo.Client) {
+	dbs := getAllDatabasesOrPanic(client, dumpDir, useDumps)
+	dbs = slices.DeleteFunc(dbs, func(db string) bool {
+		if slices.Contains(blacklist, db) {
+			log.Printf("[%s] skip blacklisted DB\n", db)
+			return true
+		}
+		return false
+	})
+	collectionCount := 0
+	collections := make(map[string][]string)
+	for _, db := range dbs {
+		collections[db] = removeBlacklisted(getAllCollectionsOrPanic(client, dumpDir, useDumps, db), blacklist)
+		collectionCount += len(collections[db])
+	}
+	progressbar.Init(int64(collectionCount), "Validators for all databases")
+	for _, db := range dbs {
+		for _, coll := range collections[db] {
+			validatorForOneCollection(client, db, coll, true)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package linksHelper
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"okieoth/schemaguesser/internal/pkg/utils"
 	"os"
@@ -168,7 +169,7 @@ func AggregateRefs(colRefs []ColRefs) []ColRefs {
 	for _, cr := range colRefs {
 		var alreadyExisting *ColRefs
 		for i, r := range ret {
-			if r.Db == cr.Db {
+			if (r.Db == cr.Db) && (r.Collection == cr.Collection) {
 				alreadyExisting = &ret[i]
 				break
 			}
@@ -194,3 +195,16 @@ func AggregateRefs(colRefs []ColRefs) []ColRefs {
 	}
 	return ret
 }
+
+// Reads the references, that were written by the 'get links' command (db_references.json)
+func LoadColRefs(linksFile string) ([]ColRefs, error) {
+	bytes, err := os.ReadFile(linksFile)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading links file (%s): %v", linksFile, err)
+	}
+	ret := make([]ColRefs, 0)
+	if err := json.Unmarshal(bytes, &ret); err != nil {
+		return nil, fmt.Errorf("error while parsing links file (%s): %v", linksFile, err)
+	}
+	return ret, nil
+}
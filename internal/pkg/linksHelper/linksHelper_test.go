@@ -123,3 +123,23 @@ func TestHarmonizeLinkAttribName(t *testing.T) {
 		assert.Equal(t, test.expected, result, "Expected %s but got %s", test.expected, result)
 	}
 }
+
+func TestAggregateRefs(t *testing.T) {
+	ref := func(attrib string, destColl string) AttribRef {
+		return AttribRef{AttribStr: attrib, References: []AttribRefDetails{{Db: "db", Collection: destColl, Attributes: []string{"_id"}}}}
+	}
+	colRefs := []ColRefs{
+		{Db: "db", Collection: "orders", AttribRefs: []AttribRef{ref("customerId", "customers")}},
+		{Db: "db", Collection: "invoices", AttribRefs: []AttribRef{ref("orderId", "orders")}},
+		{Db: "db", Collection: "orders", AttribRefs: []AttribRef{ref("customerId", "accounts"), ref("productId", "products")}},
+	}
+	aggregated := AggregateRefs(colRefs)
+	assert.Len(t, aggregated, 2)
+	assert.Equal(t, "orders", aggregated[0].Collection)
+	assert.Len(t, aggregated[0].AttribRefs, 2)
+	assert.Equal(t, "customerId", aggregated[0].AttribRefs[0].AttribStr)
+	assert.Len(t, aggregated[0].AttribRefs[0].References, 2)
+	assert.Equal(t, "productId", aggregated[0].AttribRefs[1].AttribStr)
+	assert.Equal(t, "invoices", aggregated[1].Collection)
+	assert.Len(t, aggregated[1].AttribRefs, 1)
+}
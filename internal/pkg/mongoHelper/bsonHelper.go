@@ -4,20 +4,33 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"slices"
+	"strconv"
 	"unicode"
 
 	ot "okieoth/schemaguesser/internal/pkg/optional_types"
 
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/bsontype"
 )
 
 const NUMBER = "number"
 const STRING = "string"
 const OBJECT = "object"
 const INT = "integer"
+const NULL = "null"
+
+// bson type marker for arrays without any elements, they don't give information about the item type
+const EMPTY_ARRAY_BSON_TYPE = "couldn't be retrieved - no elems"
+
+// max number of distinct values that are tracked for string and integer attributes,
+// attributes with more values aren't considered as enums. 0 disables the tracking.
+var MaxDistinctValues = 10
 
 type SchemaRaw struct {
+	// database and collection of the schema, older files don't contain them
+	Database          string         `json:"database,omitempty"`
+	Collection        string         `json:"collection,omitempty"`
 	MainType          *ComplexType   `json:"mainType"`
 	OtherComplexTypes *[]ComplexType `json:"otherComplexTypes,omitempty"`
 }
@@ -33,6 +46,21 @@ type ComplexType struct {
 	Comments      []string           `json:"comments,omitempty"`
 	Count         ot.Optional[int64] `json:"count,omitempty"`
 	IsKey         ot.Optional[bool]  `json:"isKey,omitempty"`
+	// number of documents (or sub-documents) that were processed for this type
+	SampleCount int64 `json:"sampleCount,omitempty"`
+	// sampling mode that was used to query the documents, only set for the main type
+	Sampling string `json:"sampling,omitempty"`
+	// attribute that distinguishes the variants of the main type
+	Discriminator string `json:"discriminator,omitempty"`
+	// for variants: the main type and the discriminator value of the variant
+	BaseType           string `json:"baseType,omitempty"`
+	DiscriminatorValue string `json:"discriminatorValue,omitempty"`
+	// for dictionaries: the format of the keys (uuid, objectId, ...) and the merged observations of all values
+	DictKeyFormat string         `json:"dictKeyFormat,omitempty"`
+	DictValue     *BasicElemInfo `json:"dictValue,omitempty"`
+	// optional documentation of the main type, that is provided by naming overrides
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
 type BasicElemInfo struct {
@@ -45,6 +73,39 @@ type BasicElemInfo struct {
 	Comment         string   `json:"comment,omitempty"`
 	IsComplex       bool     `json:"isComplex,omitempty"`
 	Comments        []string `json:"comments,omitempty"`
+	// number of processed documents (or sub-documents) that contained this attribute
+	OccurrenceCount int64 `json:"occurrenceCount,omitempty"`
+	// all types that were observed for this attribute, the most frequent one is
+	// also stored in the ValueType, BsonType, ... fields of the attribute
+	Types []ObservedType `json:"types,omitempty"`
+	// distinct values of string and integer attributes, as long as there are not more than MaxDistinctValues
+	DistinctValues []string `json:"distinctValues,omitempty"`
+	TooManyValues  bool     `json:"tooManyValues,omitempty"`
+	IsEnum         bool     `json:"isEnum,omitempty"`
+	// number of string values that matched one of the known string formats
+	StringFormats map[string]int64 `json:"stringFormats,omitempty"`
+	Stats         *ValueStats      `json:"stats,omitempty"`
+	// true if the attribute contained null values, null isn't tracked as own type
+	IsNullable bool `json:"isNullable,omitempty"`
+	// for arrays: the element types per position, as long as all arrays have the same length and
+	// the same types at the same positions
+	TupleItems []ObservedType `json:"tupleItems,omitempty"`
+	NoTuple    bool           `json:"noTuple,omitempty"`
+	// optional documentation, that is provided by naming overrides
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// One of the types that were observed for an attribute over the processed documents
+type ObservedType struct {
+	ValueType       string `json:"valueType,omitempty"`
+	BsonType        string `json:"bsonType,omitempty"`
+	Format          string `json:"format,omitempty"`
+	IsArray         bool   `json:"isArray,omitempty"`
+	ArrayDimensions uint   `json:"arrayDimensions,omitempty"`
+	IsComplex       bool   `json:"isComplex,omitempty"`
+	// how often this type was observed
+	Count int64 `json:"count,omitempty"`
 }
 
 func GetNewTypeName(name string, otherComplexTypes []ComplexType) string {
@@ -89,35 +150,203 @@ func addNewOtherComplexType(otherComplexTypes []ComplexType, complexType Complex
 }
 
 func addNewProperty(properties []BasicElemInfo, prop BasicElemInfo) []BasicElemInfo {
+	observedTypes := make([]ObservedType, 0)
+	if len(prop.Types) > 0 {
+		// arrays bring already the types of their elements
+		observedTypes = prop.Types
+	} else if (!isEmptyArray(&prop)) && (!prop.IsNullable) {
+		observedTypes = append(observedTypes, newObservedType(&prop))
+	}
 	for i, e := range properties {
 		if e.AttribName == prop.AttribName {
-			properties[i] = prop
+			e.OccurrenceCount++
+			e.IsNullable = e.IsNullable || prop.IsNullable
+			e.Types = addObservedTypes(e.Types, observedTypes)
+			addDistinctValues(&e, prop.DistinctValues, prop.TooManyValues)
+			addStringFormats(&e, prop.StringFormats)
+			mergeValueStats(&e.Stats, prop.Stats)
+			addTupleItems(&e, prop.TupleItems, prop.NoTuple)
+			if prop.Comment != "" {
+				e.Comment = prop.Comment
+			}
+			applyDominantType(&e)
+			properties[i] = e
 			return properties
 		}
 	}
+	prop.OccurrenceCount = 1
+	prop.Types = observedTypes
+	applyStringFormat(&prop)
 	return append(properties, prop)
 }
 
-func getAlreadyStoredType(otherComplexTypes []ComplexType, typeName string) (ComplexType, bool) {
-	for i, e := range otherComplexTypes {
-		if e.LongName == typeName {
-			return otherComplexTypes[i], true
+func isEmptyArray(prop *BasicElemInfo) bool {
+	return prop.IsArray && (prop.BsonType == EMPTY_ARRAY_BSON_TYPE)
+}
+
+func newObservedType(prop *BasicElemInfo) ObservedType {
+	return ObservedType{
+		ValueType:       prop.ValueType,
+		BsonType:        prop.BsonType,
+		Format:          prop.Format,
+		IsArray:         prop.IsArray,
+		ArrayDimensions: prop.ArrayDimensions,
+		IsComplex:       prop.IsComplex,
+		Count:           1,
+	}
+}
+
+func isIntegerBsonType(bsonType string) bool {
+	return (bsonType == "int") || (bsonType == "long")
+}
+
+// int and long values are the same observed type, otherwise numeric attributes with both become polymorphic
+func sameObservedType(t1 *ObservedType, t2 *ObservedType) bool {
+	sameBsonType := ((t1.BsonType == t2.BsonType) && (t1.Format == t2.Format)) ||
+		(isIntegerBsonType(t1.BsonType) && isIntegerBsonType(t2.BsonType))
+	return (t1.ValueType == t2.ValueType) && sameBsonType &&
+		(t1.IsArray == t2.IsArray) && (t1.ArrayDimensions == t2.ArrayDimensions) && (t1.IsComplex == t2.IsComplex)
+}
+
+// adds the count of the same observed type, a mixture of int and long values is widened to long
+func mergeObservedType(target *ObservedType, source *ObservedType) {
+	target.Count += source.Count
+	if (target.BsonType != source.BsonType) && isIntegerBsonType(target.BsonType) && isIntegerBsonType(source.BsonType) {
+		target.BsonType = "long"
+		target.Format = "int64"
+	}
+}
+
+func addObservedTypes(types []ObservedType, typesToAdd []ObservedType) []ObservedType {
+	for _, newType := range typesToAdd {
+		found := false
+		for i := range types {
+			if sameObservedType(&types[i], &newType) {
+				mergeObservedType(&types[i], &newType)
+				found = true
+				break
+			}
+		}
+		if !found {
+			types = append(types, newType)
 		}
 	}
-	return ComplexType{}, false
+	return types
+}
+
+// the most frequent observed type is used as main type of the attribute,
+// in case of equal counts the first observed type wins
+func applyDominantType(prop *BasicElemInfo) {
+	if len(prop.Types) == 0 {
+		return
+	}
+	dominant := prop.Types[0]
+	for _, t := range prop.Types[1:] {
+		if t.Count > dominant.Count {
+			dominant = t
+		}
+	}
+	prop.ValueType = dominant.ValueType
+	prop.BsonType = dominant.BsonType
+	prop.Format = dominant.Format
+	prop.IsArray = dominant.IsArray
+	prop.ArrayDimensions = dominant.ArrayDimensions
+	prop.IsComplex = dominant.IsComplex
+	applyStringFormat(prop)
+}
+
+// Returns true if more than one type was observed for the attribute
+func IsPolymorphic(prop *BasicElemInfo) bool {
+	return len(prop.Types) > 1
+}
+
+// Merges the observations of the source attribute into the target attribute.
+// It's used when two complex types are merged to one type.
+func MergeProperty(target *BasicElemInfo, source *BasicElemInfo) {
+	target.OccurrenceCount += source.OccurrenceCount
+	target.IsNullable = target.IsNullable || source.IsNullable
+	target.Types = addObservedTypes(target.Types, source.Types)
+	addDistinctValues(target, source.DistinctValues, source.TooManyValues)
+	addStringFormats(target, source.StringFormats)
+	mergeValueStats(&target.Stats, source.Stats)
+	addTupleItems(target, slices.Clone(source.TupleItems), source.NoTuple)
+	applyDominantType(target)
+}
+
+func addDistinctValues(prop *BasicElemInfo, values []string, tooManyValues bool) {
+	if prop.TooManyValues {
+		return
+	}
+	if tooManyValues {
+		prop.TooManyValues = true
+		prop.DistinctValues = nil
+		return
+	}
+	for _, v := range values {
+		if slices.Contains(prop.DistinctValues, v) {
+			continue
+		}
+		if len(prop.DistinctValues) >= MaxDistinctValues {
+			prop.TooManyValues = true
+			prop.DistinctValues = nil
+			return
+		}
+		prop.DistinctValues = append(prop.DistinctValues, v)
+	}
+}
+
+func trackValue(typeInfo *BasicElemInfo, value string) {
+	if MaxDistinctValues > 0 {
+		typeInfo.DistinctValues = []string{value}
+	}
 }
 
-func hasAlreadyProperty(mainType *ComplexType, attribName string) bool {
-	for _, p := range mainType.Properties {
-		if p.AttribName == attribName {
-			return true
+// Returns a copy of the attribute that doesn't share the collected observations with the original
+func CopyProperty(prop *BasicElemInfo) BasicElemInfo {
+	ret := *prop
+	ret.Types = slices.Clone(prop.Types)
+	ret.DistinctValues = slices.Clone(prop.DistinctValues)
+	ret.StringFormats = maps.Clone(prop.StringFormats)
+	ret.Comments = slices.Clone(prop.Comments)
+	ret.TupleItems = slices.Clone(prop.TupleItems)
+	if prop.Stats != nil {
+		stats := *prop.Stats
+		ret.Stats = &stats
+	}
+	return ret
+}
+
+// Replaces the reference to a complex type in the attribute and in all of its observed types
+func ReplaceTypeReference(prop *BasicElemInfo, typeNameToReplace string, typeNameReplacement string) {
+	if prop.IsComplex && (prop.ValueType == typeNameToReplace) {
+		prop.ValueType = typeNameReplacement
+	}
+	if len(prop.Types) == 0 {
+		return
+	}
+	// after the replacement two observed types can be the same, they are combined then
+	types := make([]ObservedType, 0, len(prop.Types))
+	for _, t := range prop.Types {
+		if t.IsComplex && (t.ValueType == typeNameToReplace) {
+			t.ValueType = typeNameReplacement
+		}
+		types = addObservedTypes(types, []ObservedType{t})
+	}
+	prop.Types = types
+	for i, t := range prop.TupleItems {
+		if t.IsComplex && (t.ValueType == typeNameToReplace) {
+			prop.TupleItems[i].ValueType = typeNameReplacement
 		}
 	}
-	return false
 }
 
-func isBasicType(elem bson.RawElement) bool {
-	return !((elem.Value().Type == bson.TypeArray) || (elem.Value().Type == bson.TypeEmbeddedDocument))
+func getAlreadyStoredType(otherComplexTypes []ComplexType, typeName string) (ComplexType, bool) {
+	for i, e := range otherComplexTypes {
+		if e.LongName == typeName {
+			return otherComplexTypes[i], true
+		}
+	}
+	return ComplexType{}, false
 }
 
 func ProcessBson(doc bson.Raw, collectionName string, mainType *ComplexType, otherComplexTypes []ComplexType) ([]ComplexType, error) {
@@ -137,79 +366,77 @@ func ProcessBson(doc bson.Raw, collectionName string, mainType *ComplexType, oth
 		mainType.Name = colNameFirstUpper
 		mainType.LongName = colNameFirstUpper
 	}
+	mainType.SampleCount++
 	for _, elem := range elements {
-		isAlreadyThere := hasAlreadyProperty(mainType, elem.Key())
-		if isAlreadyThere && isBasicType(elem) {
-			continue
-		}
 		typeInfo := BasicElemInfo{AttribName: elem.Key()}
 		typeInfo.AttribName = elem.Key()
 		switch elem.Value().Type {
-		case bson.TypeString:
-			log.Printf("Dummy: %s", elem.Value().StringValue())
-			handleTypeString(elem, &typeInfo)
-		case bson.TypeDouble:
-			handleTypeDouble(elem, &typeInfo)
 		case bson.TypeEmbeddedDocument:
 			newTypeLongName := firstUpperCase(collectionName) + firstUpperCase(elem.Key())
-			newSchemaType, existingOne := getAlreadyStoredType(otherComplexTypes, newTypeLongName)
-			var newTypeName string
-			if !existingOne {
-				newSchemaType = ComplexType{}
-				newSchemaType.LongName = newTypeLongName
-				newTypeName = GetNewTypeName(elem.Key(), otherComplexTypes)
-				newSchemaType.Name = newTypeName
-			} else {
-				newTypeName = newSchemaType.Name
-			}
-			typeInfo.ValueType = newTypeName
-			otherComplexTypes = handleTypeEmbeddedDocument(elem, &typeInfo, &newSchemaType, otherComplexTypes, newTypeName, true)
-			otherComplexTypes = addNewOtherComplexType(otherComplexTypes, newSchemaType)
+			otherComplexTypes = handleTypeEmbeddedDocumentAttrib(elem, &typeInfo, newTypeLongName, otherComplexTypes)
 		case bson.TypeArray:
 			otherComplexTypes = handleTypeArray(elem, &typeInfo, otherComplexTypes, mainType.Name)
-		case bson.TypeBinary:
-			handleTypeBinary(elem, &typeInfo)
-		case bson.TypeUndefined:
-			handleTypeUndefined(elem, &typeInfo)
-		case bson.TypeObjectID:
-			handleTypeObjectID(elem, &typeInfo)
-		case bson.TypeBoolean:
-			handleTypeBoolean(elem, &typeInfo)
-		case bson.TypeDateTime:
-			handleTypeDateTime(elem, &typeInfo)
-		case bson.TypeNull:
-			handleTypeNull(elem, &typeInfo)
-		case bson.TypeRegex:
-			handleTypeRegex(elem, &typeInfo)
-		case bson.TypeDBPointer:
-			handleTypeDBPointer(elem, &typeInfo)
-		case bson.TypeJavaScript:
-			handleTypeJavaScript(elem, &typeInfo)
-		case bson.TypeSymbol:
-			handleTypeSymbol(elem, &typeInfo)
-		case bson.TypeCodeWithScope:
-			handleTypeCodeWithScope(elem, &typeInfo)
-		case bson.TypeInt32:
-			handleTypeInt32(elem, &typeInfo)
-		case bson.TypeInt64:
-			handleTypeInt64(elem, &typeInfo)
-		case bson.TypeTimestamp:
-			handleTypeTimestamp(elem, &typeInfo)
-		case bson.TypeDecimal128:
-			handleTypeDecimal128(elem, &typeInfo)
-		case bson.TypeMinKey:
-			handleTypeMinKey(elem, &typeInfo)
-		case bson.TypeMaxKey:
-			handleTypeMaxKey(elem, &typeInfo)
+		default:
+			handleBasicType(elem, &typeInfo)
 		}
+		typeInfo.Stats = newValueStats(elem.Value())
 		mainType.Properties = addNewProperty(mainType.Properties, typeInfo)
 	}
 	return otherComplexTypes, nil
 }
 
+// sets the type information for all bson types that are no embedded documents or arrays
+func handleBasicType(elem bson.RawElement, typeInfo *BasicElemInfo) {
+	switch elem.Value().Type {
+	case bson.TypeString:
+		handleTypeString(elem, typeInfo)
+	case bson.TypeDouble:
+		handleTypeDouble(elem, typeInfo)
+	case bson.TypeBinary:
+		handleTypeBinary(elem, typeInfo)
+	case bson.TypeUndefined:
+		handleTypeUndefined(elem, typeInfo)
+	case bson.TypeObjectID:
+		handleTypeObjectID(elem, typeInfo)
+	case bson.TypeBoolean:
+		handleTypeBoolean(elem, typeInfo)
+	case bson.TypeDateTime:
+		handleTypeDateTime(elem, typeInfo)
+	case bson.TypeNull:
+		handleTypeNull(elem, typeInfo)
+	case bson.TypeRegex:
+		handleTypeRegex(elem, typeInfo)
+	case bson.TypeDBPointer:
+		handleTypeDBPointer(elem, typeInfo)
+	case bson.TypeJavaScript:
+		handleTypeJavaScript(elem, typeInfo)
+	case bson.TypeSymbol:
+		handleTypeSymbol(elem, typeInfo)
+	case bson.TypeCodeWithScope:
+		handleTypeCodeWithScope(elem, typeInfo)
+	case bson.TypeInt32:
+		handleTypeInt32(elem, typeInfo)
+	case bson.TypeInt64:
+		handleTypeInt64(elem, typeInfo)
+	case bson.TypeTimestamp:
+		handleTypeTimestamp(elem, typeInfo)
+	case bson.TypeDecimal128:
+		handleTypeDecimal128(elem, typeInfo)
+	case bson.TypeMinKey:
+		handleTypeMinKey(elem, typeInfo)
+	case bson.TypeMaxKey:
+		handleTypeMaxKey(elem, typeInfo)
+	}
+}
+
 func handleTypeString(elem bson.RawElement, typeInfo *BasicElemInfo) {
 	typeInfo.ValueType = STRING
 	typeInfo.BsonType = STRING
+	value := elem.Value().StringValue()
+	trackValue(typeInfo, value)
+	if StringFormatMatchRatio > 0 {
+		typeInfo.StringFormats = detectStringFormats(value)
+	}
 }
 
 func handleTypeDouble(elem bson.RawElement, typeInfo *BasicElemInfo) {
@@ -217,11 +444,26 @@ func handleTypeDouble(elem bson.RawElement, typeInfo *BasicElemInfo) {
 	typeInfo.BsonType = "double"
 }
 
+// processes an embedded document that is stored in an attribute, the document is merged into the
+// complex type with the given long name. If there is no such type yet, then a new one is created.
+func handleTypeEmbeddedDocumentAttrib(elem bson.RawElement, typeInfo *BasicElemInfo, newTypeLongName string, otherComplexTypes []ComplexType) []ComplexType {
+	newSchemaType, existingOne := getAlreadyStoredType(otherComplexTypes, newTypeLongName)
+	if !existingOne {
+		newSchemaType = ComplexType{}
+		newSchemaType.LongName = newTypeLongName
+		newSchemaType.Name = GetNewTypeName(elem.Key(), otherComplexTypes)
+	}
+	typeInfo.ValueType = newSchemaType.Name
+	otherComplexTypes = handleTypeEmbeddedDocument(elem, typeInfo, &newSchemaType, otherComplexTypes, newSchemaType.Name, true)
+	return addNewOtherComplexType(otherComplexTypes, newSchemaType)
+}
+
 func handleTypeEmbeddedDocument(elem bson.RawElement, typeInfo *BasicElemInfo, schemaType *ComplexType, otherComplexTypes []ComplexType, prefix string, addToOtherSchemas bool) []ComplexType {
 	typeInfo.BsonType = "embeddedDocument - unofficial type"
 	typeInfo.IsComplex = true
 
 	schemaType.Name = typeInfo.ValueType
+	schemaType.SampleCount++
 	embeddedDoc := bson.Raw(elem.Value().Value)
 
 	elements, err := embeddedDoc.Elements()
@@ -232,64 +474,15 @@ func handleTypeEmbeddedDocument(elem bson.RawElement, typeInfo *BasicElemInfo, s
 			typeInfo := BasicElemInfo{AttribName: elem.Key()}
 			typeInfo.AttribName = elem.Key()
 			switch elem.Value().Type {
-			case bson.TypeString:
-				handleTypeString(elem, &typeInfo)
-			case bson.TypeDouble:
-				handleTypeDouble(elem, &typeInfo)
 			case bson.TypeEmbeddedDocument:
-				var newTypeLongName, newTypeName string
-				newTypeLongName = schemaType.LongName + firstUpperCase(elem.Key())
-				newTypeName = GetNewTypeName(elem.Key(), otherComplexTypes)
-
-				// newTypeLongName = schemaType.LongName + firstUpperCase(elem.Key())
-				// newTypeName = getNewTypeName(elem.Key(), otherComplexTypes)
-
-				newSchemaType, existingOne := getAlreadyStoredType(otherComplexTypes, newTypeLongName)
-				if !existingOne {
-					newSchemaType = ComplexType{}
-					newSchemaType.LongName = newTypeLongName
-					newSchemaType.Name = newTypeName
-				}
-				typeInfo.ValueType = newTypeName
-				otherComplexTypes = handleTypeEmbeddedDocument(elem, &typeInfo, &newSchemaType, otherComplexTypes, schemaType.Name, true)
-				otherComplexTypes = addNewOtherComplexType(otherComplexTypes, newSchemaType)
+				newTypeLongName := schemaType.LongName + firstUpperCase(elem.Key())
+				otherComplexTypes = handleTypeEmbeddedDocumentAttrib(elem, &typeInfo, newTypeLongName, otherComplexTypes)
 			case bson.TypeArray:
 				otherComplexTypes = handleTypeArray(elem, &typeInfo, otherComplexTypes, schemaType.Name)
-			case bson.TypeBinary:
-				handleTypeBinary(elem, &typeInfo)
-			case bson.TypeUndefined:
-				handleTypeUndefined(elem, &typeInfo)
-			case bson.TypeObjectID:
-				handleTypeObjectID(elem, &typeInfo)
-			case bson.TypeBoolean:
-				handleTypeBoolean(elem, &typeInfo)
-			case bson.TypeDateTime:
-				handleTypeDateTime(elem, &typeInfo)
-			case bson.TypeNull:
-				handleTypeNull(elem, &typeInfo)
-			case bson.TypeRegex:
-				handleTypeRegex(elem, &typeInfo)
-			case bson.TypeDBPointer:
-				handleTypeDBPointer(elem, &typeInfo)
-			case bson.TypeJavaScript:
-				handleTypeJavaScript(elem, &typeInfo)
-			case bson.TypeSymbol:
-				handleTypeSymbol(elem, &typeInfo)
-			case bson.TypeCodeWithScope:
-				handleTypeCodeWithScope(elem, &typeInfo)
-			case bson.TypeInt32:
-				handleTypeInt32(elem, &typeInfo)
-			case bson.TypeInt64:
-				handleTypeInt64(elem, &typeInfo)
-			case bson.TypeTimestamp:
-				handleTypeTimestamp(elem, &typeInfo)
-			case bson.TypeDecimal128:
-				handleTypeDecimal128(elem, &typeInfo)
-			case bson.TypeMinKey:
-				handleTypeMinKey(elem, &typeInfo)
-			case bson.TypeMaxKey:
-				handleTypeMaxKey(elem, &typeInfo)
+			default:
+				handleBasicType(elem, &typeInfo)
 			}
+			typeInfo.Stats = newValueStats(elem.Value())
 			schemaType.Properties = addNewProperty(schemaType.Properties, typeInfo)
 		}
 	}
@@ -297,97 +490,71 @@ func handleTypeEmbeddedDocument(elem bson.RawElement, typeInfo *BasicElemInfo, s
 }
 
 func handleTypeArray(elem bson.RawElement, typeInfo *BasicElemInfo, otherComplexTypes []ComplexType, prefix string) []ComplexType {
-	arrayRaw := bson.Raw(elem.Value().Value)
-
 	typeInfo.IsArray = true
-	typeInfo.ArrayDimensions++
-	typeInfo.BsonType = "couldn't be retrieved - no elems"
+	typeInfo.ArrayDimensions = 1
+	typeInfo.BsonType = EMPTY_ARRAY_BSON_TYPE
 	typeInfo.ValueType = OBJECT
 	newTypeLongName := prefix + firstUpperCase(elem.Key())
-	newTypeName := GetNewTypeName(elem.Key(), otherComplexTypes)
 
-	elements, err := arrayRaw.Elements()
+	var itemFormats arrayStringFormats
+	itemTypes, otherComplexTypes, err := collectArrayItemTypes(elem.Value(), elem.Key(), newTypeLongName, 1, otherComplexTypes, &itemFormats)
 	if err != nil {
 		typeInfo.Comment = fmt.Sprintf("error while parsing array type: %v", err)
 		typeInfo.BsonType = "array type - unofficial type"
 		return otherComplexTypes
 	}
+	tupleItems, tupleCandidate := tupleItemTypes(elem.Value(), itemTypes)
+	addTupleItems(typeInfo, tupleItems, !tupleCandidate)
+	// the item types are counted once per array, not once per element
+	for i := range itemTypes {
+		itemTypes[i].Count = 1
+	}
+	typeInfo.Types = itemTypes
+	typeInfo.StringFormats = itemFormats.arrayFormats()
+	applyDominantType(typeInfo)
+	return otherComplexTypes
+}
 
-	var lastType bsontype.Type
-	lastTypeSet := false
-	var complexArrayType ComplexType
+// Collects the types of all array elements. Embedded documents of the array (also in nested arrays)
+// are merged into one complex type with the given long name. The formats of the string elements are
+// added to the given formats.
+func collectArrayItemTypes(value bson.RawValue, attribName string, itemTypeLongName string, dimension uint, otherComplexTypes []ComplexType, formats *arrayStringFormats) ([]ObservedType, []ComplexType, error) {
+	itemTypes := make([]ObservedType, 0)
+	arrayRaw := bson.Raw(value.Value)
+	elements, err := arrayRaw.Elements()
+	if err != nil {
+		return itemTypes, otherComplexTypes, err
+	}
 	for _, elem := range elements {
-		if (lastTypeSet) && (lastType != elem.Value().Type) {
-			typeInfo.Comment = "array type consists of different types, multiple type arrays are not supported"
-			typeInfo.BsonType = "array type - unofficial type"
-			return otherComplexTypes
+		if elem.Value().Type == bson.TypeArray {
+			var nestedTypes []ObservedType
+			nestedTypes, otherComplexTypes, err = collectArrayItemTypes(elem.Value(), attribName, itemTypeLongName, dimension+1, otherComplexTypes, formats)
+			if err != nil {
+				return itemTypes, otherComplexTypes, err
+			}
+			itemTypes = addObservedTypes(itemTypes, nestedTypes)
+			continue
 		}
-
-		if !lastTypeSet {
-			lastTypeSet = true
-			switch elem.Value().Type {
-			case bson.TypeString:
-				handleTypeString(elem, typeInfo)
-			case bson.TypeDouble:
-				handleTypeDouble(elem, typeInfo)
-			case bson.TypeEmbeddedDocument:
-				newSchemaType, existingOne := getAlreadyStoredType(otherComplexTypes, newTypeLongName)
-				if !existingOne {
-					newSchemaType = ComplexType{}
-					newSchemaType.LongName = newTypeLongName
-					newSchemaType.Name = newTypeName
-				}
-				typeInfo.ValueType = newTypeName
-				otherComplexTypes = handleTypeEmbeddedDocument(elem, typeInfo, &newSchemaType, otherComplexTypes, newTypeName, true)
-				otherComplexTypes = addNewOtherComplexType(otherComplexTypes, newSchemaType)
-				complexArrayType = newSchemaType
-			case bson.TypeArray:
-				otherComplexTypes = handleTypeArray(elem, typeInfo, otherComplexTypes, newTypeName)
-			case bson.TypeBinary:
-				handleTypeBinary(elem, typeInfo)
-			case bson.TypeUndefined:
-				handleTypeUndefined(elem, typeInfo)
-			case bson.TypeObjectID:
-				handleTypeObjectID(elem, typeInfo)
-			case bson.TypeBoolean:
-				handleTypeBoolean(elem, typeInfo)
-			case bson.TypeDateTime:
-				handleTypeDateTime(elem, typeInfo)
-			case bson.TypeNull:
-				handleTypeNull(elem, typeInfo)
-			case bson.TypeRegex:
-				handleTypeRegex(elem, typeInfo)
-			case bson.TypeDBPointer:
-				handleTypeDBPointer(elem, typeInfo)
-			case bson.TypeJavaScript:
-				handleTypeJavaScript(elem, typeInfo)
-			case bson.TypeSymbol:
-				handleTypeSymbol(elem, typeInfo)
-			case bson.TypeCodeWithScope:
-				handleTypeCodeWithScope(elem, typeInfo)
-			case bson.TypeInt32:
-				handleTypeInt32(elem, typeInfo)
-			case bson.TypeInt64:
-				handleTypeInt64(elem, typeInfo)
-			case bson.TypeTimestamp:
-				handleTypeTimestamp(elem, typeInfo)
-			case bson.TypeDecimal128:
-				handleTypeDecimal128(elem, typeInfo)
-			case bson.TypeMinKey:
-				handleTypeMinKey(elem, typeInfo)
-			case bson.TypeMaxKey:
-				handleTypeMaxKey(elem, typeInfo)
+		itemInfo := BasicElemInfo{AttribName: attribName}
+		if elem.Value().Type == bson.TypeEmbeddedDocument {
+			itemType, existingOne := getAlreadyStoredType(otherComplexTypes, itemTypeLongName)
+			if !existingOne {
+				itemType = ComplexType{}
+				itemType.LongName = itemTypeLongName
+				itemType.Name = GetNewTypeName(attribName, otherComplexTypes)
 			}
+			itemInfo.ValueType = itemType.Name
+			otherComplexTypes = handleTypeEmbeddedDocument(elem, &itemInfo, &itemType, otherComplexTypes, itemType.Name, true)
+			otherComplexTypes = addNewOtherComplexType(otherComplexTypes, itemType)
 		} else {
-			lastType = elem.Value().Type
-
-			// only complex types needs to be reviewed for additional attributes
-			if elem.Value().Type == bson.TypeEmbeddedDocument {
-				otherComplexTypes = handleTypeEmbeddedDocument(elem, typeInfo, &complexArrayType, otherComplexTypes, newTypeName, true)
-			}
+			handleBasicType(elem, &itemInfo)
+			formats.add(&itemInfo)
 		}
+		itemInfo.IsArray = true
+		itemInfo.ArrayDimensions = dimension
+		itemTypes = addObservedTypes(itemTypes, []ObservedType{newObservedType(&itemInfo)})
 	}
-	return otherComplexTypes
+	return itemTypes, otherComplexTypes, nil
 }
 
 func handleTypeBinary(elem bson.RawElement, typeInfo *BasicElemInfo) {
@@ -429,8 +596,9 @@ func handleTypeDateTime(elem bson.RawElement, typeInfo *BasicElemInfo) {
 }
 
 func handleTypeNull(elem bson.RawElement, typeInfo *BasicElemInfo) {
-	typeInfo.ValueType = OBJECT
-	typeInfo.BsonType = "null"
+	typeInfo.ValueType = NULL
+	typeInfo.BsonType = NULL
+	typeInfo.IsNullable = true
 }
 
 func handleTypeRegex(elem bson.RawElement, typeInfo *BasicElemInfo) {
@@ -466,12 +634,14 @@ func handleTypeInt32(elem bson.RawElement, typeInfo *BasicElemInfo) {
 	typeInfo.ValueType = INT
 	typeInfo.Format = "int32"
 	typeInfo.BsonType = "int"
+	trackValue(typeInfo, strconv.FormatInt(int64(elem.Value().Int32()), 10))
 }
 
 func handleTypeInt64(elem bson.RawElement, typeInfo *BasicElemInfo) {
 	typeInfo.ValueType = INT
 	typeInfo.Format = "int64"
 	typeInfo.BsonType = "long"
+	trackValue(typeInfo, strconv.FormatInt(elem.Value().Int64(), 10))
 }
 
 func handleTypeTimestamp(elem bson.RawElement, typeInfo *BasicElemInfo) {
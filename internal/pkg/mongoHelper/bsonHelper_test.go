@@ -2,6 +2,10 @@ package mongoHelper
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func TestFirstUpperCase(t *testing.T) {
@@ -17,3 +21,227 @@ func TestFirstUpperCase(t *testing.T) {
 		return
 	}
 }
+
+func getPropByName(t *testing.T, complexType *ComplexType, attribName string) *BasicElemInfo {
+	for i, p := range complexType.Properties {
+		if p.AttribName == attribName {
+			return &complexType.Properties[i]
+		}
+	}
+	t.Fatalf("property not found: %s", attribName)
+	return nil
+}
+
+func processTestDocs(t *testing.T, collName string, docs []bson.M) (ComplexType, []ComplexType) {
+	var mainType ComplexType
+	otherComplexTypes := make([]ComplexType, 0)
+	for _, d := range docs {
+		b, err := bson.Marshal(d)
+		require.Nil(t, err)
+		otherComplexTypes, err = ProcessBson(b, collName, &mainType, otherComplexTypes)
+		require.Nil(t, err)
+	}
+	return mainType, otherComplexTypes
+}
+
+func TestProcessBsonOccurrenceCount(t *testing.T) {
+	docs := []bson.M{
+		{"key": "v1", "sub": bson.M{"a": 1, "b": true}},
+		{"key": "v2", "sub": bson.M{"a": 2}},
+		{"key": "v3", "opt": 1.5, "sub": bson.M{"a": 3}},
+		{"key": "v4"},
+	}
+	mainType, otherComplexTypes := processTestDocs(t, "test", docs)
+
+	assert.Equal(t, int64(4), mainType.SampleCount)
+	assert.Equal(t, int64(4), getPropByName(t, &mainType, "key").OccurrenceCount)
+	assert.Equal(t, int64(1), getPropByName(t, &mainType, "opt").OccurrenceCount)
+	assert.Equal(t, int64(3), getPropByName(t, &mainType, "sub").OccurrenceCount)
+
+	require.Len(t, otherComplexTypes, 1)
+	sub := otherComplexTypes[0]
+	assert.Equal(t, int64(3), sub.SampleCount)
+	assert.Equal(t, int64(3), getPropByName(t, &sub, "a").OccurrenceCount)
+	assert.Equal(t, int64(1), getPropByName(t, &sub, "b").OccurrenceCount)
+}
+
+func TestProcessBsonObservedTypes(t *testing.T) {
+	docs := []bson.M{
+		{"val": int32(1), "stable": "a"},
+		{"val": "one", "stable": "b"},
+		{"val": "two", "stable": "c"},
+	}
+	mainType, _ := processTestDocs(t, "test", docs)
+
+	val := getPropByName(t, &mainType, "val")
+	assert.True(t, IsPolymorphic(val))
+	require.Len(t, val.Types, 2)
+	assert.Equal(t, "integer", val.Types[0].ValueType)
+	assert.Equal(t, int64(1), val.Types[0].Count)
+	assert.Equal(t, STRING, val.Types[1].ValueType)
+	assert.Equal(t, int64(2), val.Types[1].Count)
+	// the most frequent type is the main type of the attribute
+	assert.Equal(t, STRING, val.ValueType)
+
+	stable := getPropByName(t, &mainType, "stable")
+	assert.False(t, IsPolymorphic(stable))
+	require.Len(t, stable.Types, 1)
+	assert.Equal(t, int64(3), stable.Types[0].Count)
+}
+
+// int and long values are one integer type, that is widened to long
+func TestProcessBsonIntAndLong(t *testing.T) {
+	docs := []bson.M{
+		{"count": int32(1), "ids": bson.A{int32(1), int64(2)}},
+		{"count": int64(2), "ids": bson.A{int32(3)}},
+		{"count": int32(1), "ids": bson.A{}},
+	}
+	mainType, _ := processTestDocs(t, "test", docs)
+
+	count := getPropByName(t, &mainType, "count")
+	assert.False(t, IsPolymorphic(count))
+	require.Len(t, count.Types, 1)
+	assert.Equal(t, int64(3), count.Types[0].Count)
+	assert.Equal(t, "long", count.BsonType)
+	assert.Equal(t, "int64", count.Format)
+	assert.Equal(t, []string{"1", "2"}, count.DistinctValues)
+
+	ids := getPropByName(t, &mainType, "ids")
+	assert.False(t, IsPolymorphic(ids))
+	assert.Equal(t, "long", ids.BsonType)
+	assert.True(t, ids.IsArray)
+}
+
+func TestProcessBsonHeterogeneousArrays(t *testing.T) {
+	docs := []bson.M{
+		{"mixed": bson.A{int32(1), "x", bson.M{"a": int32(1)}}, "items": bson.A{bson.M{"a": int32(1)}, bson.M{"b": true}}},
+		{"mixed": bson.A{"y"}, "items": bson.A{bson.M{"a": int32(2), "c": "c"}}, "nested": bson.A{bson.A{int32(1), int32(2)}, bson.A{"a"}}},
+		{"items": bson.A{}},
+	}
+	mainType, otherComplexTypes := processTestDocs(t, "test", docs)
+
+	mixed := getPropByName(t, &mainType, "mixed")
+	assert.True(t, mixed.IsArray)
+	assert.Equal(t, "", mixed.Comment)
+	require.Len(t, mixed.Types, 3)
+	assert.Equal(t, "integer", mixed.Types[0].ValueType)
+	assert.Equal(t, STRING, mixed.Types[1].ValueType)
+	// the string items were observed in both arrays
+	assert.Equal(t, int64(2), mixed.Types[1].Count)
+	assert.True(t, mixed.Types[2].IsComplex)
+	assert.Equal(t, STRING, mixed.ValueType)
+	for _, it := range mixed.Types {
+		assert.True(t, it.IsArray)
+		assert.Equal(t, uint(1), it.ArrayDimensions)
+	}
+
+	// embedded documents with different shapes are merged into one item type
+	items := getPropByName(t, &mainType, "items")
+	assert.Equal(t, int64(3), items.OccurrenceCount)
+	assert.False(t, IsPolymorphic(items))
+	assert.True(t, items.IsComplex)
+	itemType, found := getAlreadyStoredType(otherComplexTypes, "TestItems")
+	require.True(t, found)
+	assert.Equal(t, items.ValueType, itemType.Name)
+	assert.Equal(t, int64(3), itemType.SampleCount)
+	assert.Equal(t, int64(2), getPropByName(t, &itemType, "a").OccurrenceCount)
+	assert.Equal(t, int64(1), getPropByName(t, &itemType, "b").OccurrenceCount)
+	assert.Equal(t, int64(1), getPropByName(t, &itemType, "c").OccurrenceCount)
+
+	nested := getPropByName(t, &mainType, "nested")
+	require.Len(t, nested.Types, 2)
+	for _, it := range nested.Types {
+		assert.True(t, it.IsArray)
+		assert.Equal(t, uint(2), it.ArrayDimensions)
+	}
+	assert.Equal(t, uint(2), nested.ArrayDimensions)
+}
+
+func TestProcessBsonNestedDocumentsKeepTypeName(t *testing.T) {
+	docs := []bson.M{
+		{"person": bson.M{"address": bson.M{"city": "a"}}},
+		{"person": bson.M{"address": bson.M{"city": "b", "zip": "123"}}},
+	}
+	_, otherComplexTypes := processTestDocs(t, "test", docs)
+	require.Len(t, otherComplexTypes, 2)
+	address, found := getAlreadyStoredType(otherComplexTypes, "TestPersonAddress")
+	require.True(t, found)
+	assert.Equal(t, "Address", address.Name)
+	person, found := getAlreadyStoredType(otherComplexTypes, "TestPerson")
+	require.True(t, found)
+	assert.False(t, IsPolymorphic(getPropByName(t, &person, "address")))
+}
+
+func TestProcessBsonDistinctValues(t *testing.T) {
+	oldMax := MaxDistinctValues
+	defer func() { MaxDistinctValues = oldMax }()
+	MaxDistinctValues = 3
+
+	docs := []bson.M{
+		{"status": "a", "prio": int32(1), "name": "n1"},
+		{"status": "b", "prio": int64(2), "name": "n2"},
+		{"status": "a", "prio": int32(1), "name": "n3"},
+		{"status": "c", "prio": int32(3), "name": "n4"},
+	}
+	mainType, _ := processTestDocs(t, "test", docs)
+
+	status := getPropByName(t, &mainType, "status")
+	assert.False(t, status.TooManyValues)
+	assert.Equal(t, []string{"a", "b", "c"}, status.DistinctValues)
+
+	prio := getPropByName(t, &mainType, "prio")
+	assert.Equal(t, []string{"1", "2", "3"}, prio.DistinctValues)
+
+	name := getPropByName(t, &mainType, "name")
+	assert.True(t, name.TooManyValues)
+	assert.Nil(t, name.DistinctValues)
+}
+
+func TestProcessBsonNullable(t *testing.T) {
+	docs := []bson.M{
+		{"name": nil, "always": nil, "sub": nil},
+		{"name": "a", "always": nil, "sub": bson.M{"x": int32(1)}},
+		{"name": "b", "always": nil},
+	}
+	mainType, _ := processTestDocs(t, "test", docs)
+
+	name := getPropByName(t, &mainType, "name")
+	assert.True(t, name.IsNullable)
+	assert.False(t, IsPolymorphic(name))
+	assert.Equal(t, STRING, name.ValueType)
+	assert.Equal(t, int64(3), name.OccurrenceCount)
+	require.Len(t, name.Types, 1)
+	assert.Equal(t, int64(2), name.Types[0].Count)
+
+	always := getPropByName(t, &mainType, "always")
+	assert.True(t, always.IsNullable)
+	assert.Equal(t, NULL, always.ValueType)
+	assert.Len(t, always.Types, 0)
+
+	sub := getPropByName(t, &mainType, "sub")
+	assert.True(t, sub.IsNullable)
+	assert.True(t, sub.IsComplex)
+	assert.Equal(t, "Sub", sub.ValueType)
+}
+
+func TestReplaceTypeReference(t *testing.T) {
+	prop := BasicElemInfo{
+		AttribName: "sub",
+		ValueType:  "Sub2",
+		IsComplex:  true,
+		Types: []ObservedType{
+			{ValueType: "Sub2", IsComplex: true, Count: 2},
+			{ValueType: STRING, Count: 1},
+		},
+	}
+	ReplaceTypeReference(&prop, "Sub2", "Sub")
+	assert.Equal(t, "Sub", prop.ValueType)
+	assert.Equal(t, "Sub", prop.Types[0].ValueType)
+	assert.Equal(t, STRING, prop.Types[1].ValueType)
+
+	// observed types that are the same after the replacement are combined
+	prop.Types = append(prop.Types, ObservedType{ValueType: "Sub3", IsComplex: true, Count: 3})
+	ReplaceTypeReference(&prop, "Sub3", "Sub")
+	assert.Len(t, prop.Types, 2)
+	assert.Equal(t, int64(5), prop.Types[0].Count)
+}
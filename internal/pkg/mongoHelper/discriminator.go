@@ -0,0 +1,207 @@
+package mongoHelper
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// attribute names that are typically used to distinguish entity kinds in one collection
+var knownDiscriminators = []string{"_class", "type", "_type", "kind", "className", "@type", "discriminator", "entityType"}
+
+// Returns the value of the discriminator attribute of the document, the second return value
+// is false if the document has no string value for the attribute
+func discriminatorValue(doc bson.Raw, discriminator string) (string, bool) {
+	value, err := doc.LookupErr(discriminator)
+	if err != nil {
+		return "", false
+	}
+	return value.StringValueOK()
+}
+
+// builds a type name out of a discriminator value like 'com.example.Car' or 'car-v2'
+func discriminatorTypeName(prefix string, value string) string {
+	if value == "" {
+		return prefix + "Unknown"
+	}
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	name := prefix
+	for _, p := range parts {
+		name += firstUpperCase(p)
+	}
+	return name
+}
+
+// Processes the documents split by the value of the discriminator attribute. For every discriminator
+// value a own complex type is created, that references the main type as base type. The main type contains
+// afterwards only the attributes that all variants have in common.
+func ProcessBsonWithDiscriminator(docs []bson.Raw, collectionName string, discriminator string, mainType *ComplexType, otherComplexTypes []ComplexType) ([]ComplexType, error) {
+	if mainType.Name == "" {
+		colNameFirstUpper := firstUpperCase(collectionName)
+		mainType.Name = colNameFirstUpper
+		mainType.LongName = colNameFirstUpper
+	}
+	mainType.Discriminator = discriminator
+	variants := make([]ComplexType, 0)
+	var err error
+	for _, doc := range docs {
+		value, _ := discriminatorValue(doc, discriminator)
+		index := slices.IndexFunc(variants, func(v ComplexType) bool {
+			return v.DiscriminatorValue == value
+		})
+		if index == -1 {
+			name := discriminatorTypeName(mainType.Name, value)
+			name = GetNewTypeName(name, append(otherComplexTypes, variants...))
+			variants = append(variants, ComplexType{
+				Name:               name,
+				LongName:           name,
+				BaseType:           mainType.Name,
+				DiscriminatorValue: value,
+			})
+			index = len(variants) - 1
+		}
+		otherComplexTypes, err = ProcessBson(doc, collectionName, &variants[index], otherComplexTypes)
+		if err != nil {
+			return otherComplexTypes, err
+		}
+	}
+	for _, v := range variants {
+		mainType.SampleCount += v.SampleCount
+		for _, p := range v.Properties {
+			index := slices.IndexFunc(mainType.Properties, func(e BasicElemInfo) bool {
+				return e.AttribName == p.AttribName
+			})
+			if index == -1 {
+				mainType.Properties = append(mainType.Properties, CopyProperty(&p))
+			} else {
+				MergeProperty(&mainType.Properties[index], &p)
+			}
+		}
+	}
+	// the main type keeps only the attributes that are shared by all variants
+	commonProps := make([]BasicElemInfo, 0)
+	for _, p := range mainType.Properties {
+		if !slices.ContainsFunc(variants, func(v ComplexType) bool {
+			return !slices.ContainsFunc(v.Properties, func(vp BasicElemInfo) bool {
+				return vp.AttribName == p.AttribName
+			})
+		}) {
+			commonProps = append(commonProps, p)
+		}
+	}
+	mainType.Properties = commonProps
+	return append(otherComplexTypes, variants...), nil
+}
+
+// Max number of distinct values of a discriminator candidate. It's independent of the enum detection,
+// so the detection also works with disabled enums or with more entity kinds than the enum limit.
+var MaxDiscriminatorValues = 50
+
+type discriminatorCandidate struct {
+	name   string
+	count  int
+	values []string
+	// the attribute has other types than string in some documents, or too many distinct values
+	rejected bool
+}
+
+// Collects the top level attributes, that have a string value in all documents and not more than
+// MaxDiscriminatorValues distinct values
+func discriminatorCandidates(docs []bson.Raw) []string {
+	candidates := make([]*discriminatorCandidate, 0)
+	for _, doc := range docs {
+		elements, err := doc.Elements()
+		if err != nil {
+			continue
+		}
+		for _, e := range elements {
+			index := slices.IndexFunc(candidates, func(c *discriminatorCandidate) bool { return c.name == e.Key() })
+			if index == -1 {
+				candidates = append(candidates, &discriminatorCandidate{name: e.Key()})
+				index = len(candidates) - 1
+			}
+			c := candidates[index]
+			c.count++
+			value, ok := e.Value().StringValueOK()
+			if !ok {
+				c.rejected = true
+			}
+			if c.rejected || slices.Contains(c.values, value) {
+				continue
+			}
+			if len(c.values) >= MaxDiscriminatorValues {
+				c.rejected = true
+				c.values = nil
+				continue
+			}
+			c.values = append(c.values, value)
+		}
+	}
+	ret := make([]string, 0)
+	for _, c := range candidates {
+		if !c.rejected && (c.count == len(docs)) && (len(c.values) > 1) {
+			ret = append(ret, c.name)
+		}
+	}
+	return ret
+}
+
+// Tries to find a discriminator attribute in the documents. Candidates are string attributes that exist in
+// all documents and have only a few distinct values. Known names like '_class' or 'type' are preferred, otherwise
+// the candidate is taken, that splits the documents in groups with the most different attributes.
+// If no candidate is found, an empty string is returned.
+func GuessDiscriminator(docs []bson.Raw) string {
+	candidates := discriminatorCandidates(docs)
+	for _, k := range knownDiscriminators {
+		if slices.Contains(candidates, k) {
+			return k
+		}
+	}
+	ret := ""
+	bestScore := 0
+	for _, c := range candidates {
+		score := discriminatorScore(docs, c)
+		if score > bestScore {
+			bestScore = score
+			ret = c
+		}
+	}
+	return ret
+}
+
+// number of top level attributes that aren't used by all groups of documents
+func discriminatorScore(docs []bson.Raw, discriminator string) int {
+	keysPerValue := make(map[string][]string)
+	allKeys := make([]string, 0)
+	for _, doc := range docs {
+		value, _ := discriminatorValue(doc, discriminator)
+		elements, err := doc.Elements()
+		if err != nil {
+			continue
+		}
+		keys := keysPerValue[value]
+		for _, e := range elements {
+			if !slices.Contains(keys, e.Key()) {
+				keys = append(keys, e.Key())
+			}
+			if !slices.Contains(allKeys, e.Key()) {
+				allKeys = append(allKeys, e.Key())
+			}
+		}
+		keysPerValue[value] = keys
+	}
+	score := 0
+	for _, k := range allKeys {
+		for _, keys := range keysPerValue {
+			if !slices.Contains(keys, k) {
+				score++
+				break
+			}
+		}
+	}
+	return score
+}
@@ -0,0 +1,125 @@
+package mongoHelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func marshalTestDocs(t *testing.T, docs []bson.M) []bson.Raw {
+	ret := make([]bson.Raw, 0)
+	for _, d := range docs {
+		b, err := bson.Marshal(d)
+		require.Nil(t, err)
+		ret = append(ret, b)
+	}
+	return ret
+}
+
+var vehicleDocs = []bson.M{
+	{"name": "a", "kind": "car", "wheels": int32(4), "engine": bson.M{"ps": int32(100)}},
+	{"name": "b", "kind": "bike", "bell": true},
+	{"name": "c", "kind": "car", "wheels": int32(3), "engine": bson.M{"ps": int32(70)}},
+	{"name": "d", "kind": "bike", "bell": false},
+	{"name": "e", "kind": "de.example.Truck-v2", "wheels": int32(8)},
+}
+
+func TestDiscriminatorTypeName(t *testing.T) {
+	assert.Equal(t, "VehiclesCar", discriminatorTypeName("Vehicles", "car"))
+	assert.Equal(t, "VehiclesDeExampleTruckV2", discriminatorTypeName("Vehicles", "de.example.Truck-v2"))
+	assert.Equal(t, "VehiclesUnknown", discriminatorTypeName("Vehicles", ""))
+}
+
+func TestProcessBsonWithDiscriminator(t *testing.T) {
+	var mainType ComplexType
+	otherComplexTypes, err := ProcessBsonWithDiscriminator(marshalTestDocs(t, vehicleDocs), "vehicles", "kind", &mainType, make([]ComplexType, 0))
+	require.Nil(t, err)
+
+	assert.Equal(t, "Vehicles", mainType.Name)
+	assert.Equal(t, "kind", mainType.Discriminator)
+	assert.Equal(t, int64(5), mainType.SampleCount)
+	require.Len(t, mainType.Properties, 2)
+	assert.Equal(t, int64(5), getPropByName(t, &mainType, "name").OccurrenceCount)
+	assert.Equal(t, int64(5), getPropByName(t, &mainType, "kind").OccurrenceCount)
+
+	variantNames := make([]string, 0)
+	for _, ct := range otherComplexTypes {
+		if ct.BaseType != "" {
+			assert.Equal(t, "Vehicles", ct.BaseType)
+			variantNames = append(variantNames, ct.Name)
+		}
+	}
+	assert.Equal(t, []string{"VehiclesCar", "VehiclesBike", "VehiclesDeExampleTruckV2"}, variantNames)
+
+	car := otherComplexTypes[findComplexTypeIndex(t, otherComplexTypes, "VehiclesCar")]
+	assert.Equal(t, "car", car.DiscriminatorValue)
+	assert.Equal(t, int64(2), car.SampleCount)
+	assert.Equal(t, int64(2), getPropByName(t, &car, "wheels").OccurrenceCount)
+	engine := getPropByName(t, &car, "engine")
+	assert.True(t, engine.IsComplex)
+	findComplexTypeIndex(t, otherComplexTypes, engine.ValueType)
+
+	bike := otherComplexTypes[findComplexTypeIndex(t, otherComplexTypes, "VehiclesBike")]
+	assert.Equal(t, int64(2), getPropByName(t, &bike, "bell").OccurrenceCount)
+}
+
+func TestGuessDiscriminator(t *testing.T) {
+	assert.Equal(t, "kind", GuessDiscriminator(marshalTestDocs(t, vehicleDocs)))
+
+	// the known name 'type' is preferred over other candidates
+	docs := []bson.M{
+		{"type": "a", "color": "red", "x": int32(1)},
+		{"type": "b", "color": "blue", "y": int32(1)},
+		{"type": "a", "color": "blue", "x": int32(2)},
+	}
+	assert.Equal(t, "type", GuessDiscriminator(marshalTestDocs(t, docs)))
+
+	// attributes with one value only or missing in some documents aren't candidates
+	docs = []bson.M{
+		{"status": "open", "category": "a"},
+		{"status": "open"},
+	}
+	assert.Equal(t, "", GuessDiscriminator(marshalTestDocs(t, docs)))
+
+	// attributes with null values or other types aren't candidates
+	docs = []bson.M{
+		{"kind": "car", "wheels": int32(4)},
+		{"kind": nil, "bell": true},
+		{"kind": "car", "wheels": int32(3)},
+	}
+	assert.Equal(t, "", GuessDiscriminator(marshalTestDocs(t, docs)))
+}
+
+// the detection doesn't depend on the enum detection
+func TestGuessDiscriminatorWithoutEnums(t *testing.T) {
+	oldMaxDistinctValues := MaxDistinctValues
+	defer func() { MaxDistinctValues = oldMaxDistinctValues }()
+	MaxDistinctValues = 0
+	assert.Equal(t, "kind", GuessDiscriminator(marshalTestDocs(t, vehicleDocs)))
+
+	// more entity kinds than the enum limit
+	MaxDistinctValues = 2
+	assert.Equal(t, "kind", GuessDiscriminator(marshalTestDocs(t, vehicleDocs)))
+}
+
+func TestGuessDiscriminatorMaxValues(t *testing.T) {
+	oldMaxDiscriminatorValues := MaxDiscriminatorValues
+	defer func() { MaxDiscriminatorValues = oldMaxDiscriminatorValues }()
+	MaxDiscriminatorValues = 2
+	// 'kind' has three distinct values
+	assert.Equal(t, "", GuessDiscriminator(marshalTestDocs(t, vehicleDocs)))
+	MaxDiscriminatorValues = 3
+	assert.Equal(t, "kind", GuessDiscriminator(marshalTestDocs(t, vehicleDocs)))
+}
+
+func findComplexTypeIndex(t *testing.T, complexTypes []ComplexType, name string) int {
+	for i, ct := range complexTypes {
+		if ct.Name == name {
+			return i
+		}
+	}
+	t.Fatalf("complex type not found: %s", name)
+	return -1
+}
@@ -9,6 +9,7 @@ import (
 	"log"
 	"okieoth/schemaguesser/internal/pkg/utils"
 	"os"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -19,6 +20,56 @@ import (
 
 type HandleDataCallback func(bson.Raw) error
 
+// Options to control which documents are queried
+type QueryOptions struct {
+	// one of the SAMPLING_* modes, empty is handled like SAMPLING_FIRST
+	Sampling   string
+	Filter     bson.D
+	Projection bson.D
+	Sort       bson.D
+}
+
+// Parses a query document that is given as MongoDB Extended JSON, an empty string returns nil
+func ParseExtJson(str string) (bson.D, error) {
+	if strings.TrimSpace(str) == "" {
+		return nil, nil
+	}
+	var ret bson.D
+	if err := bson.UnmarshalExtJSON([]byte(str), false, &ret); err != nil {
+		return nil, fmt.Errorf("error while parsing extended JSON '%s': %v", str, err)
+	}
+	return ret, nil
+}
+
+func NewQueryOptions(sampling string, filter string, projection string, sort string) (QueryOptions, error) {
+	var ret QueryOptions
+	var err error
+	if err = CheckSamplingMode(sampling); err != nil {
+		return ret, err
+	}
+	ret.Sampling = sampling
+	if ret.Filter, err = ParseExtJson(filter); err != nil {
+		return ret, err
+	}
+	if ret.Projection, err = ParseExtJson(projection); err != nil {
+		return ret, err
+	}
+	if ret.Sort, err = ParseExtJson(sort); err != nil {
+		return ret, err
+	}
+	if (len(ret.Sort) > 0) && (sampling != "") && (sampling != SAMPLING_FIRST) {
+		return ret, fmt.Errorf("a sort can only be combined with the sampling '%s'", SAMPLING_FIRST)
+	}
+	return ret, nil
+}
+
+func queryFilter(queryOptions QueryOptions) interface{} {
+	if queryOptions.Filter == nil {
+		return bson.M{}
+	}
+	return queryOptions.Filter
+}
+
 var ConStr string
 
 func Connect(conStr string) (*mongo.Client, error) {
@@ -94,98 +145,111 @@ func ListIndexes(client *mongo.Client, databaseName string, collectionName strin
 	return ret, nil
 }
 
-func queryCollectionWithAggregation(client *mongo.Client, databaseName string, collectionName string, itemCount int, handleDataCallback HandleDataCallback) error {
+func queryCollectionWithAggregation(client *mongo.Client, databaseName string, collectionName string, itemCount int, queryOptions QueryOptions, handleDataCallback HandleDataCallback) error {
 	db := client.Database(databaseName)
 	collection := db.Collection(collectionName)
 
-	// Define a simple aggregation pipeline that acts like a find
-	pipeline := mongo.Pipeline{
-		{{"$match", bson.M{}}}, // Add any match conditions if needed
-		{{"$limit", itemCount}},
-	}
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Set allowDiskUse to true in aggregation options
-	aggregationOptions := options.Aggregate().SetAllowDiskUse(true)
-
-	startTime := time.Now()
-	cursor, err := collection.Aggregate(ctx, pipeline, aggregationOptions)
+	queryParts, err := getQueryParts(ctx, collection, int64(itemCount), queryOptions)
 	if err != nil {
-		log.Printf("[%s:%s] Collection query error: %v\n", databaseName, collectionName, err)
+		log.Printf("[%s:%s] Error while preparing the query: %v\n", databaseName, collectionName, err)
 		return err
 	}
-	log.Printf("[%s:%s] Collection query executed in %v\n", databaseName, collectionName, time.Since(startTime))
 
-	for cursor.Next(ctx) {
-		bsonRaw := cursor.Current
-		if err := handleDataCallback(bsonRaw); err != nil {
-			log.Printf("[%s:%s] error while processing the data: %v\n", databaseName, collectionName, err)
+	// Set allowDiskUse to true in aggregation options
+	aggregationOptions := options.Aggregate().SetAllowDiskUse(true)
+
+	for _, part := range queryParts {
+		startTime := time.Now()
+		cursor, err := collection.Aggregate(ctx, queryPartPipeline(part, queryOptions), aggregationOptions)
+		if err != nil {
+			log.Printf("[%s:%s] Collection query error: %v\n", databaseName, collectionName, err)
 			return err
 		}
+		log.Printf("[%s:%s] Collection query executed in %v\n", databaseName, collectionName, time.Since(startTime))
+
+		for cursor.Next(ctx) {
+			bsonRaw := cursor.Current
+			if err := handleDataCallback(bsonRaw); err != nil {
+				log.Printf("[%s:%s] error while processing the data: %v\n", databaseName, collectionName, err)
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
-func queryCollection(client *mongo.Client, databaseName string, collectionName string, itemCount int, mongo44 bool, handleDataCallback HandleDataCallback) error {
+func queryCollection(client *mongo.Client, databaseName string, collectionName string, itemCount int, mongo44 bool, queryOptions QueryOptions, handleDataCallback HandleDataCallback) error {
+	if queryOptions.Sampling == SAMPLING_RANDOM {
+		// random samples are only possible with an aggregation
+		return queryCollectionWithAggregation(client, databaseName, collectionName, itemCount, queryOptions, handleDataCallback)
+	}
 	db := client.Database(databaseName)
 	collection := db.Collection(collectionName)
-	// setAllowDiskUse requires mongodb 4.4 at minimum
-	startTime := time.Now()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	findOptions := options.Find().SetLimit(int64(itemCount))
-	if mongo44 {
-		findOptions = findOptions.SetAllowDiskUse(true)
-	}
-	cursor, err := collection.Find(ctx, bson.M{}, findOptions)
+	queryParts, err := getQueryParts(ctx, collection, int64(itemCount), queryOptions)
 	if err != nil {
-		//panic(err)
-		log.Printf("[%s:%s] Collection query error: %v\n", databaseName, collectionName, err)
+		log.Printf("[%s:%s] Error while preparing the query: %v\n", databaseName, collectionName, err)
 		return err
 	}
-	log.Printf("Query executed in %v\n", time.Since(startTime))
 
-	for cursor.Next(ctx) {
-		bsonRaw := cursor.Current
-		if err := handleDataCallback(bsonRaw); err != nil {
-			log.Printf("[%s:%s] error while processing the data: %v\n", databaseName, collectionName, err)
+	for _, part := range queryParts {
+		startTime := time.Now()
+		findOptions := options.Find().SetLimit(part.limit)
+		// setAllowDiskUse requires mongodb 4.4 at minimum
+		if mongo44 {
+			findOptions = findOptions.SetAllowDiskUse(true)
+		}
+		if len(part.sort) > 0 {
+			findOptions = findOptions.SetSort(part.sort)
+		}
+		if part.skip > 0 {
+			findOptions = findOptions.SetSkip(part.skip)
+		}
+		if len(queryOptions.Projection) > 0 {
+			findOptions = findOptions.SetProjection(queryOptions.Projection)
+		}
+		cursor, err := collection.Find(ctx, queryFilter(queryOptions), findOptions)
+		if err != nil {
+			//panic(err)
+			log.Printf("[%s:%s] Collection query error: %v\n", databaseName, collectionName, err)
 			return err
 		}
+		log.Printf("Query executed in %v\n", time.Since(startTime))
+
+		for cursor.Next(ctx) {
+			bsonRaw := cursor.Current
+			if err := handleDataCallback(bsonRaw); err != nil {
+				log.Printf("[%s:%s] error while processing the data: %v\n", databaseName, collectionName, err)
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
-func DumpCollectionToFile(ctx context.Context, outputFile *os.File, client *mongo.Client, databaseName string, collectionName string, itemCount int64, useAggregation bool, mongo44 bool) (uint64, error) {
+func DumpCollectionToFile(ctx context.Context, outputFile *os.File, client *mongo.Client, databaseName string, collectionName string, itemCount int64, useAggregation bool, mongo44 bool, queryOptions QueryOptions) (uint64, error) {
 	if useAggregation {
-		return dumpCollectionWithAggregationToFile(ctx, outputFile, client, databaseName, collectionName, itemCount)
+		return dumpCollectionWithAggregationToFile(ctx, outputFile, client, databaseName, collectionName, itemCount, queryOptions)
 	} else {
-		return dumpCollectionToFile(ctx, outputFile, client, databaseName, collectionName, itemCount, mongo44)
+		return dumpCollectionToFile(ctx, outputFile, client, databaseName, collectionName, itemCount, mongo44, queryOptions)
 	}
 }
 
-func dumpCollectionWithAggregationToFile(ctx context.Context, outputFile *os.File, client *mongo.Client, databaseName string, collectionName string, itemCount int64) (uint64, error) {
+func dumpCollectionWithAggregationToFile(ctx context.Context, outputFile *os.File, client *mongo.Client, databaseName string, collectionName string, itemCount int64, queryOptions QueryOptions) (uint64, error) {
 	db := client.Database(databaseName)
 	collection := db.Collection(collectionName)
 	// setAllowDiskUse requires mongodb 4.4 at minimum
 	startTime := time.Now()
 
 	// Define a simple aggregation pipeline that acts like a find
-
-	var pipeline []bson.D
-	if itemCount > 0 {
-		pipeline = mongo.Pipeline{
-			{{"$match", bson.M{}}}, // Add any match conditions if needed
-			{{"$limit", itemCount}},
-		}
-	} else {
-		pipeline = mongo.Pipeline{
-			{{"$match", bson.M{}}}, // Add any match conditions if needed
-		}
-	}
+	pipeline := queryPartPipeline(queryPart{sort: queryOptions.Sort, limit: itemCount}, queryOptions)
 
 	// Set allowDiskUse to true in aggregation options
 	aggregationOptions := options.Aggregate().SetAllowDiskUse(true)
@@ -210,7 +274,7 @@ func dumpCollectionWithAggregationToFile(ctx context.Context, outputFile *os.Fil
 	return dumpCount, nil
 }
 
-func dumpCollectionToFile(ctx context.Context, outputFile *os.File, client *mongo.Client, databaseName string, collectionName string, itemCount int64, mongo44 bool) (uint64, error) {
+func dumpCollectionToFile(ctx context.Context, outputFile *os.File, client *mongo.Client, databaseName string, collectionName string, itemCount int64, mongo44 bool, queryOptions QueryOptions) (uint64, error) {
 	db := client.Database(databaseName)
 	collection := db.Collection(collectionName)
 	// setAllowDiskUse requires mongodb 4.4 at minimum
@@ -223,7 +287,13 @@ func dumpCollectionToFile(ctx context.Context, outputFile *os.File, client *mong
 			findOptions = findOptions.SetAllowDiskUse(true)
 		}
 	}
-	cursor, err := collection.Find(ctx, bson.M{}, findOptions)
+	if len(queryOptions.Sort) > 0 {
+		findOptions.SetSort(queryOptions.Sort)
+	}
+	if len(queryOptions.Projection) > 0 {
+		findOptions.SetProjection(queryOptions.Projection)
+	}
+	cursor, err := collection.Find(ctx, queryFilter(queryOptions), findOptions)
 	if err != nil {
 		//panic(err)
 		log.Printf("[%s:%s] dumpCollectionToFile - Collection query error: %v\n", databaseName, collectionName, err)
@@ -305,11 +375,11 @@ func insertChunk(documents []interface{}, collection *mongo.Collection) error {
 }
 
 // This version only works from mongodb v4.4
-func QueryCollection(client *mongo.Client, databaseName string, collectionName string, itemCount int, useAggregation bool, mongo44 bool, handleDataCallback HandleDataCallback) error {
+func QueryCollection(client *mongo.Client, databaseName string, collectionName string, itemCount int, useAggregation bool, mongo44 bool, queryOptions QueryOptions, handleDataCallback HandleDataCallback) error {
 	if useAggregation {
-		return queryCollectionWithAggregation(client, databaseName, collectionName, itemCount, handleDataCallback)
+		return queryCollectionWithAggregation(client, databaseName, collectionName, itemCount, queryOptions, handleDataCallback)
 	} else {
-		return queryCollection(client, databaseName, collectionName, itemCount, mongo44, handleDataCallback)
+		return queryCollection(client, databaseName, collectionName, itemCount, mongo44, queryOptions, handleDataCallback)
 	}
 }
 
@@ -319,7 +389,7 @@ func CountCollection(client *mongo.Client, dbName string, collName string) (int6
 	startTime := time.Now()
 
 	pipeline := mongo.Pipeline{
-		{{"$count", "totalCount"}},
+		{{Key: "$count", Value: "totalCount"}},
 	}
 
 	// Set aggregation options with AllowDiskUse
@@ -371,3 +441,16 @@ func ReadDatabasesOrPanic(client *mongo.Client) []string {
 	}
 	return dbs
 }
+
+// Sets the validator of an existing collection with the 'collMod' command
+func ApplyValidator(client *mongo.Client, dbName string, collName string, validator bson.D, validationLevel string, validationAction string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	command := bson.D{
+		{Key: "collMod", Value: collName},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: validationLevel},
+		{Key: "validationAction", Value: validationAction},
+	}
+	return client.Database(dbName).RunCommand(ctx, command).Err()
+}
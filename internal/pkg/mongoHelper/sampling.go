@@ -0,0 +1,114 @@
+package mongoHelper
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// takes the first documents in natural order
+const SAMPLING_FIRST = "first"
+
+// takes random documents by the use of '$sample'
+const SAMPLING_RANDOM = "random"
+
+// takes the newest documents, sorted by '_id' descending
+const SAMPLING_LATEST = "latest"
+
+// takes documents from evenly spaced ranges over the '_id' order
+const SAMPLING_SPREAD = "spread"
+
+var SamplingModes = []string{SAMPLING_FIRST, SAMPLING_RANDOM, SAMPLING_LATEST, SAMPLING_SPREAD}
+
+// max number of ranges that are queried for the spread sampling
+const spreadRangeCount = 10
+
+// Describes one query that needs to be executed to get (a part of) the sample documents
+type queryPart struct {
+	sort   bson.D
+	skip   int64
+	limit  int64
+	sample bool
+}
+
+func CheckSamplingMode(sampling string) error {
+	if (sampling != "") && !slices.Contains(SamplingModes, sampling) {
+		return fmt.Errorf("unknown sampling mode '%s', possible values are: %v", sampling, SamplingModes)
+	}
+	return nil
+}
+
+func getQueryParts(ctx context.Context, collection *mongo.Collection, itemCount int64, queryOptions QueryOptions) ([]queryPart, error) {
+	if err := CheckSamplingMode(queryOptions.Sampling); err != nil {
+		return nil, err
+	}
+	switch queryOptions.Sampling {
+	case SAMPLING_RANDOM:
+		return []queryPart{{limit: itemCount, sample: true}}, nil
+	case SAMPLING_LATEST:
+		return []queryPart{{sort: bson.D{{Key: "_id", Value: -1}}, limit: itemCount}}, nil
+	case SAMPLING_SPREAD:
+		var count int64
+		var err error
+		if len(queryOptions.Filter) > 0 {
+			count, err = collection.CountDocuments(ctx, queryOptions.Filter)
+		} else {
+			count, err = collection.EstimatedDocumentCount(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return spreadQueryParts(count, itemCount), nil
+	default:
+		return []queryPart{{sort: queryOptions.Sort, limit: itemCount}}, nil
+	}
+}
+
+// splits the documents, sorted by '_id', in ranges of the same size and takes
+// from the start of every range the same number of documents
+func spreadQueryParts(documentCount int64, itemCount int64) []queryPart {
+	sort := bson.D{{Key: "_id", Value: 1}}
+	if (itemCount <= 0) || (documentCount <= itemCount) {
+		return []queryPart{{sort: sort, limit: itemCount}}
+	}
+	rangeCount := min(int64(spreadRangeCount), itemCount)
+	ret := make([]queryPart, 0, rangeCount)
+	for i := int64(0); i < rangeCount; i++ {
+		limit := itemCount / rangeCount
+		if i == rangeCount-1 {
+			limit += itemCount % rangeCount
+		}
+		ret = append(ret, queryPart{
+			sort:  sort,
+			skip:  i * (documentCount / rangeCount),
+			limit: limit,
+		})
+	}
+	return ret
+}
+
+func queryPartPipeline(p queryPart, queryOptions QueryOptions) mongo.Pipeline {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: queryFilter(queryOptions)}},
+	}
+	if p.sample {
+		pipeline = append(pipeline, bson.D{{Key: "$sample", Value: bson.M{"size": p.limit}}})
+	} else {
+		if len(p.sort) > 0 {
+			pipeline = append(pipeline, bson.D{{Key: "$sort", Value: p.sort}})
+		}
+		if p.skip > 0 {
+			pipeline = append(pipeline, bson.D{{Key: "$skip", Value: p.skip}})
+		}
+		if p.limit > 0 {
+			pipeline = append(pipeline, bson.D{{Key: "$limit", Value: p.limit}})
+		}
+	}
+	if len(queryOptions.Projection) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$project", Value: queryOptions.Projection}})
+	}
+	return pipeline
+}
@@ -0,0 +1,76 @@
+package mongoHelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCheckSamplingMode(t *testing.T) {
+	for _, m := range SamplingModes {
+		assert.Nil(t, CheckSamplingMode(m))
+	}
+	assert.Nil(t, CheckSamplingMode(""))
+	assert.NotNil(t, CheckSamplingMode("middle"))
+}
+
+func TestSpreadQueryParts(t *testing.T) {
+	// small collections are queried completely
+	parts := spreadQueryParts(50, 100)
+	require.Len(t, parts, 1)
+	assert.Equal(t, int64(0), parts[0].skip)
+	assert.Equal(t, int64(100), parts[0].limit)
+
+	parts = spreadQueryParts(10000, 105)
+	require.Len(t, parts, spreadRangeCount)
+	var sum int64
+	for i, p := range parts {
+		assert.Equal(t, int64(i*1000), p.skip)
+		assert.Equal(t, bson.D{{Key: "_id", Value: 1}}, p.sort)
+		sum += p.limit
+	}
+	assert.Equal(t, int64(105), sum)
+	assert.Equal(t, int64(15), parts[spreadRangeCount-1].limit)
+
+	parts = spreadQueryParts(10000, 3)
+	require.Len(t, parts, 3)
+	assert.Equal(t, int64(3333), parts[1].skip)
+	assert.Equal(t, int64(1), parts[1].limit)
+}
+
+func TestQueryPartPipeline(t *testing.T) {
+	pipeline := queryPartPipeline(queryPart{limit: 10, sample: true}, QueryOptions{})
+	require.Len(t, pipeline, 2)
+	assert.Equal(t, "$sample", pipeline[1][0].Key)
+
+	queryOptions := QueryOptions{Projection: bson.D{{Key: "payload", Value: 0}}}
+	pipeline = queryPartPipeline(queryPart{sort: bson.D{{Key: "_id", Value: -1}}, skip: 5, limit: 10}, queryOptions)
+	require.Len(t, pipeline, 5)
+	assert.Equal(t, "$match", pipeline[0][0].Key)
+	assert.Equal(t, "$sort", pipeline[1][0].Key)
+	assert.Equal(t, "$skip", pipeline[2][0].Key)
+	assert.Equal(t, "$limit", pipeline[3][0].Key)
+	assert.Equal(t, "$project", pipeline[4][0].Key)
+}
+
+func TestNewQueryOptions(t *testing.T) {
+	queryOptions, err := NewQueryOptions("", `{"type": "invoice", "created": {"$gte": {"$date": "2024-01-01T00:00:00Z"}}}`, `{"payload": 0}`, `{"created": -1}`)
+	require.Nil(t, err)
+	require.Len(t, queryOptions.Filter, 2)
+	assert.Equal(t, "type", queryOptions.Filter[0].Key)
+	assert.Equal(t, "invoice", queryOptions.Filter[0].Value)
+	assert.Equal(t, bson.D{{Key: "payload", Value: int32(0)}}, queryOptions.Projection)
+	assert.Equal(t, bson.D{{Key: "created", Value: int32(-1)}}, queryOptions.Sort)
+
+	queryOptions, err = NewQueryOptions(SAMPLING_RANDOM, "", "", "")
+	require.Nil(t, err)
+	assert.Nil(t, queryOptions.Filter)
+	assert.Equal(t, bson.M{}, queryFilter(queryOptions))
+
+	_, err = NewQueryOptions("", "{type: invoice", "", "")
+	assert.NotNil(t, err)
+	_, err = NewQueryOptions(SAMPLING_LATEST, "", "", `{"created": -1}`)
+	assert.NotNil(t, err)
+}
@@ -17,6 +17,8 @@ import (
 
 var KeepNullUuids bool
 
+var uuidRegex = regexp.MustCompile(`^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-[a-fA-F0-9]{4}-[a-fA-F0-9]{4}-[a-fA-F0-9]{12}$`)
+
 func checkIfStringIsUUIDString(value bson.RawValue) (bool, error) {
 	if value.Type != bson.TypeString {
 		return false, errors.New("value is not of type string")
@@ -24,8 +26,6 @@ func checkIfStringIsUUIDString(value bson.RawValue) (bool, error) {
 
 	str := value.StringValue()
 
-	uuidRegex := regexp.MustCompile(`^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-[a-fA-F0-9]{4}-[a-fA-F0-9]{4}-[a-fA-F0-9]{12}$`)
-
 	if uuidRegex.MatchString(str) {
 		return true, nil
 	}
@@ -0,0 +1,172 @@
+package mongoHelper
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const FORMAT_UUID = "uuid"
+const FORMAT_DATE_TIME = "date-time"
+const FORMAT_DATE = "date"
+const FORMAT_EMAIL = "email"
+const FORMAT_IPV4 = "ipv4"
+const FORMAT_IPV6 = "ipv6"
+const FORMAT_URI = "uri"
+
+// checked formats, in case that more than one format matches the first one wins
+var stringFormats = []string{FORMAT_UUID, FORMAT_DATE_TIME, FORMAT_DATE, FORMAT_EMAIL, FORMAT_IPV4, FORMAT_IPV6, FORMAT_URI}
+
+// min ratio of string values that needs to match a format, before the format is set for an attribute.
+// 0 disables the format detection
+var StringFormatMatchRatio = 1.0
+
+var emailRegex = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+func matchesStringFormat(value string, format string) bool {
+	switch format {
+	case FORMAT_UUID:
+		return uuidRegex.MatchString(value)
+	case FORMAT_DATE_TIME:
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case FORMAT_DATE:
+		_, err := time.Parse(time.DateOnly, value)
+		return err == nil
+	case FORMAT_EMAIL:
+		return emailRegex.MatchString(value)
+	case FORMAT_IPV4:
+		ip := net.ParseIP(value)
+		return (ip != nil) && (ip.To4() != nil) && strings.Contains(value, ".")
+	case FORMAT_IPV6:
+		ip := net.ParseIP(value)
+		return (ip != nil) && strings.Contains(value, ":")
+	case FORMAT_URI:
+		u, err := url.Parse(value)
+		return (err == nil) && (u.Scheme != "") && ((u.Host != "") || (u.Opaque != ""))
+	}
+	return false
+}
+
+// returns all formats that the string value matches
+func detectStringFormats(value string) map[string]int64 {
+	var ret map[string]int64
+	for _, f := range stringFormats {
+		if matchesStringFormat(value, f) {
+			if ret == nil {
+				ret = make(map[string]int64)
+			}
+			ret[f] = 1
+		}
+	}
+	return ret
+}
+
+func addStringFormats(prop *BasicElemInfo, formats map[string]int64) {
+	for f, c := range formats {
+		if prop.StringFormats == nil {
+			prop.StringFormats = make(map[string]int64)
+		}
+		prop.StringFormats[f] += c
+	}
+}
+
+// formats of the string elements of one array
+type arrayStringFormats struct {
+	stringCount int64
+	formats     map[string]int64
+}
+
+func (f *arrayStringFormats) add(item *BasicElemInfo) {
+	if item.BsonType != STRING {
+		return
+	}
+	f.stringCount++
+	for format, c := range item.StringFormats {
+		if f.formats == nil {
+			f.formats = make(map[string]int64)
+		}
+		f.formats[format] += c
+	}
+}
+
+// The observed types of arrays are counted once per array, so are the formats. An array matches a
+// format, if enough of its string elements match it.
+func (f *arrayStringFormats) arrayFormats() map[string]int64 {
+	var ret map[string]int64
+	for format, matches := range f.formats {
+		if (matches > 0) && (float64(matches) >= StringFormatMatchRatio*float64(f.stringCount)) {
+			if ret == nil {
+				ret = make(map[string]int64)
+			}
+			ret[format] = 1
+		}
+	}
+	return ret
+}
+
+// Sets the format of string attributes and arrays of strings, in case that enough of the observed
+// values match one of the known formats
+func applyStringFormat(prop *BasicElemInfo) {
+	if (StringFormatMatchRatio <= 0) || (prop.ValueType != STRING) || (prop.BsonType != STRING) {
+		return
+	}
+	var stringCount int64
+	for _, t := range prop.Types {
+		if (t.BsonType == STRING) && (t.IsArray == prop.IsArray) && (t.ArrayDimensions == prop.ArrayDimensions) {
+			stringCount += t.Count
+		}
+	}
+	if stringCount == 0 {
+		return
+	}
+	for _, f := range stringFormats {
+		matches := prop.StringFormats[f]
+		if (matches > 0) && (float64(matches) >= StringFormatMatchRatio*float64(stringCount)) {
+			prop.Format = f
+			return
+		}
+	}
+}
+
+// key formats that are typical for dictionaries, additionally to uuid, date-time and date
+const KEY_FORMAT_OBJECT_ID = "objectId"
+const KEY_FORMAT_NUMERIC = "numeric"
+
+var dictKeyFormats = []string{FORMAT_UUID, KEY_FORMAT_OBJECT_ID, FORMAT_DATE_TIME, FORMAT_DATE, KEY_FORMAT_NUMERIC}
+
+var objectIdHexRegex = regexp.MustCompile(`^[a-fA-F0-9]{24}$`)
+var numericRegex = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+func matchesKeyFormat(key string, format string) bool {
+	switch format {
+	case KEY_FORMAT_OBJECT_ID:
+		return objectIdHexRegex.MatchString(key)
+	case KEY_FORMAT_NUMERIC:
+		return numericRegex.MatchString(key)
+	}
+	return matchesStringFormat(key, format)
+}
+
+// Returns the format that all keys match, e.g. 'uuid' or 'numeric'. If the keys
+// have no common format, an empty string is returned.
+func GuessKeyFormat(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	for _, f := range dictKeyFormats {
+		matchesAll := true
+		for _, k := range keys {
+			if !matchesKeyFormat(k, f) {
+				matchesAll = false
+				break
+			}
+		}
+		if matchesAll {
+			return f
+		}
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package mongoHelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMatchesStringFormat(t *testing.T) {
+	tests := []struct {
+		value  string
+		format string
+		expect bool
+	}{
+		{"056bcf58-e17e-42ba-8186-f25ffbde8b35", FORMAT_UUID, true},
+		{"056bcf58-e17e-42ba-8186", FORMAT_UUID, false},
+		{"2024-03-01T12:30:00Z", FORMAT_DATE_TIME, true},
+		{"2024-03-01T12:30:00.123+02:00", FORMAT_DATE_TIME, true},
+		{"2024-03-01", FORMAT_DATE_TIME, false},
+		{"2024-03-01", FORMAT_DATE, true},
+		{"2024-13-01", FORMAT_DATE, false},
+		{"max.mustermann@example.com", FORMAT_EMAIL, true},
+		{"max.mustermann", FORMAT_EMAIL, false},
+		{"192.168.0.1", FORMAT_IPV4, true},
+		{"192.168.0.1", FORMAT_IPV6, false},
+		{"fe80::1", FORMAT_IPV6, true},
+		{"fe80::1", FORMAT_IPV4, false},
+		{"https://github.com/OkieOth/mschemaguesser", FORMAT_URI, true},
+		{"mailto:max@example.com", FORMAT_URI, true},
+		{"/just/a/path", FORMAT_URI, false},
+		{"some text", FORMAT_URI, false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expect, matchesStringFormat(test.value, test.format), "value: %s, format: %s", test.value, test.format)
+	}
+}
+
+func TestProcessBsonStringFormats(t *testing.T) {
+	oldRatio := StringFormatMatchRatio
+	defer func() { StringFormatMatchRatio = oldRatio }()
+
+	docs := []bson.M{
+		{"mail": "a@example.com", "ip": "10.0.0.1", "day": "2024-01-01"},
+		{"mail": "b@example.com", "ip": "10.0.0.2", "day": "2024-01-02"},
+		{"mail": "c@example.com", "ip": "10.0.0.3", "day": "2024-01-03"},
+		{"mail": "no mail", "ip": "10.0.0.4", "day": int32(3)},
+	}
+	StringFormatMatchRatio = 1.0
+	mainType, _ := processTestDocs(t, "test", docs)
+	assert.Equal(t, "", getPropByName(t, &mainType, "mail").Format)
+	assert.Equal(t, FORMAT_IPV4, getPropByName(t, &mainType, "ip").Format)
+	// the integer value doesn't count for the string format
+	assert.Equal(t, FORMAT_DATE, getPropByName(t, &mainType, "day").Format)
+
+	StringFormatMatchRatio = 0.75
+	mainType, _ = processTestDocs(t, "test", docs)
+	assert.Equal(t, FORMAT_EMAIL, getPropByName(t, &mainType, "mail").Format)
+
+	StringFormatMatchRatio = 0
+	mainType, _ = processTestDocs(t, "test", docs)
+	assert.Equal(t, "", getPropByName(t, &mainType, "ip").Format)
+}
+
+func TestProcessBsonArrayStringFormats(t *testing.T) {
+	oldRatio := StringFormatMatchRatio
+	defer func() { StringFormatMatchRatio = oldRatio }()
+
+	docs := []bson.M{
+		{
+			"mails":  bson.A{"a@example.com", "b@example.com"},
+			"ids":    bson.A{bson.A{"056bcf58-e17e-42ba-8186-f25ffbde8b35"}, bson.A{"44f371bb-3603-4b8b-ba83-35ae9b036085"}},
+			"mixed":  bson.A{"a@example.com", "no mail"},
+			"scalar": bson.A{"2024-01-01", int32(3)},
+		},
+		{
+			"mails":  bson.A{"c@example.com"},
+			"ids":    bson.A{},
+			"mixed":  bson.A{"b@example.com", "still no mail"},
+			"scalar": bson.A{"2024-01-02"},
+		},
+	}
+	StringFormatMatchRatio = 1.0
+	mainType, _ := processTestDocs(t, "test", docs)
+	mails := getPropByName(t, &mainType, "mails")
+	assert.True(t, mails.IsArray)
+	assert.Equal(t, FORMAT_EMAIL, mails.Format)
+	assert.Equal(t, FORMAT_UUID, getPropByName(t, &mainType, "ids").Format)
+	assert.Equal(t, "", getPropByName(t, &mainType, "mixed").Format)
+	// the integer element doesn't count for the string format
+	assert.Equal(t, FORMAT_DATE, getPropByName(t, &mainType, "scalar").Format)
+
+	StringFormatMatchRatio = 0.5
+	mainType, _ = processTestDocs(t, "test", docs)
+	assert.Equal(t, FORMAT_EMAIL, getPropByName(t, &mainType, "mixed").Format)
+}
+
+func TestGuessKeyFormat(t *testing.T) {
+	tests := []struct {
+		keys   []string
+		expect string
+	}{
+		{[]string{"056bcf58-e17e-42ba-8186-f25ffbde8b35", "44f371bb-3603-4b8b-ba83-35ae9b036085"}, FORMAT_UUID},
+		{[]string{"65f1c2a4e13b8a0012345678", "65F1C2A4E13B8A0012345679"}, KEY_FORMAT_OBJECT_ID},
+		{[]string{"2024-03-01", "2024-03-02"}, FORMAT_DATE},
+		{[]string{"2024-03-01T12:30:00Z"}, FORMAT_DATE_TIME},
+		{[]string{"1", "42", "-3"}, KEY_FORMAT_NUMERIC},
+		{[]string{"1", "name"}, ""},
+		{[]string{"street", "city"}, ""},
+		{[]string{}, ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expect, GuessKeyFormat(test.keys), "keys: %v", test.keys)
+	}
+}
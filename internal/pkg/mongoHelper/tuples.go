@@ -0,0 +1,102 @@
+package mongoHelper
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Returns the types of the array elements per position. The second return value is false, if the
+// array can't be a tuple, because it contains nested arrays. Embedded documents reference the
+// complex type out of the already collected item types. Empty arrays return no types.
+func tupleItemTypes(value bson.RawValue, itemTypes []ObservedType) ([]ObservedType, bool) {
+	elements, err := bson.Raw(value.Value).Elements()
+	if err != nil {
+		return nil, false
+	}
+	var ret []ObservedType
+	for _, elem := range elements {
+		var itemType ObservedType
+		switch elem.Value().Type {
+		case bson.TypeArray:
+			return nil, false
+		case bson.TypeEmbeddedDocument:
+			found := false
+			for _, t := range itemTypes {
+				if t.IsComplex && (t.ArrayDimensions == 1) {
+					itemType = t
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, false
+			}
+			itemType.IsArray = false
+			itemType.ArrayDimensions = 0
+		default:
+			itemInfo := BasicElemInfo{}
+			handleBasicType(elem, &itemInfo)
+			if itemInfo.IsNullable {
+				// null elements are no hint for a tuple
+				return nil, false
+			}
+			itemType = newObservedType(&itemInfo)
+		}
+		itemType.Count = 1
+		ret = append(ret, itemType)
+	}
+	return ret, true
+}
+
+// Merges the element types per position of one more array into the attribute. As soon as an array
+// has another length or another type at one position, the attribute is no tuple any longer.
+func addTupleItems(prop *BasicElemInfo, items []ObservedType, noTuple bool) {
+	if prop.NoTuple {
+		return
+	}
+	if noTuple {
+		prop.NoTuple = true
+		prop.TupleItems = nil
+		return
+	}
+	if len(items) == 0 {
+		return
+	}
+	if len(prop.TupleItems) == 0 {
+		prop.TupleItems = items
+		return
+	}
+	if len(prop.TupleItems) != len(items) {
+		prop.NoTuple = true
+		prop.TupleItems = nil
+		return
+	}
+	for i := range items {
+		if !sameObservedType(&prop.TupleItems[i], &items[i]) {
+			prop.NoTuple = true
+			prop.TupleItems = nil
+			return
+		}
+	}
+	for i := range items {
+		mergeObservedType(&prop.TupleItems[i], &items[i])
+	}
+}
+
+// An attribute is considered as tuple, if all observed values are one dimensional arrays with the
+// same length (at least two elements) and not all positions have the same type
+func IsTuple(prop *BasicElemInfo) bool {
+	if prop.NoTuple || (len(prop.TupleItems) < 2) || (len(prop.Types) == 0) {
+		return false
+	}
+	for _, t := range prop.Types {
+		if !t.IsArray || (t.ArrayDimensions != 1) {
+			return false
+		}
+	}
+	for _, t := range prop.TupleItems[1:] {
+		if !sameObservedType(&t, &prop.TupleItems[0]) {
+			return true
+		}
+	}
+	return false
+}
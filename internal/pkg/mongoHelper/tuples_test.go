@@ -0,0 +1,59 @@
+package mongoHelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestProcessBsonTuples(t *testing.T) {
+	docs := []bson.M{
+		{"point": bson.A{"a", int32(1), 1.5}, "pair": bson.A{"x", int32(1)}, "list": bson.A{int32(1), int32(2)}, "sub": bson.A{"k", bson.M{"v": 1}}},
+		{"point": bson.A{"b", int32(2), 2.5}, "pair": bson.A{"y", int32(2), int32(3)}, "list": bson.A{int32(3), int32(4)}, "sub": bson.A{"l", bson.M{"v": 2}}},
+		{"point": bson.A{}, "pair": bson.A{"z", "z"}, "list": bson.A{int32(5), int32(6)}, "sub": bson.A{"m", bson.M{"w": 3}}},
+	}
+	mainType, _ := processTestDocs(t, "test", docs)
+
+	point := getPropByName(t, &mainType, "point")
+	assert.True(t, IsTuple(point))
+	require.Len(t, point.TupleItems, 3)
+	assert.Equal(t, STRING, point.TupleItems[0].ValueType)
+	assert.Equal(t, INT, point.TupleItems[1].ValueType)
+	assert.Equal(t, NUMBER, point.TupleItems[2].ValueType)
+	// the empty array doesn't count
+	assert.Equal(t, int64(2), point.TupleItems[0].Count)
+
+	// different lengths
+	pair := getPropByName(t, &mainType, "pair")
+	assert.False(t, IsTuple(pair))
+	assert.True(t, pair.NoTuple)
+	assert.Nil(t, pair.TupleItems)
+
+	// all positions have the same type
+	assert.False(t, IsTuple(getPropByName(t, &mainType, "list")))
+
+	// embedded documents of the array share one complex type
+	sub := getPropByName(t, &mainType, "sub")
+	assert.True(t, IsTuple(sub))
+	assert.True(t, sub.TupleItems[1].IsComplex)
+	assert.False(t, sub.TupleItems[1].IsArray)
+}
+
+func TestAddTupleItems(t *testing.T) {
+	var prop BasicElemInfo
+	addTupleItems(&prop, []ObservedType{{ValueType: STRING, Count: 1}, {ValueType: INT, Count: 1}}, false)
+	addTupleItems(&prop, nil, false)
+	addTupleItems(&prop, []ObservedType{{ValueType: STRING, Count: 1}, {ValueType: INT, Count: 1}}, false)
+	require.Len(t, prop.TupleItems, 2)
+	assert.Equal(t, int64(2), prop.TupleItems[1].Count)
+
+	addTupleItems(&prop, []ObservedType{{ValueType: STRING, Count: 1}, {ValueType: STRING, Count: 1}}, false)
+	assert.True(t, prop.NoTuple)
+	assert.Nil(t, prop.TupleItems)
+
+	// once no tuple, always no tuple
+	addTupleItems(&prop, []ObservedType{{ValueType: STRING, Count: 1}, {ValueType: INT, Count: 1}}, false)
+	assert.Nil(t, prop.TupleItems)
+}
@@ -0,0 +1,157 @@
+package mongoHelper
+
+import (
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	ot "okieoth/schemaguesser/internal/pkg/optional_types"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Statistics over the observed values of an attribute
+type ValueStats struct {
+	MinNumber ot.Optional[float64]   `json:"minNumber,omitempty"`
+	MaxNumber ot.Optional[float64]   `json:"maxNumber,omitempty"`
+	MinDate   ot.Optional[time.Time] `json:"minDate,omitempty"`
+	MaxDate   ot.Optional[time.Time] `json:"maxDate,omitempty"`
+	MinLength ot.Optional[int64]     `json:"minLength,omitempty"`
+	MaxLength ot.Optional[int64]     `json:"maxLength,omitempty"`
+	MinItems  ot.Optional[int64]     `json:"minItems,omitempty"`
+	MaxItems  ot.Optional[int64]     `json:"maxItems,omitempty"`
+}
+
+func setMin[C int64 | float64](v *ot.Optional[C], newValue C) {
+	if (!v.IsSet) || (newValue < v.Value) {
+		v.Set(newValue)
+	}
+}
+
+func setMax[C int64 | float64](v *ot.Optional[C], newValue C) {
+	if (!v.IsSet) || (newValue > v.Value) {
+		v.Set(newValue)
+	}
+}
+
+func setMinDate(v *ot.Optional[time.Time], newValue time.Time) {
+	if (!v.IsSet) || newValue.Before(v.Value) {
+		v.Set(newValue)
+	}
+}
+
+func setMaxDate(v *ot.Optional[time.Time], newValue time.Time) {
+	if (!v.IsSet) || newValue.After(v.Value) {
+		v.Set(newValue)
+	}
+}
+
+func addNumberValue(stats *ValueStats, value float64) {
+	setMin(&stats.MinNumber, value)
+	setMax(&stats.MaxNumber, value)
+}
+
+func addDateValue(stats *ValueStats, value time.Time) {
+	setMinDate(&stats.MinDate, value)
+	setMaxDate(&stats.MaxDate, value)
+}
+
+func addStringLength(stats *ValueStats, length int64) {
+	setMin(&stats.MinLength, length)
+	setMax(&stats.MaxLength, length)
+}
+
+func addItemCount(stats *ValueStats, count int64) {
+	setMin(&stats.MinItems, count)
+	setMax(&stats.MaxItems, count)
+}
+
+// returns the value of a numeric bson value as float. NaN and infinite values are not usable as
+// minimum or maximum, they can't be serialized to JSON and NaN doesn't compare to other values.
+func numberValue(value bson.RawValue) (float64, bool) {
+	var f float64
+	switch value.Type {
+	case bson.TypeDouble:
+		f = value.Double()
+	case bson.TypeInt32:
+		f = float64(value.Int32())
+	case bson.TypeInt64:
+		f = float64(value.Int64())
+	case bson.TypeDecimal128:
+		var err error
+		f, err = strconv.ParseFloat(value.Decimal128().String(), 64)
+		if err != nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+// Collects the statistics of a single value
+func newValueStats(value bson.RawValue) *ValueStats {
+	var stats ValueStats
+	switch value.Type {
+	case bson.TypeDouble, bson.TypeInt32, bson.TypeInt64, bson.TypeDecimal128:
+		f, ok := numberValue(value)
+		if !ok {
+			return nil
+		}
+		addNumberValue(&stats, f)
+	case bson.TypeDateTime:
+		addDateValue(&stats, value.Time().UTC())
+	case bson.TypeString:
+		addStringLength(&stats, int64(utf8.RuneCountInString(value.StringValue())))
+	case bson.TypeArray:
+		values, err := value.Array().Values()
+		if err != nil {
+			return nil
+		}
+		addItemCount(&stats, int64(len(values)))
+	default:
+		return nil
+	}
+	return &stats
+}
+
+// Merges the statistics of the source into the target
+func mergeValueStats(target **ValueStats, source *ValueStats) {
+	if source == nil {
+		return
+	}
+	if *target == nil {
+		s := *source
+		*target = &s
+		return
+	}
+	t := *target
+	if source.MinNumber.IsSet {
+		setMin(&t.MinNumber, source.MinNumber.Value)
+	}
+	if source.MaxNumber.IsSet {
+		setMax(&t.MaxNumber, source.MaxNumber.Value)
+	}
+	if source.MinDate.IsSet {
+		setMinDate(&t.MinDate, source.MinDate.Value)
+	}
+	if source.MaxDate.IsSet {
+		setMaxDate(&t.MaxDate, source.MaxDate.Value)
+	}
+	if source.MinLength.IsSet {
+		setMin(&t.MinLength, source.MinLength.Value)
+	}
+	if source.MaxLength.IsSet {
+		setMax(&t.MaxLength, source.MaxLength.Value)
+	}
+	if source.MinItems.IsSet {
+		setMin(&t.MinItems, source.MinItems.Value)
+	}
+	if source.MaxItems.IsSet {
+		setMax(&t.MaxItems, source.MaxItems.Value)
+	}
+}
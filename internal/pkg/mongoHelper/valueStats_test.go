@@ -0,0 +1,98 @@
+package mongoHelper
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestProcessBsonValueStats(t *testing.T) {
+	d1 := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	d2 := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	dec, err := primitive.ParseDecimal128("-10.5")
+	require.Nil(t, err)
+	docs := []bson.M{
+		{"num": int32(5), "text": "abc", "created": d2, "list": bson.A{1, 2, 3}, "sub": bson.M{"price": 2.5}},
+		{"num": int64(-3), "text": "äöüß€", "created": d1, "list": bson.A{}, "sub": bson.M{"price": dec}},
+		{"num": 7.25, "text": "", "list": bson.A{"a"}},
+	}
+	mainType, otherComplexTypes := processTestDocs(t, "test", docs)
+
+	num := getPropByName(t, &mainType, "num")
+	require.NotNil(t, num.Stats)
+	assert.Equal(t, -3.0, num.Stats.MinNumber.Value)
+	assert.Equal(t, 7.25, num.Stats.MaxNumber.Value)
+	assert.False(t, num.Stats.MinLength.IsSet)
+
+	text := getPropByName(t, &mainType, "text")
+	assert.Equal(t, int64(0), text.Stats.MinLength.Value)
+	assert.Equal(t, int64(5), text.Stats.MaxLength.Value)
+
+	created := getPropByName(t, &mainType, "created")
+	assert.Equal(t, d1, created.Stats.MinDate.Value)
+	assert.Equal(t, d2, created.Stats.MaxDate.Value)
+
+	list := getPropByName(t, &mainType, "list")
+	assert.Equal(t, int64(0), list.Stats.MinItems.Value)
+	assert.Equal(t, int64(3), list.Stats.MaxItems.Value)
+
+	sub, found := getAlreadyStoredType(otherComplexTypes, "TestSub")
+	require.True(t, found)
+	price := getPropByName(t, &sub, "price")
+	assert.Equal(t, -10.5, price.Stats.MinNumber.Value)
+	assert.Equal(t, 2.5, price.Stats.MaxNumber.Value)
+}
+
+func TestMergeValueStats(t *testing.T) {
+	var target *ValueStats
+	mergeValueStats(&target, nil)
+	assert.Nil(t, target)
+
+	var s1, s2 ValueStats
+	addNumberValue(&s1, 3)
+	addItemCount(&s2, 4)
+	addNumberValue(&s2, 10)
+	mergeValueStats(&target, &s1)
+	mergeValueStats(&target, &s2)
+	require.NotNil(t, target)
+	assert.Equal(t, 3.0, target.MinNumber.Value)
+	assert.Equal(t, 10.0, target.MaxNumber.Value)
+	assert.Equal(t, int64(4), target.MinItems.Value)
+	// the merge doesn't change the source
+	assert.Equal(t, 3.0, s1.MaxNumber.Value)
+}
+
+func TestProcessBsonValueStatsNonFiniteNumbers(t *testing.T) {
+	nanDec, err := primitive.ParseDecimal128("NaN")
+	require.Nil(t, err)
+	docs := []bson.M{
+		{"num": math.NaN(), "dec": nanDec, "inf": math.Inf(1)},
+		{"num": 2.5, "dec": nanDec, "inf": math.Inf(-1)},
+		{"num": math.Inf(1), "inf": 4.0},
+		{"num": -1.0},
+	}
+	mainType, _ := processTestDocs(t, "test", docs)
+
+	num := getPropByName(t, &mainType, "num")
+	require.NotNil(t, num.Stats)
+	assert.Equal(t, -1.0, num.Stats.MinNumber.Value)
+	assert.Equal(t, 2.5, num.Stats.MaxNumber.Value)
+
+	dec := getPropByName(t, &mainType, "dec")
+	assert.Nil(t, dec.Stats)
+
+	inf := getPropByName(t, &mainType, "inf")
+	require.NotNil(t, inf.Stats)
+	assert.Equal(t, 4.0, inf.Stats.MinNumber.Value)
+	assert.Equal(t, 4.0, inf.Stats.MaxNumber.Value)
+
+	// the schema with the collected stats must be serializable
+	_, err = json.Marshal(mainType)
+	assert.Nil(t, err)
+}
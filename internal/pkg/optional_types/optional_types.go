@@ -24,7 +24,7 @@ func (m *Optional[C]) UnSet() {
 }
 
 func (v *Optional[C]) UnmarshalJSON(data []byte) error {
-	if len(data) == 0 {
+	if (len(data) == 0) || (string(data) == "null") {
 		v.IsSet = false
 		return nil
 	}
@@ -13,6 +13,22 @@ func TestOptionalString(t *testing.T) {
 	}
 }
 
+func TestOptionalUnmarshalNull(t *testing.T) {
+	var i Optional[int64]
+	if err := i.UnmarshalJSON([]byte("null")); err != nil {
+		t.Errorf("error while unmarshal null: %v", err)
+	}
+	if i.IsSet {
+		t.Errorf("value is set after unmarshal null")
+	}
+	if err := i.UnmarshalJSON([]byte("42")); err != nil {
+		t.Errorf("error while unmarshal value: %v", err)
+	}
+	if (!i.IsSet) || (i.Value != 42) {
+		t.Errorf("value isn't set after unmarshal: %v", i)
+	}
+}
+
 type DummyEnum int64
 
 const (
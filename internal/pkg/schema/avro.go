@@ -0,0 +1,298 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+)
+
+// namespace of the created avro records, if it's empty the name of the database is used
+var AvroNamespace = ""
+
+type AvroRecord struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Doc       string      `json:"doc,omitempty"`
+	Fields    []AvroField `json:"fields"`
+}
+
+type AvroField struct {
+	Name    string          `json:"name"`
+	Doc     string          `json:"doc,omitempty"`
+	Type    any             `json:"type"`
+	Default json.RawMessage `json:"default,omitempty"`
+	// original name of the attribute, if it isn't a valid avro name
+	AttribName string `json:"x-attrib-name,omitempty"`
+}
+
+type AvroArray struct {
+	Type  string `json:"type"`
+	Items any    `json:"items"`
+}
+
+type AvroMap struct {
+	Type   string `json:"type"`
+	Values any    `json:"values"`
+}
+
+type AvroLogicalType struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType"`
+}
+
+// avro types for the BSON types. ObjectIds and decimals are transported in their string
+// representation, a Decimal128 has no fixed scale, like it's needed by the avro decimal type.
+var avroBsonTypes = map[string]any{
+	"objectId":  "string",
+	"date":      AvroLogicalType{Type: "long", LogicalType: "timestamp-millis"},
+	"decimal":   "string",
+	"timestamp": "long",
+	"binData":   "bytes",
+	"int":       "int",
+	"long":      "long",
+	"double":    "double",
+	"bool":      "boolean",
+	"string":    "string",
+}
+
+type avroGenerator struct {
+	otherComplexTypes []mongoHelper.ComplexType
+	// avro needs every named type defined once, the following usages reference it by name
+	definedRecords []string
+}
+
+// creates a valid avro name out of an attribute name, not allowed characters are replaced by '_'
+func avroName(attribName string) string {
+	runes := []rune(attribName)
+	for i, r := range runes {
+		if !(((r >= 'a') && (r <= 'z')) || ((r >= 'A') && (r <= 'Z')) || ((r >= '0') && (r <= '9')) || (r == '_')) {
+			runes[i] = '_'
+		}
+	}
+	ret := string(runes)
+	if (ret == "") || unicode.IsDigit(runes[0]) {
+		return "_" + ret
+	}
+	return ret
+}
+
+func avroDoc(lines ...string) string {
+	ret := make([]string, 0)
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); l != "" {
+			ret = append(ret, l)
+		}
+	}
+	return strings.Join(ret, "\n")
+}
+
+func avroArray(itemType any, arrayDimensions uint) any {
+	for i := uint(0); i < arrayDimensions; i++ {
+		itemType = AvroArray{Type: "array", Items: itemType}
+	}
+	return itemType
+}
+
+// adds 'null' as first type of the union, that's needed for 'null' as default value
+func avroNullable(t any) any {
+	members, ok := t.([]any)
+	if !ok {
+		return []any{"null", t}
+	}
+	return append([]any{"null"}, slices.DeleteFunc(slices.Clone(members), func(m any) bool { return m == "null" })...)
+}
+
+// Creates an avro union out of the member types. Avro doesn't allow more than one array or map in
+// a union, so the arrays (maps) are merged to one array (map) with a union of the item types.
+func avroUnion(members []any) any {
+	ret := make([]any, 0)
+	keys := make([]string, 0)
+	arrayItems := make([]any, 0)
+	mapValues := make([]any, 0)
+	for _, m := range members {
+		var key string
+		switch t := m.(type) {
+		case AvroArray:
+			arrayItems = append(arrayItems, t.Items)
+			continue
+		case AvroMap:
+			mapValues = append(mapValues, t.Values)
+			continue
+		case AvroLogicalType:
+			key = t.Type
+		case *AvroRecord:
+			key = t.Name
+		case string:
+			key = t
+		}
+		// a union can't contain a logical type together with its underlying type, the plain type wins.
+		// A record definition is kept, also if its name follows.
+		if i := slices.Index(keys, key); i >= 0 {
+			if _, isLogical := ret[i].(AvroLogicalType); isLogical {
+				if _, isString := m.(string); isString {
+					ret[i] = m
+				}
+			}
+			continue
+		}
+		keys = append(keys, key)
+		ret = append(ret, m)
+	}
+	if len(arrayItems) > 0 {
+		ret = append(ret, AvroArray{Type: "array", Items: avroUnion(arrayItems)})
+	}
+	if len(mapValues) > 0 {
+		ret = append(ret, AvroMap{Type: "map", Values: avroUnion(mapValues)})
+	}
+	if len(ret) == 1 {
+		return ret[0]
+	}
+	return ret
+}
+
+// returns the record definition at the first usage of a complex type, afterwards only the name
+func (g *avroGenerator) recordType(typeName string) any {
+	complexType, err := getComplexTypeByName(typeName, g.otherComplexTypes)
+	if err != nil {
+		log.Printf("avro: can't find complex type '%s'", typeName)
+		return "string"
+	}
+	if complexType.IsDictionary {
+		return AvroMap{Type: "map", Values: g.dictValueType(complexType)}
+	}
+	name := goIdentifier(complexType.Name)
+	if slices.Contains(g.definedRecords, name) {
+		return name
+	}
+	return g.record(complexType, flatProperties(complexType, g.otherComplexTypes))
+}
+
+func (g *avroGenerator) dictValueType(dictType *mongoHelper.ComplexType) any {
+	if dictType.DictValue == nil {
+		return "null"
+	}
+	valueType := g.propType(dictType.DictValue)
+	if dictType.DictValue.IsNullable {
+		return avroNullable(valueType)
+	}
+	return valueType
+}
+
+// avro type of a single, not array value
+func (g *avroGenerator) baseType(t mongoHelper.ObservedType) any {
+	if t.IsComplex {
+		return g.recordType(t.ValueType)
+	}
+	// uuids of type binData keep their 16 raw bytes, only uuid strings get the logical type
+	if (t.Format == mongoHelper.FORMAT_UUID) && (t.BsonType == "string") {
+		return AvroLogicalType{Type: "string", LogicalType: "uuid"}
+	}
+	if avroType, ok := avroBsonTypes[t.BsonType]; ok {
+		return avroType
+	}
+	if t.BsonType == mongoHelper.NULL {
+		return "null"
+	}
+	return "string"
+}
+
+func (g *avroGenerator) unionType(types []mongoHelper.ObservedType) any {
+	members := make([]any, 0)
+	for _, t := range types {
+		members = append(members, avroArray(g.baseType(t), t.ArrayDimensions))
+	}
+	return avroUnion(members)
+}
+
+func (g *avroGenerator) propType(prop *mongoHelper.BasicElemInfo) any {
+	switch {
+	case mongoHelper.IsTuple(prop):
+		return AvroArray{Type: "array", Items: g.unionType(prop.TupleItems)}
+	case mongoHelper.IsPolymorphic(prop):
+		if isArrayUnion(*prop) {
+			return AvroArray{Type: "array", Items: g.unionType(unionItemTypes(*prop))}
+		}
+		return g.unionType(prop.Types)
+	case prop.IsArray && (prop.BsonType == mongoHelper.EMPTY_ARRAY_BSON_TYPE):
+		// only empty arrays were observed
+		return avroArray("null", prop.ArrayDimensions)
+	case (prop.BsonType == mongoHelper.NULL) && !prop.IsComplex:
+		return "null"
+	}
+	return avroArray(g.baseType(mongoHelper.ObservedType{
+		ValueType: prop.ValueType,
+		BsonType:  prop.BsonType,
+		Format:    prop.Format,
+		IsComplex: prop.IsComplex,
+	}), prop.ArrayDimensions)
+}
+
+func (g *avroGenerator) record(complexType *mongoHelper.ComplexType, props []flatProperty, doc ...string) *AvroRecord {
+	ret := AvroRecord{
+		Type:   "record",
+		Name:   goIdentifier(complexType.Name),
+		Doc:    avroDoc(append(doc, complexType.Title, complexType.Description)...),
+		Fields: make([]AvroField, 0),
+	}
+	// the name is reserved before the fields are created, to reference it in recursive types
+	g.definedRecords = append(g.definedRecords, ret.Name)
+	fieldNames := make([]string, 0)
+	for _, f := range props {
+		p := f.prop
+		field := AvroField{Name: avroName(p.AttribName), Type: g.propType(p)}
+		for j := 2; slices.Contains(fieldNames, field.Name); j++ {
+			field.Name = avroName(p.AttribName) + "_" + strconv.Itoa(j)
+		}
+		fieldNames = append(fieldNames, field.Name)
+		if field.Name != p.AttribName {
+			field.AttribName = p.AttribName
+		}
+		if (f.optional || p.IsNullable) && (field.Type != "null") {
+			field.Type = avroNullable(field.Type)
+			field.Default = json.RawMessage("null")
+		}
+		var possibleValues string
+		if p.IsEnum {
+			possibleValues = "possible values: " + strings.Join(p.DistinctValues, ", ")
+		}
+		field.Doc = avroDoc(p.Title, p.Description, p.Comment, possibleValues)
+		ret.Fields = append(ret.Fields, field)
+	}
+	return &ret
+}
+
+// Creates an avro record schema for the collection. The other complex types are defined as nested
+// records at their first usage. The variants of a discriminated collection are merged into the main record.
+func AvroSchema(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) *AvroRecord {
+	g := avroGenerator{otherComplexTypes: otherComplexTypes, definedRecords: make([]string, 0)}
+	mainDoc := fmt.Sprintf("Storage model for database: %s, collection: %s", database, collection)
+	if mainType.Discriminator != "" {
+		mainDoc += fmt.Sprintf("\nThe attribute '%s' distinguishes the variants, the attributes of the variants are optional", mainType.Discriminator)
+	}
+	ret := g.record(mainType, flatProperties(mainType, otherComplexTypes), mainDoc)
+	ret.Namespace = AvroNamespace
+	if ret.Namespace == "" {
+		ret.Namespace = avroNamespace(database)
+	}
+	return ret
+}
+
+// the namespace is a dot separated sequence of avro names
+func avroNamespace(database string) string {
+	segments := strings.Split(database, ".")
+	for i, s := range segments {
+		segments[i] = avroName(s)
+	}
+	return strings.Join(segments, ".")
+}
+
+func PrintAvroSchema(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) error {
+	return writeJsonOutput(AvroSchema(database, collection, mainType, otherComplexTypes), "avsc", database, collection, outputDir)
+}
@@ -0,0 +1,64 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// indented JSON like in the written files
+func avroTestOutput(t *testing.T, record *AvroRecord) string {
+	jsonData, err := marshalJson(record)
+	require.Nil(t, err)
+	var indented bytes.Buffer
+	require.Nil(t, json.Indent(&indented, jsonData, "", "  "))
+	indented.WriteByte('\n')
+	return indented.String()
+}
+
+func TestAvroSchema(t *testing.T) {
+	mainType, otherComplexTypes := goldenTestSchema(t)
+	assertGolden(t, "orders.avsc", avroTestOutput(t, AvroSchema("shop", "orders", mainType, otherComplexTypes)))
+
+	mainType, otherComplexTypes = guessTestSchema(t, "metrics", "", wrapperTestDocs())
+	assertGolden(t, "metrics.avsc", avroTestOutput(t, AvroSchema("shop", "metrics", mainType, otherComplexTypes)))
+}
+
+func TestAvroUnion(t *testing.T) {
+	timestamp := AvroLogicalType{Type: "long", LogicalType: "timestamp-millis"}
+	uuidString := AvroLogicalType{Type: "string", LogicalType: "uuid"}
+	record := &AvroRecord{Type: "record", Name: "Address"}
+	tests := []struct {
+		name     string
+		members  []any
+		expected any
+	}{
+		{"single member", []any{"int"}, "int"},
+		{"duplicates", []any{"int", "string", "int"}, []any{"int", "string"}},
+		{"records", []any{record, "Address", "null"}, []any{record, "null"}},
+		{"logical type before plain type", []any{timestamp, "long"}, "long"},
+		{"plain type before logical type", []any{"string", uuidString, "int"}, []any{"string", "int"}},
+		{"different logical types", []any{timestamp, uuidString}, []any{timestamp, uuidString}},
+		{
+			"arrays are merged",
+			[]any{AvroArray{Type: "array", Items: "int"}, "string", AvroArray{Type: "array", Items: "string"}, AvroArray{Type: "array", Items: "int"}},
+			[]any{"string", AvroArray{Type: "array", Items: []any{"int", "string"}}},
+		},
+		{
+			"maps are merged",
+			[]any{AvroMap{Type: "map", Values: "double"}, AvroMap{Type: "map", Values: timestamp}, "null"},
+			[]any{"null", AvroMap{Type: "map", Values: []any{"double", timestamp}}},
+		},
+		{
+			"arrays and maps",
+			[]any{AvroMap{Type: "map", Values: "int"}, AvroArray{Type: "array", Items: uuidString}, AvroArray{Type: "array", Items: "string"}},
+			[]any{AvroArray{Type: "array", Items: "string"}, AvroMap{Type: "map", Values: "int"}},
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, avroUnion(test.members), test.name)
+	}
+}
@@ -0,0 +1,49 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+)
+
+// user provided template that is rendered for every collection
+type CustomTemplate struct {
+	Name    string
+	Content string
+	FileExt string
+}
+
+// Loads the template file and checks its syntax. Without a given file extension, the extension
+// is taken from the file name without '.tmpl', e.g. 'wiki.md.tmpl' creates '.md' files
+func LoadCustomTemplate(templateFile string, fileExt string) (*CustomTemplate, error) {
+	content, err := os.ReadFile(templateFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read template file '%s': %w", templateFile, err)
+	}
+	name := filepath.Base(templateFile)
+	if _, err = template.New(name).Funcs(templateFuncs()).Parse(string(content)); err != nil {
+		return nil, fmt.Errorf("can't parse template file '%s': %w", templateFile, err)
+	}
+	if fileExt == "" {
+		fileExt = strings.TrimPrefix(filepath.Ext(strings.TrimSuffix(name, ".tmpl")), ".")
+	}
+	if fileExt == "" {
+		return nil, fmt.Errorf("no file extension for the output of template '%s' given", templateFile)
+	}
+	return &CustomTemplate{
+		Name:    name,
+		Content: string(content),
+		FileExt: strings.TrimPrefix(fileExt, "."),
+	}, nil
+}
+
+// Renders the user template with the same data as the PlantUml template, that covers the
+// data of the JSON schema template plus the type relations and the enums
+func PrintCustomTemplate(customTemplate *CustomTemplate, database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) error {
+	input := newPumlTemplateInput(database, collection, mainType, otherComplexTypes)
+	return printTemplateBase(customTemplate.Name, customTemplate.Content, customTemplate.FileExt, database, collection, &input, outputDir)
+}
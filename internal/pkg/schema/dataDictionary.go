@@ -0,0 +1,328 @@
+package schema
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	linkshelper "okieoth/schemaguesser/internal/pkg/linksHelper"
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+	"okieoth/schemaguesser/internal/pkg/utils"
+)
+
+const DICT_FORMAT_MARKDOWN = "markdown"
+const DICT_FORMAT_HTML = "html"
+
+var DictionaryFormats = []string{DICT_FORMAT_MARKDOWN, DICT_FORMAT_HTML}
+
+func CheckDictionaryFormat(format string) error {
+	if !slices.Contains(DictionaryFormats, format) {
+		return fmt.Errorf("unknown dictionary format '%s', possible values are: %v", format, DictionaryFormats)
+	}
+	return nil
+}
+
+// One attribute of a collection, nested attributes are contained with their full path, e.g. 'items[].productId'
+type DictField struct {
+	Path string
+	Type string
+	// type with 'object' instead of the names of the complex types, the names can differ between two runs
+	// while the structure is the same. The structure of the complex types is contained in the nested fields
+	StructureType string
+	Format        string
+	// ratio of the parent documents that contain the attribute
+	Presence string
+	// counts the presence ratio is computed from
+	OccurrenceCount int64
+	SampleCount     int64
+	IsNullable      bool
+	IsEnum          bool
+	Examples        string
+	// for attributes of variants: the variant that contains the attribute
+	Variant     string
+	Description string
+}
+
+type DictReference struct {
+	// attribute path like in the key-values files, e.g. 'items_sub-productId'
+	Attribute  string
+	Database   string
+	Collection string
+	Attributes string
+	// file name of the page of the other collection, empty if it isn't part of the dictionary
+	Page string
+}
+
+type DictCollection struct {
+	Database      string
+	Collection    string
+	Page          string
+	Title         string
+	Description   string
+	SampleCount   int64
+	Sampling      string
+	Discriminator string
+	Fields        []DictField
+	// false if the indexes were not queried, e.g. without database connection
+	IndexesRead  bool
+	Indexes      []string
+	References   []DictReference
+	ReferencedBy []DictReference
+}
+
+type DictDatabase struct {
+	Name        string
+	Collections []*DictCollection
+}
+
+type DictIndexInput struct {
+	Title     string
+	Databases []DictDatabase
+}
+
+// file extension of the collection pages
+func dictionaryExt(format string) string {
+	if format == DICT_FORMAT_HTML {
+		return "dict.html"
+	}
+	return "dict.md"
+}
+
+func dictionaryPage(format string, database string, collection string) string {
+	return filepath.Base(utils.GetFileName("", dictionaryExt(format), database, collection))
+}
+
+// type of an attribute in the dictionary, simple attributes are shown with their bson type. Without
+// type names the complex types are shown as 'object'
+func dictAttributeType(prop *mongoHelper.BasicElemInfo, typeNames bool) string {
+	if !mongoHelper.IsPolymorphic(prop) {
+		return dictTypeName(prop.ValueType, prop.BsonType, prop.IsComplex && typeNames, prop.ArrayDimensions)
+	}
+	names := make([]string, 0)
+	for _, t := range unionItemTypes(*prop) {
+		n := dictTypeName(t.ValueType, t.BsonType, t.IsComplex && typeNames, t.ArrayDimensions)
+		if !slices.Contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	if isArrayUnion(*prop) {
+		return "(" + strings.Join(names, " | ") + ")[]"
+	}
+	return strings.Join(names, " | ")
+}
+
+func dictTypeName(valueType string, bsonType string, isComplex bool, arrayDimensions uint) string {
+	if isComplex {
+		return valueType + arrayMarker(arrayDimensions)
+	}
+	return validatorBsonType(bsonType) + arrayMarker(arrayDimensions)
+}
+
+// example values of an attribute, the distinct values if they were collected, otherwise the value range
+func dictExamples(prop *mongoHelper.BasicElemInfo) string {
+	if len(prop.DistinctValues) > 0 {
+		ret := strings.Join(prop.DistinctValues, ", ")
+		if prop.TooManyValues {
+			ret += ", ..."
+		}
+		return ret
+	}
+	if prop.Stats == nil {
+		return ""
+	}
+	ranges := make([]string, 0)
+	if prop.Stats.MinNumber.IsSet && prop.Stats.MaxNumber.IsSet {
+		ranges = append(ranges, fmt.Sprintf("%s .. %s",
+			strconv.FormatFloat(prop.Stats.MinNumber.Value, 'f', -1, 64),
+			strconv.FormatFloat(prop.Stats.MaxNumber.Value, 'f', -1, 64)))
+	}
+	if prop.Stats.MinDate.IsSet && prop.Stats.MaxDate.IsSet {
+		ranges = append(ranges, fmt.Sprintf("%s .. %s",
+			prop.Stats.MinDate.Value.Format(time.RFC3339), prop.Stats.MaxDate.Value.Format(time.RFC3339)))
+	}
+	if prop.Stats.MinLength.IsSet && prop.Stats.MaxLength.IsSet {
+		ranges = append(ranges, fmt.Sprintf("length %d .. %d", prop.Stats.MinLength.Value, prop.Stats.MaxLength.Value))
+	}
+	if prop.Stats.MinItems.IsSet && prop.Stats.MaxItems.IsSet {
+		ranges = append(ranges, fmt.Sprintf("items %d .. %d", prop.Stats.MinItems.Value, prop.Stats.MaxItems.Value))
+	}
+	return strings.Join(ranges, ", ")
+}
+
+type dictFieldsBuilder struct {
+	otherComplexTypes []mongoHelper.ComplexType
+	fields            []DictField
+}
+
+func (b *dictFieldsBuilder) addProperty(prefix string, prop *mongoHelper.BasicElemInfo, sampleCount int64, variant string, visited []string) {
+	path := prefix + prop.AttribName
+	b.fields = append(b.fields, DictField{
+		Path:            path,
+		Type:            dictAttributeType(prop, true),
+		StructureType:   dictAttributeType(prop, false),
+		Format:          prop.Format,
+		Presence:        presenceRatio(*prop, sampleCount),
+		OccurrenceCount: prop.OccurrenceCount,
+		SampleCount:     sampleCount,
+		IsNullable:      prop.IsNullable,
+		IsEnum:          prop.IsEnum,
+		Examples:        dictExamples(prop),
+		Variant:         variant,
+		Description:     prop.Description,
+	})
+	if !mongoHelper.IsPolymorphic(prop) {
+		if prop.IsComplex {
+			b.addComplexType(path+arrayMarker(prop.ArrayDimensions)+".", prop.ValueType, variant, visited)
+		}
+		return
+	}
+	added := make([]string, 0)
+	for _, t := range prop.Types {
+		if t.IsComplex && !slices.Contains(added, t.ValueType) {
+			added = append(added, t.ValueType)
+			b.addComplexType(path+arrayMarker(t.ArrayDimensions)+".", t.ValueType, variant, visited)
+		}
+	}
+}
+
+// adds the attributes of a nested type, recursive types are only resolved once per path
+func (b *dictFieldsBuilder) addComplexType(prefix string, typeName string, variant string, visited []string) {
+	if slices.Contains(visited, typeName) {
+		return
+	}
+	t, err := getComplexTypeByName(typeName, b.otherComplexTypes)
+	if err != nil {
+		return
+	}
+	visited = append(slices.Clone(visited), typeName)
+	if t.IsDictionary {
+		if t.DictValue != nil {
+			value := *t.DictValue
+			value.AttribName = "<key>"
+			b.addProperty(prefix, &value, value.OccurrenceCount, variant, visited)
+		}
+		return
+	}
+	for i := range t.Properties {
+		b.addProperty(prefix, &t.Properties[i], t.SampleCount, variant, visited)
+	}
+}
+
+func dictFields(s *PersistedSchema) []DictField {
+	b := dictFieldsBuilder{
+		otherComplexTypes: s.OtherComplexTypes,
+		fields:            make([]DictField, 0),
+	}
+	visited := []string{s.MainType.Name}
+	for i := range s.MainType.Properties {
+		b.addProperty("", &s.MainType.Properties[i], s.MainType.SampleCount, "", visited)
+	}
+	for _, v := range variants(s.OtherComplexTypes) {
+		if v.BaseType != s.MainType.Name {
+			continue
+		}
+		variant := v.Name
+		if v.DiscriminatorValue != "" {
+			variant = fmt.Sprintf("%s (%s=%s)", v.Name, s.MainType.Discriminator, v.DiscriminatorValue)
+		}
+		own := ownProperties(v, s.MainType)
+		for i := range own {
+			b.addProperty("", &own[i], v.SampleCount, variant, append(slices.Clone(visited), v.Name))
+		}
+	}
+	return b.fields
+}
+
+// Creates the dictionary pages of the persisted schemas. The indexes are given per '<db>.<collection>',
+// collections without entry are shown without indexes. The references are the ones that were found by the
+// 'get links' command.
+func NewDictCollections(format string, schemas []PersistedSchema, indexes map[string][]string, colRefs []linkshelper.ColRefs) []*DictCollection {
+	ret := make([]*DictCollection, 0)
+	findPage := func(database string, collection string) string {
+		if slices.ContainsFunc(schemas, func(s PersistedSchema) bool {
+			return (s.Database == database) && (s.Collection == collection)
+		}) {
+			return dictionaryPage(format, database, collection)
+		}
+		return ""
+	}
+	for i := range schemas {
+		s := &schemas[i]
+		c := DictCollection{
+			Database:      s.Database,
+			Collection:    s.Collection,
+			Page:          dictionaryPage(format, s.Database, s.Collection),
+			Title:         s.MainType.Title,
+			Description:   s.MainType.Description,
+			SampleCount:   s.MainType.SampleCount,
+			Sampling:      s.MainType.Sampling,
+			Discriminator: s.MainType.Discriminator,
+			Fields:        dictFields(s),
+			References:    make([]DictReference, 0),
+			ReferencedBy:  make([]DictReference, 0),
+		}
+		c.Indexes, c.IndexesRead = indexes[s.Database+"."+s.Collection]
+		for _, cr := range colRefs {
+			for _, ar := range cr.AttribRefs {
+				for _, ref := range ar.References {
+					if (cr.Db == s.Database) && (cr.Collection == s.Collection) {
+						c.References = append(c.References, DictReference{
+							Attribute:  ar.AttribStr,
+							Database:   ref.Db,
+							Collection: ref.Collection,
+							Attributes: strings.Join(ref.Attributes, ", "),
+							Page:       findPage(ref.Db, ref.Collection),
+						})
+					}
+					if (ref.Db == s.Database) && (ref.Collection == s.Collection) {
+						c.ReferencedBy = append(c.ReferencedBy, DictReference{
+							Attribute:  ar.AttribStr,
+							Database:   cr.Db,
+							Collection: cr.Collection,
+							Attributes: strings.Join(ref.Attributes, ", "),
+							Page:       findPage(cr.Db, cr.Collection),
+						})
+					}
+				}
+			}
+		}
+		ret = append(ret, &c)
+	}
+	return ret
+}
+
+// groups the collections by database for the index page
+func NewDictIndexInput(title string, collections []*DictCollection) DictIndexInput {
+	ret := DictIndexInput{
+		Title:     title,
+		Databases: make([]DictDatabase, 0),
+	}
+	for _, c := range collections {
+		i := slices.IndexFunc(ret.Databases, func(d DictDatabase) bool { return d.Name == c.Database })
+		if i == -1 {
+			ret.Databases = append(ret.Databases, DictDatabase{Name: c.Database})
+			i = len(ret.Databases) - 1
+		}
+		ret.Databases[i].Collections = append(ret.Databases[i].Collections, c)
+	}
+	return ret
+}
+
+// writes the page of one collection to '<database>_<collection>.dict.md' or '<database>_<collection>.dict.html'
+func WriteDictionaryPage(format string, input *DictCollection, outputDir string) error {
+	if format == DICT_FORMAT_HTML {
+		return printTemplateBase("dict_page_html.tmpl", dictPageHtmlTemplateStr, dictionaryExt(format), input.Database, input.Collection, input, outputDir)
+	}
+	return printTemplateBase("dict_page_markdown.tmpl", dictPageMarkdownTemplateStr, dictionaryExt(format), input.Database, input.Collection, input, outputDir)
+}
+
+// writes the index page to 'index.md' or 'index.html'
+func WriteDictionaryIndex(format string, input *DictIndexInput, outputDir string) error {
+	if format == DICT_FORMAT_HTML {
+		return printTemplateToFile("dict_index_html.tmpl", dictIndexHtmlTemplateStr, "index.html", input, outputDir)
+	}
+	return printTemplateToFile("dict_index_markdown.tmpl", dictIndexMarkdownTemplateStr, "index.md", input, outputDir)
+}
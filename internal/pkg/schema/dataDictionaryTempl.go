@@ -0,0 +1,173 @@
+package schema
+
+var dictPageMarkdownTemplateStr = `# {{ MarkdownText .Database }}.{{ MarkdownText .Collection }}
+
+[Index](index.md)
+{{- if ne .Title "" }}
+
+**{{ MarkdownText .Title }}**
+{{- end }}
+{{- if ne .Description "" }}
+
+{{ MarkdownText .Description }}
+{{- end }}
+
+Processed documents: {{ .SampleCount }}{{ if ne .Sampling "" }} (sampling: {{ .Sampling }}){{ end }}
+{{- if ne .Discriminator "" }}
+
+Variants are distinguished by the attribute: {{ MarkdownCode .Discriminator }}
+{{- end }}
+
+## Fields
+
+| Field | Type | Format | Presence | Nullable | Examples | Variant | Description |
+|---|---|---|---:|---|---|---|---|
+{{- range .Fields }}
+| {{ MarkdownCode .Path }} | {{ MarkdownCode .Type }}{{ if .IsEnum }} (enum){{ end }} | {{ MarkdownText .Format }} | {{ .Presence }} | {{ if .IsNullable }}yes{{ end }} | {{ MarkdownText .Examples }} | {{ MarkdownText .Variant }} | {{ MarkdownText .Description }} |
+{{- end }}
+
+## Indexes
+{{ if not .IndexesRead }}
+Indexes were not read.
+{{- else if eq (len .Indexes) 0 }}
+No indexes.
+{{- else }}{{ range .Indexes }}
+- {{ MarkdownCode . }}
+{{- end }}
+{{- end }}
+
+## References
+{{ if eq (len .References) 0 }}
+No references to other collections found.
+{{- else }}
+| Field | Referenced collection | Referenced fields |
+|---|---|---|
+{{- range .References }}
+| {{ MarkdownCode .Attribute }} | {{ if ne .Page "" }}[{{ MarkdownText .Database }}.{{ MarkdownText .Collection }}]({{ .Page }}){{ else }}{{ MarkdownText .Database }}.{{ MarkdownText .Collection }}{{ end }} | {{ MarkdownCode .Attributes }} |
+{{- end }}
+{{- end }}
+
+## Referenced by
+{{ if eq (len .ReferencedBy) 0 }}
+No references from other collections found.
+{{- else }}
+| Collection | Field | Referenced fields |
+|---|---|---|
+{{- range .ReferencedBy }}
+| {{ if ne .Page "" }}[{{ MarkdownText .Database }}.{{ MarkdownText .Collection }}]({{ .Page }}){{ else }}{{ MarkdownText .Database }}.{{ MarkdownText .Collection }}{{ end }} | {{ MarkdownCode .Attribute }} | {{ MarkdownCode .Attributes }} |
+{{- end }}
+{{- end }}
+`
+
+var dictIndexMarkdownTemplateStr = `# {{ MarkdownText .Title }}
+{{ range .Databases }}
+## {{ MarkdownText .Name }}
+
+| Collection | Processed documents | Fields | References |
+|---|---:|---:|---:|
+{{- range .Collections }}
+| [{{ MarkdownText .Collection }}]({{ .Page }}) | {{ .SampleCount }} | {{ len .Fields }} | {{ len .References }} |
+{{- end }}
+{{ end -}}
+`
+
+var dictHtmlStyle = `  <style>
+    body { font-family: Helvetica, Arial, sans-serif; margin: 2em; }
+    table { border-collapse: collapse; margin-bottom: 1.5em; }
+    th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+    th { background-color: #eee; }
+    td.number { text-align: right; }
+    code { font-size: 0.95em; }
+  </style>`
+
+var dictPageHtmlTemplateStr = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{ html .Database }}.{{ html .Collection }}</title>
+` + dictHtmlStyle + `
+</head>
+<body>
+  <h1>{{ html .Database }}.{{ html .Collection }}</h1>
+  <p><a href="index.html">Index</a></p>
+  {{- if ne .Title "" }}
+  <p><b>{{ html .Title }}</b></p>
+  {{- end }}
+  {{- if ne .Description "" }}
+  <p>{{ html .Description }}</p>
+  {{- end }}
+  <p>Processed documents: {{ .SampleCount }}{{ if ne .Sampling "" }} (sampling: {{ html .Sampling }}){{ end }}</p>
+  {{- if ne .Discriminator "" }}
+  <p>Variants are distinguished by the attribute: <code>{{ html .Discriminator }}</code></p>
+  {{- end }}
+
+  <h2>Fields</h2>
+  <table>
+    <tr><th>Field</th><th>Type</th><th>Format</th><th>Presence</th><th>Nullable</th><th>Examples</th><th>Variant</th><th>Description</th></tr>
+  {{- range .Fields }}
+    <tr><td><code>{{ html .Path }}</code></td><td><code>{{ html .Type }}</code>{{ if .IsEnum }} (enum){{ end }}</td><td>{{ html .Format }}</td><td class="number">{{ .Presence }}</td><td>{{ if .IsNullable }}yes{{ end }}</td><td>{{ html .Examples }}</td><td>{{ html .Variant }}</td><td>{{ html .Description }}</td></tr>
+  {{- end }}
+  </table>
+
+  <h2>Indexes</h2>
+  {{- if not .IndexesRead }}
+  <p>Indexes were not read.</p>
+  {{- else if eq (len .Indexes) 0 }}
+  <p>No indexes.</p>
+  {{- else }}
+  <ul>
+  {{- range .Indexes }}
+    <li><code>{{ html . }}</code></li>
+  {{- end }}
+  </ul>
+  {{- end }}
+
+  <h2>References</h2>
+  {{- if eq (len .References) 0 }}
+  <p>No references to other collections found.</p>
+  {{- else }}
+  <table>
+    <tr><th>Field</th><th>Referenced collection</th><th>Referenced fields</th></tr>
+  {{- range .References }}
+    <tr><td><code>{{ html .Attribute }}</code></td><td>{{ if ne .Page "" }}<a href="{{ html .Page }}">{{ html .Database }}.{{ html .Collection }}</a>{{ else }}{{ html .Database }}.{{ html .Collection }}{{ end }}</td><td><code>{{ html .Attributes }}</code></td></tr>
+  {{- end }}
+  </table>
+  {{- end }}
+
+  <h2>Referenced by</h2>
+  {{- if eq (len .ReferencedBy) 0 }}
+  <p>No references from other collections found.</p>
+  {{- else }}
+  <table>
+    <tr><th>Collection</th><th>Field</th><th>Referenced fields</th></tr>
+  {{- range .ReferencedBy }}
+    <tr><td>{{ if ne .Page "" }}<a href="{{ html .Page }}">{{ html .Database }}.{{ html .Collection }}</a>{{ else }}{{ html .Database }}.{{ html .Collection }}{{ end }}</td><td><code>{{ html .Attribute }}</code></td><td><code>{{ html .Attributes }}</code></td></tr>
+  {{- end }}
+  </table>
+  {{- end }}
+</body>
+</html>
+`
+
+var dictIndexHtmlTemplateStr = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{ html .Title }}</title>
+` + dictHtmlStyle + `
+</head>
+<body>
+  <h1>{{ html .Title }}</h1>
+{{- range .Databases }}
+
+  <h2>{{ html .Name }}</h2>
+  <table>
+    <tr><th>Collection</th><th>Processed documents</th><th>Fields</th><th>References</th></tr>
+  {{- range .Collections }}
+    <tr><td><a href="{{ html .Page }}">{{ html .Collection }}</a></td><td class="number">{{ .SampleCount }}</td><td class="number">{{ len .Fields }}</td><td class="number">{{ len .References }}</td></tr>
+  {{- end }}
+  </table>
+{{- end }}
+</body>
+</html>
+`
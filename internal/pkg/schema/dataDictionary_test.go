@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDictFields(t *testing.T) {
+	mainType, otherComplexTypes := goldenTestSchema(t)
+	fields := dictFields(&PersistedSchema{Database: "shop", Collection: "orders", MainType: mainType, OtherComplexTypes: otherComplexTypes})
+	paths := make(map[string]DictField)
+	for _, f := range fields {
+		paths[f.Path+"|"+f.Variant] = f
+	}
+	assert.Contains(t, paths, "customer.name|")
+	assert.Contains(t, paths, "items[].sku|")
+	assert.Contains(t, paths, "prices.<key>|")
+	assert.Contains(t, paths, "url|OrdersOnline (type=online)")
+	assert.Contains(t, paths, "store|OrdersStore (type=store)")
+	// inherited attributes are only contained once for the main type
+	assert.NotContains(t, paths, "type|OrdersOnline (type=online)")
+	assert.True(t, paths["type|"].IsEnum)
+	assert.True(t, paths["note|"].IsNullable)
+}
+
+func TestNewDictCollections(t *testing.T) {
+	schemas, colRefs := linkedTestSchemas(t)
+	indexes := map[string][]string{"shop.customers": {"_id_: {_id: 1}"}}
+	collections := NewDictCollections(DICT_FORMAT_MARKDOWN, schemas, indexes, colRefs)
+	require.Len(t, collections, 2)
+	customers, orders := collections[0], collections[1]
+
+	assert.Equal(t, "shop_customers.dict.md", customers.Page)
+	assert.True(t, customers.IndexesRead)
+	assert.Equal(t, []string{"_id_: {_id: 1}"}, customers.Indexes)
+	assert.Empty(t, customers.References)
+	assert.Equal(t, []DictReference{
+		{Attribute: "customerId", Database: "shop", Collection: "orders", Attributes: "_id", Page: "shop_orders.dict.md"},
+	}, customers.ReferencedBy)
+
+	assert.False(t, orders.IndexesRead)
+	assert.Equal(t, []DictReference{
+		{Attribute: "customerId", Database: "shop", Collection: "customers", Attributes: "_id", Page: "shop_customers.dict.md"},
+		// collections without schema are not linked
+		{Attribute: "items_sub-productId", Database: "crm", Collection: "products", Attributes: "sku"},
+	}, orders.References)
+	assert.Empty(t, orders.ReferencedBy)
+
+	index := NewDictIndexInput("Data dictionary", collections)
+	require.Len(t, index.Databases, 1)
+	assert.Equal(t, "shop", index.Databases[0].Name)
+	assert.Len(t, index.Databases[0].Collections, 2)
+}
+
+func TestWriteDictionary(t *testing.T) {
+	schemas, colRefs := linkedTestSchemas(t)
+	indexes := map[string][]string{"shop.customers": {"_id_: {_id: 1}"}}
+	tests := []struct {
+		format string
+		ext    string
+		index  string
+		// the special characters of the attribute names and descriptions must be escaped
+		escaped    []string
+		notEscaped []string
+	}{
+		{
+			format: DICT_FORMAT_MARKDOWN,
+			ext:    "md",
+			index:  "index.md",
+			escaped: []string{
+				"Customers with \\*VIP\\* status \\| &lt;b&gt;bold&lt;/b&gt; & more",
+				"Full name\\_with \\`ticks\\` and a second line",
+				"| `<note>` |",
+				"a\\|b",
+			},
+			notEscaped: []string{"*VIP* status |", "<b>", "`ticks`"},
+		},
+		{
+			format: DICT_FORMAT_HTML,
+			ext:    "html",
+			index:  "index.html",
+			escaped: []string{
+				"Customers with *VIP* status | &lt;b&gt;bold&lt;/b&gt; &amp; more",
+				"Full name_with `ticks`\nand a second line",
+				"<code>&lt;note&gt;</code>",
+			},
+			notEscaped: []string{"<b>bold</b>", "<note>", "& more"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			outputDir := t.TempDir()
+			collections := NewDictCollections(test.format, schemas, indexes, colRefs)
+			for _, c := range collections {
+				require.Nil(t, WriteDictionaryPage(test.format, c, outputDir))
+				page := readTestOutput(t, outputDir, c.Page)
+				assertGolden(t, c.Page, page)
+				if c.Collection != "customers" {
+					continue
+				}
+				for _, s := range test.escaped {
+					assert.Contains(t, page, s)
+				}
+				for _, s := range test.notEscaped {
+					assert.NotContains(t, page, s)
+				}
+			}
+			index := NewDictIndexInput("Data dictionary <shop>", collections)
+			require.Nil(t, WriteDictionaryIndex(test.format, &index, outputDir))
+			assertGolden(t, "shop.dict_"+test.index, readTestOutput(t, outputDir, test.index))
+		})
+	}
+}
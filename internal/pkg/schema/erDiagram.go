@@ -0,0 +1,244 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	linkshelper "okieoth/schemaguesser/internal/pkg/linksHelper"
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+)
+
+const ER_FORMAT_PUML = "puml"
+const ER_FORMAT_MERMAID = "mermaid"
+
+var ErDiagramFormats = []string{ER_FORMAT_PUML, ER_FORMAT_MERMAID}
+
+// if set, all top level attributes of the collections are shown in the entity-relationship diagrams,
+// otherwise only '_id' and the attributes that take part in references
+var ErAllAttributes bool
+
+func CheckErDiagramFormat(format string) error {
+	if !slices.Contains(ErDiagramFormats, format) {
+		return fmt.Errorf("unknown diagram format '%s', possible values are: %v", format, ErDiagramFormats)
+	}
+	return nil
+}
+
+type ErAttribute struct {
+	Name string
+	Type string
+	// '_id' attribute
+	IsKey bool
+	// the attribute references another collection
+	IsReference bool
+	// the attribute has different types, 'Type' contains all of them
+	IsPolymorphic bool
+}
+
+type ErEntity struct {
+	// identifier of the entity in the diagram
+	Id         string
+	Label      string
+	Database   string
+	Collection string
+	Attributes []ErAttribute
+	// true for referenced collections without a persisted schema, e.g. collections of other databases
+	SchemaMissing bool
+}
+
+type ErRelation struct {
+	Start string
+	End   string
+	Label string
+	// true if the referenced attribute is the '_id' of the target collection
+	ToId bool
+}
+
+type ErDiagramInput struct {
+	Title     string
+	Entities  []ErEntity
+	Relations []ErRelation
+}
+
+func erEntityId(database string, collection string) string {
+	replaceInvalid := func(r rune) rune {
+		if ((r >= 'a') && (r <= 'z')) || ((r >= 'A') && (r <= 'Z')) || ((r >= '0') && (r <= '9')) {
+			return r
+		}
+		return '_'
+	}
+	return strings.Map(replaceInvalid, database) + "__" + strings.Map(replaceInvalid, collection)
+}
+
+// returns the top level attribute of an attribute path from the key values, e.g. 'items_sub-productId' -> 'items'
+func topLevelAttribute(attribPath string) string {
+	ret, _, _ := strings.Cut(attribPath, "-")
+	for strings.HasSuffix(ret, "_sub") && (ret != "_sub") {
+		ret = strings.TrimSuffix(ret, "_sub")
+	}
+	return ret
+}
+
+func erAttributeType(prop *mongoHelper.BasicElemInfo) string {
+	switch {
+	case mongoHelper.IsPolymorphic(prop):
+		return unionTypeName(*prop)
+	case prop.IsComplex:
+		return prop.ValueType + arrayMarker(prop.ArrayDimensions)
+	case (prop.BsonType == "binData") && (prop.Format == mongoHelper.FORMAT_UUID):
+		return mongoHelper.FORMAT_UUID + arrayMarker(prop.ArrayDimensions)
+	}
+	return validatorBsonType(prop.BsonType) + arrayMarker(prop.ArrayDimensions)
+}
+
+type erDiagramBuilder struct {
+	database string
+	schemas  []PersistedSchema
+	entities []ErEntity
+	// attributes per entity id that take part in references
+	referencing map[string][]string
+	referenced  map[string][]string
+}
+
+func (b *erDiagramBuilder) entityIndex(database string, collection string) int {
+	return slices.IndexFunc(b.entities, func(e ErEntity) bool {
+		return (e.Database == database) && (e.Collection == collection)
+	})
+}
+
+// adds an entity for referenced collections without schema
+func (b *erDiagramBuilder) ensureEntity(database string, collection string) string {
+	if i := b.entityIndex(database, collection); i >= 0 {
+		return b.entities[i].Id
+	}
+	b.entities = append(b.entities, ErEntity{
+		Id:            erEntityId(database, collection),
+		Label:         b.entityLabel(database, collection),
+		Database:      database,
+		Collection:    collection,
+		SchemaMissing: true,
+	})
+	return b.entities[len(b.entities)-1].Id
+}
+
+// the database is only part of the label for diagrams over multiple databases, or for collections of other databases
+func (b *erDiagramBuilder) entityLabel(database string, collection string) string {
+	if database == b.database {
+		return collection
+	}
+	return database + "." + collection
+}
+
+func (b *erDiagramBuilder) attributes(s *PersistedSchema, id string) []ErAttribute {
+	ret := make([]ErAttribute, 0)
+	add := func(attribName string) {
+		if slices.ContainsFunc(ret, func(a ErAttribute) bool { return a.Name == attribName }) {
+			return
+		}
+		a := ErAttribute{
+			Name:        attribName,
+			IsKey:       attribName == "_id",
+			IsReference: slices.Contains(b.referencing[id], attribName),
+		}
+		if prop := s.findAttribute(attribName); prop != nil {
+			a.Type = erAttributeType(prop)
+			a.IsPolymorphic = mongoHelper.IsPolymorphic(prop)
+		}
+		ret = append(ret, a)
+	}
+	if ErAllAttributes {
+		for _, p := range s.MainType.Properties {
+			add(p.AttribName)
+		}
+	} else if s.findAttribute("_id") != nil {
+		add("_id")
+	}
+	for _, a := range b.referencing[id] {
+		add(a)
+	}
+	for _, a := range b.referenced[id] {
+		add(a)
+	}
+	return ret
+}
+
+// Creates the input for an entity-relationship diagram. The persisted schemas are the entities and the
+// references between them, that were found by the 'get links' command, are the relations. If the
+// database is not empty, collections of other databases are labeled with their database name.
+func NewErDiagramInput(title string, database string, schemas []PersistedSchema, colRefs []linkshelper.ColRefs) ErDiagramInput {
+	b := erDiagramBuilder{
+		database:    database,
+		schemas:     schemas,
+		entities:    make([]ErEntity, 0),
+		referencing: make(map[string][]string),
+		referenced:  make(map[string][]string),
+	}
+	for _, s := range schemas {
+		b.entities = append(b.entities, ErEntity{
+			Id:         erEntityId(s.Database, s.Collection),
+			Label:      b.entityLabel(s.Database, s.Collection),
+			Database:   s.Database,
+			Collection: s.Collection,
+		})
+	}
+	schemaCount := len(b.entities)
+	isIncluded := func(database string, collection string) bool {
+		i := b.entityIndex(database, collection)
+		return (i >= 0) && (i < schemaCount)
+	}
+
+	relations := make([]ErRelation, 0)
+	addAttrib := func(m map[string][]string, id string, attribName string) {
+		if !slices.Contains(m[id], attribName) {
+			m[id] = append(m[id], attribName)
+		}
+	}
+	for _, cr := range colRefs {
+		for _, ar := range cr.AttribRefs {
+			for _, ref := range ar.References {
+				if !isIncluded(cr.Db, cr.Collection) && !isIncluded(ref.Db, ref.Collection) {
+					continue
+				}
+				start := b.ensureEntity(cr.Db, cr.Collection)
+				end := b.ensureEntity(ref.Db, ref.Collection)
+				addAttrib(b.referencing, start, topLevelAttribute(ar.AttribStr))
+				for _, a := range ref.Attributes {
+					addAttrib(b.referenced, end, topLevelAttribute(a))
+				}
+				r := ErRelation{
+					Start: start,
+					End:   end,
+					Label: fmt.Sprintf("%s -> %s", ar.AttribStr, strings.Join(ref.Attributes, ", ")),
+					ToId:  slices.Contains(ref.Attributes, "_id"),
+				}
+				if !slices.Contains(relations, r) {
+					relations = append(relations, r)
+				}
+			}
+		}
+	}
+	for i := range b.entities {
+		if i < schemaCount {
+			b.entities[i].Attributes = b.attributes(&b.schemas[i], b.entities[i].Id)
+			continue
+		}
+		// without schema only the names of the referenced attributes are known
+		for _, a := range b.referenced[b.entities[i].Id] {
+			b.entities[i].Attributes = append(b.entities[i].Attributes, ErAttribute{Name: a, IsKey: a == "_id"})
+		}
+	}
+	return ErDiagramInput{
+		Title:     title,
+		Entities:  b.entities,
+		Relations: relations,
+	}
+}
+
+// writes the diagram to '<database>_all.er.puml' or '<database>_all.er.mmd'
+func WriteErDiagram(format string, database string, input *ErDiagramInput, outputDir string) error {
+	if format == ER_FORMAT_MERMAID {
+		return printTemplateBase("er_mermaid.tmpl", erMermaidTemplateStr, "er.mmd", database, "all", input, outputDir)
+	}
+	return printTemplateBase("er_plantuml.tmpl", erPumlTemplateStr, "er.puml", database, "all", input, outputDir)
+}
@@ -0,0 +1,45 @@
+package schema
+
+var erPumlTemplateStr = `
+@startuml
+title {{ .Title }}\n\n
+hide circle
+hide empty members
+skinparam linetype ortho
+
+footer Created with https://github.com/OkieOth/mschemaguesser
+
+{{ range $index, $entity := .Entities -}}
+entity "{{ $entity.Label }}" as {{ $entity.Id }} {{ if $entity.SchemaMissing }}#EEEEEE{{ else }}#FFFFFF{{ end }} {
+  {{- range $entity.Attributes }}
+  {{ if .IsKey }}* {{ end }}{{ .Name }}{{ if ne .Type "" }} : {{ .Type }}{{ end }}{{ if .IsReference }} <<FK>>{{ end }}
+  {{- end }}
+}
+
+{{ end -}}
+
+{{ range $index, $relation := .Relations -}}
+{{ $relation.Start }} }o--{{ if $relation.ToId }}||{{ else }}o{ {{- end }} {{ $relation.End }} : {{ $relation.Label }}
+{{ end }}
+@enduml
+`
+
+var erMermaidTemplateStr = `---
+title: "{{ .Title }}"
+---
+erDiagram
+{{- range $index, $entity := .Entities }}
+  {{- if gt (len $entity.Attributes) 0 }}
+  {{ $entity.Id }}["{{ $entity.Label }}"] {
+    {{- range $entity.Attributes }}
+    {{ if eq .Type "" }}unknown{{ else if .IsPolymorphic }}mixed{{ else }}{{ MermaidErWord .Type }}{{ end }} {{ MermaidErWord .Name }}{{ if .IsKey }} PK{{ else if .IsReference }} FK{{ end }}
+    {{- end }}
+  }
+  {{- else }}
+  {{ $entity.Id }}["{{ $entity.Label }}"]
+  {{- end }}
+{{- end }}
+{{- range $index, $relation := .Relations }}
+  {{ $relation.Start }} }o--{{ if $relation.ToId }}||{{ else }}o{ {{- end }} {{ $relation.End }} : "{{ Replace $relation.Label "\"" "'" }}"
+{{- end }}
+`
@@ -0,0 +1,82 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopLevelAttribute(t *testing.T) {
+	tests := map[string]string{
+		"customerId":                "customerId",
+		"items_sub-productId":       "items",
+		"matrix_sub_sub-value":      "matrix",
+		"address-city":              "address",
+		"_sub":                      "_sub",
+		"history_sub-changes-value": "history",
+	}
+	for attribPath, expected := range tests {
+		assert.Equal(t, expected, topLevelAttribute(attribPath), attribPath)
+	}
+}
+
+func TestNewErDiagramInput(t *testing.T) {
+	schemas, colRefs := linkedTestSchemas(t)
+	input := NewErDiagramInput("Collections of database: shop", "shop", schemas, colRefs)
+
+	require.Len(t, input.Entities, 3)
+	customers, orders, products := input.Entities[0], input.Entities[1], input.Entities[2]
+	assert.Equal(t, "shop__customers", customers.Id)
+	assert.Equal(t, "customers", customers.Label)
+	assert.False(t, customers.SchemaMissing)
+	assert.Equal(t, []ErAttribute{{Name: "_id", Type: "int", IsKey: true}}, customers.Attributes)
+
+	assert.Equal(t, []ErAttribute{
+		{Name: "_id", Type: "string", IsKey: true},
+		{Name: "customerId", Type: "int", IsReference: true},
+		{Name: "items", Type: "Items[]", IsReference: true},
+	}, orders.Attributes)
+
+	// referenced collection of another database without persisted schema
+	assert.Equal(t, "crm__products", products.Id)
+	assert.Equal(t, "crm.products", products.Label)
+	assert.True(t, products.SchemaMissing)
+	assert.Equal(t, []ErAttribute{{Name: "sku"}}, products.Attributes)
+
+	assert.Equal(t, []ErRelation{
+		{Start: "shop__orders", End: "shop__customers", Label: "customerId -> _id", ToId: true},
+		{Start: "shop__orders", End: "crm__products", Label: "items_sub-productId -> sku"},
+	}, input.Relations)
+}
+
+func TestNewErDiagramInputAllAttributes(t *testing.T) {
+	defer func(v bool) { ErAllAttributes = v }(ErAllAttributes)
+	ErAllAttributes = true
+	schemas, colRefs := linkedTestSchemas(t)
+	input := NewErDiagramInput("Collections of database: shop", "shop", schemas, colRefs)
+	names := make([]string, 0)
+	for _, a := range input.Entities[0].Attributes {
+		names = append(names, a.Name)
+	}
+	assert.Equal(t, []string{"_id", "name (full)", "<note>", "size~{cm}", "address"}, names)
+	assert.True(t, input.Entities[0].Attributes[3].IsPolymorphic)
+}
+
+func TestWriteErDiagram(t *testing.T) {
+	defer func(v bool) { ErAllAttributes = v }(ErAllAttributes)
+	schemas, colRefs := linkedTestSchemas(t)
+	for _, allAttributes := range []bool{false, true} {
+		ErAllAttributes = allAttributes
+		input := NewErDiagramInput("Collections of database: shop", "shop", schemas, colRefs)
+		suffix := ""
+		if allAttributes {
+			suffix = "_all_attributes"
+		}
+		outputDir := t.TempDir()
+		require.Nil(t, WriteErDiagram(ER_FORMAT_MERMAID, "shop", &input, outputDir))
+		assertGolden(t, "shop"+suffix+".er.mmd", readTestOutput(t, outputDir, "shop_all.er.mmd"))
+		require.Nil(t, WriteErDiagram(ER_FORMAT_PUML, "shop", &input, outputDir))
+		assertGolden(t, "shop"+suffix+".er.puml", readTestOutput(t, outputDir, "shop_all.er.puml"))
+	}
+}
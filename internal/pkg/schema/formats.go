@@ -0,0 +1,42 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+)
+
+const FORMAT_JSON_SCHEMA = "json"
+const FORMAT_GO = "go"
+const FORMAT_TYPESCRIPT = "ts"
+const FORMAT_PROTOBUF = "proto"
+const FORMAT_AVRO = "avro"
+
+var OutputFormats = []string{FORMAT_JSON_SCHEMA, FORMAT_GO, FORMAT_TYPESCRIPT, FORMAT_PROTOBUF, FORMAT_AVRO}
+
+// format of the created model for every collection
+var OutputFormat = FORMAT_JSON_SCHEMA
+
+func CheckOutputFormat(outputFormat string) error {
+	if !slices.Contains(OutputFormats, outputFormat) {
+		return fmt.Errorf("unknown output format '%s', possible values are: %v", outputFormat, OutputFormats)
+	}
+	return nil
+}
+
+// writes the model of the collection in the configured output format
+func PrintModel(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) error {
+	switch OutputFormat {
+	case FORMAT_GO:
+		return PrintGoStructs(database, collection, mainType, otherComplexTypes, outputDir)
+	case FORMAT_TYPESCRIPT:
+		return PrintTypeScript(database, collection, mainType, otherComplexTypes, outputDir)
+	case FORMAT_PROTOBUF:
+		return PrintProtobuf(database, collection, mainType, otherComplexTypes, outputDir)
+	case FORMAT_AVRO:
+		return PrintAvroSchema(database, collection, mainType, otherComplexTypes, outputDir)
+	default:
+		return PrintSchema(database, collection, mainType, otherComplexTypes, outputDir)
+	}
+}
@@ -0,0 +1,328 @@
+package schema
+
+import (
+	"fmt"
+	"go/format"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+)
+
+// package name of the generated go code
+var GoPackage = "model"
+
+const goImportTime = "time"
+const goImportPrimitive = "go.mongodb.org/mongo-driver/bson/primitive"
+const goImportUuid = "github.com/google/uuid"
+
+type goTypeMapping struct {
+	goType     string
+	importPath string
+}
+
+// go types for the BSON types, that don't fit to the plain JSON types
+var goBsonTypes = map[string]goTypeMapping{
+	"objectId":            {"primitive.ObjectID", goImportPrimitive},
+	"date":                {"time.Time", goImportTime},
+	"decimal":             {"primitive.Decimal128", goImportPrimitive},
+	"timestamp":           {"primitive.Timestamp", goImportPrimitive},
+	"regex":               {"primitive.Regex", goImportPrimitive},
+	"javascript":          {"primitive.JavaScript", goImportPrimitive},
+	"javascriptWithScope": {"primitive.CodeWithScope", goImportPrimitive},
+	"symbol":              {"primitive.Symbol", goImportPrimitive},
+	"dbPointer":           {"primitive.DBPointer", goImportPrimitive},
+	"undefined":           {"primitive.Undefined", goImportPrimitive},
+	"minKey":              {"primitive.MinKey", goImportPrimitive},
+	"maxKey":              {"primitive.MaxKey", goImportPrimitive},
+	"int":                 {"int32", ""},
+	"long":                {"int64", ""},
+	"double":              {"float64", ""},
+	"bool":                {"bool", ""},
+	"string":              {"string", ""},
+}
+
+type goGenerator struct {
+	imports []string
+	// names of the complex types that are rendered as maps
+	dictTypes []string
+	// uuid.UUID needs its own codec, the default registry decodes binary values only into byte slices
+	usesUuid bool
+	sb       strings.Builder
+}
+
+func (g *goGenerator) addImport(importPath string) {
+	if (importPath != "") && !slices.Contains(g.imports, importPath) {
+		g.imports = append(g.imports, importPath)
+	}
+}
+
+// creates an exported go identifier out of an attribute or type name, e.g. 'order_items' -> 'OrderItems'
+func goIdentifier(name string) string {
+	var sb strings.Builder
+	for _, w := range splitWords(name) {
+		sb.WriteString(upperFirst(w))
+	}
+	ret := sb.String()
+	if ret == "" {
+		return "X"
+	}
+	if unicode.IsDigit([]rune(ret)[0]) {
+		return "X" + ret
+	}
+	return ret
+}
+
+// go type of a single, not array value
+func (g *goGenerator) goBaseType(t mongoHelper.ObservedType) string {
+	if t.IsComplex {
+		return goIdentifier(t.ValueType)
+	}
+	// the default registry of the mongo driver decodes only the binary subtypes 0 and 2 into byte slices,
+	// so uuids are decoded by the codec of the generated code and the other subtypes need primitive.Binary
+	if (t.BsonType == "binData") && (t.Format == mongoHelper.FORMAT_UUID) {
+		g.addImport(goImportUuid)
+		g.usesUuid = true
+		return "uuid.UUID"
+	}
+	if t.BsonType == "binData" {
+		g.addImport(goImportPrimitive)
+		return "primitive.Binary"
+	}
+	if m, ok := goBsonTypes[t.BsonType]; ok {
+		g.addImport(m.importPath)
+		return m.goType
+	}
+	return "any"
+}
+
+// common go type of the observed types of a polymorphic attribute, numbers are widened, all
+// other mixtures end in 'any'
+func (g *goGenerator) goUnionType(types []mongoHelper.ObservedType) string {
+	dims := types[0].ArrayDimensions
+	bsonTypes := make([]string, 0)
+	for _, t := range types {
+		if (t.ArrayDimensions != dims) || t.IsComplex {
+			return "any"
+		}
+		if !slices.Contains(bsonTypes, t.BsonType) {
+			bsonTypes = append(bsonTypes, t.BsonType)
+		}
+	}
+	var itemType string
+	switch {
+	case !slices.ContainsFunc(bsonTypes, func(s string) bool { return (s != "int") && (s != "long") }):
+		itemType = "int64"
+	case !slices.ContainsFunc(bsonTypes, func(s string) bool { return (s != "int") && (s != "long") && (s != "double") }):
+		itemType = "float64"
+	default:
+		itemType = "any"
+	}
+	return arrayMarker(dims) + itemType
+}
+
+func (g *goGenerator) goPropType(prop *mongoHelper.BasicElemInfo) string {
+	switch {
+	case mongoHelper.IsTuple(prop):
+		return "[]any"
+	case mongoHelper.IsPolymorphic(prop):
+		if isArrayUnion(*prop) {
+			return "[]" + g.goUnionType(unionItemTypes(*prop))
+		}
+		return g.goUnionType(prop.Types)
+	case prop.IsArray && (prop.BsonType == mongoHelper.EMPTY_ARRAY_BSON_TYPE):
+		return arrayMarker(prop.ArrayDimensions) + "any"
+	case (prop.BsonType == mongoHelper.NULL) && !prop.IsComplex:
+		return "any"
+	}
+	return arrayMarker(prop.ArrayDimensions) + g.goBaseType(mongoHelper.ObservedType{
+		ValueType: prop.ValueType,
+		BsonType:  prop.BsonType,
+		Format:    prop.Format,
+		IsComplex: prop.IsComplex,
+	})
+}
+
+// types that have already a 'nil' value are not rendered as pointers
+func (g *goGenerator) isNilable(goType string) bool {
+	return (goType == "any") || strings.HasPrefix(goType, "[]") || slices.Contains(g.dictTypes, goType)
+}
+
+func goTag(attribName string, omitEmpty bool) string {
+	name := attribName
+	if omitEmpty {
+		name += ",omitempty"
+	}
+	tag := fmt.Sprintf("bson:%s json:%s", strconv.Quote(name), strconv.Quote(name))
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// writes the not empty lines as '//' comments, it's used for go and protobuf
+func writeLineComment(sb *strings.Builder, indent string, lines ...string) {
+	for _, l := range lines {
+		for _, s := range strings.Split(l, "\n") {
+			if s = strings.TrimSpace(s); s != "" {
+				sb.WriteString(indent + "// " + s + "\n")
+			}
+		}
+	}
+}
+
+func (g *goGenerator) writeComment(indent string, lines ...string) {
+	writeLineComment(&g.sb, indent, lines...)
+}
+
+func (g *goGenerator) writeFields(props []mongoHelper.BasicElemInfo, sampleCount int64) {
+	fieldNames := make([]string, 0)
+	for i := range props {
+		p := &props[i]
+		fieldName := goIdentifier(p.AttribName)
+		for j := 2; slices.Contains(fieldNames, fieldName); j++ {
+			fieldName = goIdentifier(p.AttribName) + strconv.Itoa(j)
+		}
+		fieldNames = append(fieldNames, fieldName)
+
+		goType := g.goPropType(p)
+		optional := isOptional(*p, sampleCount)
+		if (optional || p.IsNullable) && !g.isNilable(goType) {
+			goType = "*" + goType
+		}
+		g.writeComment("\t", p.Title, p.Description, p.Comment)
+		if p.IsEnum {
+			g.writeComment("\t", "possible values: "+strings.Join(p.DistinctValues, ", "))
+		}
+		g.sb.WriteString(fmt.Sprintf("\t%s %s %s\n", fieldName, goType, goTag(p.AttribName, optional)))
+	}
+}
+
+func (g *goGenerator) writeType(complexType *mongoHelper.ComplexType, mainType *mongoHelper.ComplexType, doc ...string) {
+	typeName := goIdentifier(complexType.Name)
+	g.sb.WriteString("\n")
+	g.writeComment("", doc...)
+	g.writeComment("", complexType.Title, complexType.Description)
+	if complexType.IsDictionary {
+		valueType := "any"
+		if complexType.DictValue != nil {
+			valueType = g.goPropType(complexType.DictValue)
+			if complexType.DictValue.IsNullable && !g.isNilable(valueType) {
+				valueType = "*" + valueType
+			}
+		}
+		g.sb.WriteString(fmt.Sprintf("type %s map[string]%s\n", typeName, valueType))
+		return
+	}
+	g.sb.WriteString(fmt.Sprintf("type %s struct {\n", typeName))
+	props := complexType.Properties
+	if (complexType.BaseType != "") && (complexType.BaseType == mainType.Name) {
+		g.sb.WriteString(fmt.Sprintf("\t%s `bson:\",inline\"`\n", goIdentifier(mainType.Name)))
+		props = ownProperties(*complexType, mainType)
+	}
+	g.writeFields(props, complexType.SampleCount)
+	g.sb.WriteString("}\n")
+}
+
+// codec for the uuid.UUID attributes, the function name contains the main type, so the code of
+// multiple collections can be part of the same package
+const goUuidCodecStr = `
+// Register%[1]sCodecs registers the codec for the uuid.UUID attributes in a bson registry, the default
+// registry of the mongo driver decodes binary values only into byte slices. Legacy uuids (subtype 3)
+// are read in their stored byte order and written as subtype 4.
+//
+//	reg := bson.NewRegistry()
+//	Register%[1]sCodecs(reg)
+//	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetRegistry(reg))
+func Register%[1]sCodecs(reg *bsoncodec.Registry) {
+	uuidType := reflect.TypeOf(uuid.UUID{})
+	reg.RegisterTypeEncoder(uuidType, bsoncodec.ValueEncoderFunc(func(_ bsoncodec.EncodeContext, vw bsonrw.ValueWriter, v reflect.Value) error {
+		u := v.Interface().(uuid.UUID)
+		return vw.WriteBinaryWithSubtype(u[:], bson.TypeBinaryUUID)
+	}))
+	reg.RegisterTypeDecoder(uuidType, bsoncodec.ValueDecoderFunc(func(_ bsoncodec.DecodeContext, vr bsonrw.ValueReader, v reflect.Value) error {
+		if vr.Type() == bson.TypeNull {
+			v.Set(reflect.Zero(uuidType))
+			return vr.ReadNull()
+		}
+		data, subtype, err := vr.ReadBinary()
+		if err != nil {
+			return err
+		}
+		if (subtype != bson.TypeBinaryUUID) && (subtype != bson.TypeBinaryUUIDOld) {
+			return fmt.Errorf("binary subtype %%d can't be decoded into uuid.UUID", subtype)
+		}
+		u, err := uuid.FromBytes(data)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(u))
+		return nil
+	}))
+}
+`
+
+// Creates go structs for the main type and all other complex types of a collection
+func GoStructs(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) string {
+	g := goGenerator{imports: make([]string, 0), dictTypes: make([]string, 0)}
+	for _, t := range otherComplexTypes {
+		if t.IsDictionary {
+			g.dictTypes = append(g.dictTypes, goIdentifier(t.Name))
+		}
+	}
+	mainDoc := fmt.Sprintf("%s is the storage model for database: %s, collection: %s", goIdentifier(mainType.Name), database, collection)
+	if mainType.Discriminator != "" {
+		mainDoc += fmt.Sprintf("\nThe attribute '%s' distinguishes the variants", mainType.Discriminator)
+	}
+	g.writeType(mainType, mainType, mainDoc)
+	for i := range otherComplexTypes {
+		t := &otherComplexTypes[i]
+		var doc string
+		if t.DiscriminatorValue != "" {
+			doc = fmt.Sprintf("%s is the variant for %s: %s", goIdentifier(t.Name), mainType.Discriminator, t.DiscriminatorValue)
+		}
+		g.writeType(t, mainType, doc)
+	}
+	if g.usesUuid {
+		for _, i := range []string{"fmt", "reflect", "go.mongodb.org/mongo-driver/bson", "go.mongodb.org/mongo-driver/bson/bsoncodec", "go.mongodb.org/mongo-driver/bson/bsonrw"} {
+			g.addImport(i)
+		}
+		g.sb.WriteString(fmt.Sprintf(goUuidCodecStr, goIdentifier(mainType.Name)))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("// Code generated by schemaguesser. DO NOT EDIT.\n\n")
+	sb.WriteString(fmt.Sprintf("package %s\n", GoPackage))
+	if len(g.imports) > 0 {
+		// standard library first, separated by an empty line from the other imports
+		slices.Sort(g.imports)
+		std := slices.DeleteFunc(slices.Clone(g.imports), func(s string) bool { return strings.Contains(s, ".") })
+		other := slices.DeleteFunc(slices.Clone(g.imports), func(s string) bool { return !strings.Contains(s, ".") })
+		sb.WriteString("\nimport (\n")
+		for _, i := range std {
+			sb.WriteString(fmt.Sprintf("\t%s\n", strconv.Quote(i)))
+		}
+		if (len(std) > 0) && (len(other) > 0) {
+			sb.WriteString("\n")
+		}
+		for _, i := range other {
+			sb.WriteString(fmt.Sprintf("\t%s\n", strconv.Quote(i)))
+		}
+		sb.WriteString(")\n")
+	}
+	sb.WriteString(g.sb.String())
+
+	formatted, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		log.Printf("[%s:%s] error, failed to format the go code: %v", database, collection, err)
+		return sb.String()
+	}
+	return string(formatted)
+}
+
+func PrintGoStructs(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) error {
+	return writeTextOutput(GoStructs(database, collection, mainType, otherComplexTypes), "go", database, collection, outputDir)
+}
@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGoStructsBinaryTypes(t *testing.T) {
+	docs := []bson.M{
+		{"_id": primitive.NewObjectID(), "uid": primitive.Binary{Subtype: 4, Data: make([]byte, 16)}, "data": primitive.Binary{Data: []byte{1}}},
+	}
+	mainType, otherComplexTypes := guessTestSchema(t, "test", "", docs)
+	code := GoStructs("db", "test", mainType, otherComplexTypes)
+	// the documents are maps, so the order and the alignment of the fields is not fixed
+	assert.Regexp(t, "// Mongodb type binary: subtype=4\n\tUid +uuid.UUID +`bson:\"uid\" json:\"uid\"`", code)
+	assert.Regexp(t, "Data +primitive.Binary +`bson:\"data\" json:\"data\"`", code)
+	assert.Contains(t, code, "\"github.com/google/uuid\"")
+	assert.Contains(t, code, "func RegisterTestCodecs(reg *bsoncodec.Registry) {")
+}
+
+func TestGoStructsWithoutUuids(t *testing.T) {
+	docs := []bson.D{
+		{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "data", Value: primitive.Binary{Data: []byte{1}}}},
+	}
+	mainType, otherComplexTypes := guessTestSchema(t, "test", "", docs)
+	code := GoStructs("db", "test", mainType, otherComplexTypes)
+	assert.NotContains(t, code, "github.com/google/uuid")
+	assert.NotContains(t, code, "Codecs")
+}
+
+// The generated structs are compiled together with a small program, that decodes the documents
+// with the default registry of the mongo driver and the generated uuid codec into the main type
+func TestGoStructsDecodeDocuments_IT(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles the generated code")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go toolchain found")
+	}
+	dec, err := primitive.ParseDecimal128("12.34")
+	require.Nil(t, err)
+	created := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	uid := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	docs := []bson.M{
+		{
+			"_id": primitive.NewObjectID(), "uid": primitive.Binary{Subtype: 4, Data: uid[:]},
+			"legacy": primitive.Binary{Subtype: 3, Data: make([]byte, 16)}, "raw": primitive.Binary{Data: []byte{1, 2}},
+			"price": dec, "created": created, "count": int32(1), "total": int64(2), "ratio": 0.5,
+			"name": "a", "tags": bson.A{"x", "y"}, "address": bson.M{"street": "s", "zip": int32(1)},
+			"items": bson.A{bson.M{"qty": int32(1), "ref": primitive.Binary{Subtype: 4, Data: make([]byte, 16)}}},
+			"ts":    primitive.Timestamp{T: 1, I: 2}, "opt": nil,
+		},
+		{
+			"_id": primitive.NewObjectID(), "uid": primitive.Binary{Subtype: 4, Data: uid[:]},
+			"legacy": primitive.Binary{Subtype: 3, Data: make([]byte, 16)}, "raw": primitive.Binary{Data: []byte{3}},
+			"price": dec, "created": created, "count": int32(2), "total": int64(3), "ratio": 1.5,
+			"name": "b", "tags": bson.A{}, "items": bson.A{},
+			"ts": primitive.Timestamp{T: 2, I: 1}, "opt": "text",
+		},
+	}
+	mainType, otherComplexTypes := guessTestSchema(t, "orders", "", docs)
+	saved := GoPackage
+	GoPackage = "main"
+	defer func() { GoPackage = saved }()
+	code := GoStructs("db", "orders", mainType, otherComplexTypes)
+
+	var sb strings.Builder
+	sb.WriteString("package main\n\nimport (\n\t\"encoding/hex\"\n\t\"fmt\"\n\t\"os\"\n\n\t\"go.mongodb.org/mongo-driver/bson\"\n)\n\n")
+	sb.WriteString("var docs = []string{\n")
+	for _, d := range docs {
+		b, err := bson.Marshal(d)
+		require.Nil(t, err)
+		sb.WriteString(fmt.Sprintf("\t%q,\n", hex.EncodeToString(b)))
+	}
+	sb.WriteString("}\n\n")
+	sb.WriteString(`func main() {
+	reg := bson.NewRegistry()
+	RegisterOrdersCodecs(reg)
+	for _, d := range docs {
+		b, err := hex.DecodeString(d)
+		if err != nil {
+			panic(err)
+		}
+		var o Orders
+		if err := bson.UnmarshalWithRegistry(reg, b, &o); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println(o.Uid)
+	}
+}
+`)
+	// the module uses the dependencies of this module
+	dir := t.TempDir()
+	goMod, err := os.ReadFile(filepath.Join("..", "..", "..", "go.mod"))
+	require.Nil(t, err)
+	goSum, err := os.ReadFile(filepath.Join("..", "..", "..", "go.sum"))
+	require.Nil(t, err)
+	goMod = []byte(strings.Replace(string(goMod), "module okieoth/schemaguesser", "module gostructstest", 1))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "go.mod"), goMod, 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "go.sum"), goSum, 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "model.go"), []byte(code), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(sb.String()), 0644))
+
+	cmd := exec.Command(goBin, "run", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.Nil(t, err, "generated code:\n%s\noutput:\n%s", code, out)
+	assert.Equal(t, strings.Repeat(uid.String()+"\n", 2), string(out))
+}
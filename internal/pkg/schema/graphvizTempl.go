@@ -0,0 +1,73 @@
+package schema
+
+var graphvizTemplateStr = `digraph {{ printf "%q" (print .Database "_" .Collection) }} {
+  graph [label={{ printf "%q" (print "Storage model for database: " .Database ", collection: " .Collection) }}, labelloc=t, rankdir=LR, fontname="Helvetica"]
+  node [shape=plain, fontname="Helvetica"]
+  edge [fontname="Helvetica"]
+
+  {{ printf "%q" .MainType.Name }} [label=<<table border="0" cellborder="1" cellspacing="0" cellpadding="4">
+    <tr><td bgcolor="lightgrey"><b>{{ html .MainType.Name }}</b></td></tr>
+  {{- range $index, $prop := .MainType.Properties }}
+  {{- if IsPolymorphic $prop }}
+    <tr><td align="left">{{ html $prop.AttribName }}: {{ html (UnionTypeName $prop) }}{{ if $prop.IsNullable }}?{{ end }} <font color="darkorange">(polymorphic)</font></td></tr>
+  {{- else if $prop.IsEnum }}
+    <tr><td align="left">{{ html $prop.AttribName }}: {{ html (EnumTypeName $.MainType.Name $prop) }}{{ if $prop.IsNullable }}?{{ end }} <font color="grey">// {{ html $prop.BsonType }}</font></td></tr>
+  {{- else }}
+    <tr><td align="left">{{ html $prop.AttribName }}: {{ html $prop.ValueType }}{{ ArrayMarker $prop.ArrayDimensions }}{{ if $prop.IsNullable }}?{{ end }}
+    {{- if not $prop.IsComplex }} <font color="grey">// {{ html $prop.BsonType }}</font>{{ end }}</td></tr>
+  {{- end }}
+  {{- end }}
+  {{- range .MainType.Comments }}
+    <tr><td align="left"><i>{{ html . }}</i></td></tr>
+  {{- end }}
+  </table>>]
+{{- range $index, $type := .OtherComplexTypes }}
+  {{- if $type.IsDictionary }}
+
+  {{ printf "%q" $type.Name }} [label=<<table border="0" cellborder="1" cellspacing="0" cellpadding="4">
+    <tr><td bgcolor="lightgrey">&laquo;Map&raquo;<br/><b>{{ html $type.Name }}</b></td></tr>
+    {{- if ne $type.DictKeyFormat "" }}
+    <tr><td align="left">keyFormat: {{ html $type.DictKeyFormat }}</td></tr>
+    {{- end }}
+    {{- if $type.DictValue }}
+    <tr><td align="left">valueType: {{ html (UnionTypeName $type.DictValue) }}{{ if $type.DictValue.IsNullable }}?{{ end }}</td></tr>
+    {{- end }}
+  </table>>]
+    {{- if and $type.DictValue $type.DictValue.IsComplex }}
+  {{ printf "%q" $type.Name }} -> {{ printf "%q" $type.DictValueType }} [style=dashed, arrowhead=none]
+    {{- end }}
+  {{- else }}
+
+  {{ printf "%q" $type.Name }} [label=<<table border="0" cellborder="1" cellspacing="0" cellpadding="4">
+    <tr><td bgcolor="lightgrey"><b>{{ html $type.Name }}</b></td></tr>
+  {{- range $index, $prop := OwnProperties $type $.MainType }}
+  {{- if IsPolymorphic $prop }}
+    <tr><td align="left">{{ html $prop.AttribName }}: {{ html (UnionTypeName $prop) }}{{ if $prop.IsNullable }}?{{ end }} <font color="darkorange">(polymorphic)</font></td></tr>
+  {{- else if $prop.IsEnum }}
+    <tr><td align="left">{{ html $prop.AttribName }}: {{ html (EnumTypeName $type.Name $prop) }}{{ if $prop.IsNullable }}?{{ end }} <font color="grey">// {{ html $prop.BsonType }}</font></td></tr>
+  {{- else }}
+    <tr><td align="left">{{ html $prop.AttribName }}: {{ html $prop.ValueType }}{{ ArrayMarker $prop.ArrayDimensions }}{{ if $prop.IsNullable }}?{{ end }}
+    {{- if not $prop.IsComplex }} <font color="grey">// {{ html $prop.BsonType }}</font>{{ end }}</td></tr>
+  {{- end }}
+  {{- end }}
+  </table>>]
+    {{- if ne $type.BaseType "" }}
+  {{ printf "%q" $type.BaseType }} -> {{ printf "%q" $type.Name }} [dir=back, arrowtail=empty]
+    {{- end }}
+  {{- end }}
+{{- end }}
+{{- range $index, $enum := .Enums }}
+
+  {{ printf "%q" $enum.Name }} [label=<<table border="0" cellborder="1" cellspacing="0" cellpadding="4">
+    <tr><td bgcolor="lightgrey">&laquo;enumeration&raquo;<br/><b>{{ html $enum.Name }}</b></td></tr>
+  {{- range $enum.Values }}
+    <tr><td align="left">{{ html . }}</td></tr>
+  {{- end }}
+  </table>>]
+  {{ printf "%q" $enum.Owner }} -> {{ printf "%q" $enum.Name }}
+{{- end }}
+{{ range $index, $type := .Relations }}
+  {{ printf "%q" $type.Start }} -> {{ printf "%q" $type.End }} [dir=back, arrowtail=diamond]
+{{- end }}
+}
+`
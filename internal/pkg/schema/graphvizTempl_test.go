@@ -0,0 +1,48 @@
+package schema
+
+import (
+	"testing"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+	"okieoth/schemaguesser/internal/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteGraphviz(t *testing.T) {
+	outputDir := t.TempDir()
+	mainType, otherComplexTypes := goldenTestSchema(t)
+	require.Nil(t, WriteGraphviz("shop", "orders", mainType, otherComplexTypes, outputDir))
+	assertGolden(t, "orders.schema.dot", readTestOutput(t, outputDir, "shop_orders.schema.dot"))
+
+	schemas, _ := linkedTestSchemas(t)
+	require.Nil(t, WriteGraphviz("shop", "customers", schemas[0].MainType, schemas[0].OtherComplexTypes, outputDir))
+	assertGolden(t, "customers.schema.dot", readTestOutput(t, outputDir, "shop_customers.schema.dot"))
+}
+
+func TestDiagramEscaping(t *testing.T) {
+	mainType := mongoHelper.ComplexType{
+		Name: "Orders",
+		Properties: []mongoHelper.BasicElemInfo{
+			{AttribName: "status", ValueType: "string", BsonType: "string", IsEnum: true, DistinctValues: []string{"a<b", "c{d}", "e\nf"}},
+			{AttribName: "labels", ValueType: "Labels<x>", BsonType: "object", IsComplex: true},
+		},
+	}
+	// dictionaries without value type must not break the templates
+	otherComplexTypes := []mongoHelper.ComplexType{{Name: "Labels<x>", IsDictionary: true}}
+	outputDir := t.TempDir()
+
+	require.Nil(t, WriteGraphviz(`my "shop"`, "orders", &mainType, otherComplexTypes, outputDir))
+	dot := readTestOutput(t, outputDir, utils.GetFileName("", "schema.dot", `my "shop"`, "orders"))
+	assert.Contains(t, dot, `graph [label="Storage model for database: my \"shop\", collection: orders"`)
+	assert.Contains(t, dot, "labels: Labels&lt;x&gt;</td>")
+	assert.Contains(t, dot, "<td align=\"left\">a&lt;b</td>")
+	assert.NotContains(t, dot, "Labels<x>:")
+
+	require.Nil(t, WritePlantUml("shop", "orders", &mainType, otherComplexTypes, outputDir))
+	puml := readTestOutput(t, outputDir, "shop_orders.schema.puml")
+	assert.Contains(t, puml, "\n  c[d]\n")
+	assert.Contains(t, puml, "\n  e f\n")
+	assert.NotContains(t, puml, "valueType")
+}
@@ -0,0 +1,460 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+	"okieoth/schemaguesser/internal/pkg/utils"
+)
+
+// Typed model of the created JSON schemas. The order of the fields defines the order of the keys
+// in the output, the 'x-' fields are extensions with the observations of the analysed documents.
+type JsonSchema struct {
+	Schema               string   `json:"$schema,omitempty"`
+	Id                   string   `json:"$id,omitempty"`
+	Title                string   `json:"title,omitempty"`
+	Description          string   `json:"description,omitempty"`
+	Version              string   `json:"version,omitempty"`
+	XModelType           string   `json:"x-model-type,omitempty"`
+	XCollection          string   `json:"x-collection,omitempty"`
+	XCollectionElemCount string   `json:"x-collection-elem-count,omitempty"`
+	XSampling            string   `json:"x-sampling,omitempty"`
+	XProcessingComments  []string `json:"x-processing-comments,omitempty"`
+
+	XPresenceRatio json.Number `json:"x-presence-ratio,omitempty"`
+	XObservedCount int64       `json:"x-observed-count,omitempty"`
+	*ValueKeywords
+	*ObservedValues
+	XComment   string   `json:"x-comment,omitempty"`
+	XBsonType  string   `json:"x-bson-type,omitempty"`
+	XBsonTypes []string `json:"x-bson-types,omitempty"`
+	Format     string   `json:"format,omitempty"`
+	Enum       []any    `json:"enum,omitempty"`
+
+	Type                TypeList `json:"type,omitempty"`
+	XDict               *bool    `json:"x-dict,omitempty"`
+	XDictKeyFormat      string   `json:"x-dict-key-format,omitempty"`
+	XBaseType           string   `json:"x-base-type,omitempty"`
+	XDiscriminatorValue string   `json:"x-discriminator-value,omitempty"`
+
+	Ref                   string                   `json:"$ref,omitempty"`
+	AnyOf                 []*JsonSchema            `json:"anyOf,omitempty"`
+	Items                 *JsonSchema              `json:"items,omitempty"`
+	PrefixItems           []*JsonSchema            `json:"prefixItems,omitempty"`
+	Required              []string                 `json:"required,omitempty"`
+	Properties            *OrderedMap[*JsonSchema] `json:"properties,omitempty"`
+	AdditionalProperties  *JsonSchema              `json:"additionalProperties,omitempty"`
+	OneOf                 []*JsonSchema            `json:"oneOf,omitempty"`
+	Discriminator         *Discriminator           `json:"discriminator,omitempty"`
+	UnevaluatedProperties *bool                    `json:"unevaluatedProperties,omitempty"`
+	Definitions           *OrderedMap[*JsonSchema] `json:"definitions,omitempty"`
+	Defs                  *OrderedMap[*JsonSchema] `json:"$defs,omitempty"`
+}
+
+// observed value statistics as JSON schema validation keywords
+type ValueKeywords struct {
+	Minimum   *float64 `json:"minimum,omitempty"`
+	Maximum   *float64 `json:"maximum,omitempty"`
+	MinLength *int64   `json:"minLength,omitempty"`
+	MaxLength *int64   `json:"maxLength,omitempty"`
+	MinItems  *int64   `json:"minItems,omitempty"`
+	MaxItems  *int64   `json:"maxItems,omitempty"`
+}
+
+// observed value statistics as extensions, they don't restrict the valid values. JSON schema
+// has no keywords for date ranges, so they are always written as extensions
+type ObservedValues struct {
+	Minimum       *float64 `json:"x-observed-minimum,omitempty"`
+	Maximum       *float64 `json:"x-observed-maximum,omitempty"`
+	FormatMinimum string   `json:"x-observed-formatMinimum,omitempty"`
+	FormatMaximum string   `json:"x-observed-formatMaximum,omitempty"`
+	MinLength     *int64   `json:"x-observed-minLength,omitempty"`
+	MaxLength     *int64   `json:"x-observed-maxLength,omitempty"`
+	MinItems      *int64   `json:"x-observed-minItems,omitempty"`
+	MaxItems      *int64   `json:"x-observed-maxItems,omitempty"`
+}
+
+type Discriminator struct {
+	PropertyName string             `json:"propertyName"`
+	Mapping      OrderedMap[string] `json:"mapping,omitempty"`
+}
+
+// JSON type keyword, one type is written as string, more types as array
+type TypeList []string
+
+func (t TypeList) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return marshalJson(t[0])
+	}
+	return marshalJson([]string(t))
+}
+
+type OrderedEntry[T any] struct {
+	Key   string
+	Value T
+}
+
+// JSON object that keeps the order of its keys
+type OrderedMap[T any] []OrderedEntry[T]
+
+func (m *OrderedMap[T]) Add(key string, value T) {
+	*m = append(*m, OrderedEntry[T]{Key: key, Value: value})
+}
+
+func (m OrderedMap[T]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, e := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := marshalJson(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJson(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// like json.Marshal, but without the escaping of HTML characters
+func marshalJson(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func typeList(nullable bool, types ...string) TypeList {
+	ret := TypeList(types)
+	if nullable && !(len(types) == 1 && types[0] == mongoHelper.NULL) {
+		ret = append(ret, mongoHelper.NULL)
+	}
+	return ret
+}
+
+// Creates the JSON schema for one collection
+func NewJsonSchema(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) *JsonSchema {
+	ret := mainTypeSchema(database, collection, mainType, otherComplexTypes)
+	ret.Schema = schemaUri()
+	ret.Version = "0.0.0"
+	ret.XModelType = "mongodb-storage-model"
+	ret.XSampling = mainType.Sampling
+	ret.XProcessingComments = mainType.Comments
+	if isDraft2020() {
+		ret.Id = schemaId(database, collection)
+	}
+	if mainType.Count.IsSet {
+		ret.XCollectionElemCount = strconv.FormatInt(mainType.Count.Value, 10)
+	}
+	definitions := make(OrderedMap[*JsonSchema], 0)
+	for i := range otherComplexTypes {
+		definitions.Add(otherComplexTypes[i].Name, complexTypeSchema(&otherComplexTypes[i]))
+	}
+	if isDraft2020() {
+		ret.Defs = &definitions
+	} else {
+		ret.Definitions = &definitions
+	}
+	return ret
+}
+
+// schema of the main type without the document level keywords
+func mainTypeSchema(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) *JsonSchema {
+	ret := JsonSchema{
+		Title:       mainType.Name,
+		Description: fmt.Sprintf("Storage model for database: %s, collection: %s", database, collection),
+		Type:        TypeList{"object"},
+		Required:    requiredProps(mainType),
+		Properties:  propertiesSchema(mainType),
+	}
+	if isDraft2020() {
+		ret.UnevaluatedProperties = boolPtr(false)
+	}
+	if mainType.Title != "" {
+		ret.Title = mainType.Title
+	}
+	if mainType.Description != "" {
+		ret.Description = mainType.Description
+	}
+	if mainType.Discriminator != "" {
+		ret.Discriminator = &Discriminator{PropertyName: mainType.Discriminator}
+		for _, v := range variants(otherComplexTypes) {
+			ret.OneOf = append(ret.OneOf, &JsonSchema{Ref: refPrefix() + v.Name})
+		}
+		for _, v := range discriminatorMapping(otherComplexTypes) {
+			ret.Discriminator.Mapping.Add(v.DiscriminatorValue, refPrefix()+v.Name)
+		}
+	}
+	return &ret
+}
+
+// calls the function for the schema and all its sub-schemas
+func walkSchema(s *JsonSchema, f func(*JsonSchema)) {
+	if s == nil {
+		return
+	}
+	f(s)
+	walkSchema(s.Items, f)
+	walkSchema(s.AdditionalProperties, f)
+	for _, l := range [][]*JsonSchema{s.AnyOf, s.PrefixItems, s.OneOf} {
+		for _, c := range l {
+			walkSchema(c, f)
+		}
+	}
+	for _, m := range []*OrderedMap[*JsonSchema]{s.Properties, s.Definitions, s.Defs} {
+		if m != nil {
+			for _, e := range *m {
+				walkSchema(e.Value, f)
+			}
+		}
+	}
+}
+
+func complexTypeSchema(complexType *mongoHelper.ComplexType) *JsonSchema {
+	ret := JsonSchema{
+		Type:                TypeList{"object"},
+		XDict:               boolPtr(complexType.IsDictionary),
+		XBaseType:           complexType.BaseType,
+		XDiscriminatorValue: complexType.DiscriminatorValue,
+	}
+	if complexType.IsDictionary {
+		ret.XDictKeyFormat = complexType.DictKeyFormat
+		if complexType.DictValue != nil {
+			ret.AdditionalProperties = propertySchema(complexType.DictValue)
+		}
+		return &ret
+	}
+	ret.Required = requiredProps(complexType)
+	ret.Properties = propertiesSchema(complexType)
+	if isDraft2020() {
+		ret.UnevaluatedProperties = boolPtr(false)
+	}
+	return &ret
+}
+
+func propertiesSchema(complexType *mongoHelper.ComplexType) *OrderedMap[*JsonSchema] {
+	ret := make(OrderedMap[*JsonSchema], 0)
+	for i := range complexType.Properties {
+		p := &complexType.Properties[i]
+		s := propertySchema(p)
+		if isOptional(*p, complexType.SampleCount) {
+			s.XPresenceRatio = json.Number(presenceRatio(*p, complexType.SampleCount))
+		}
+		ret.Add(p.AttribName, s)
+	}
+	return &ret
+}
+
+func propertySchema(prop *mongoHelper.BasicElemInfo) *JsonSchema {
+	ret := JsonSchema{
+		Title:       prop.Title,
+		Description: prop.Description,
+		XComment:    prop.Comment,
+	}
+	setValueStats(&ret, prop.Stats)
+	switch {
+	case isTuple(*prop):
+		ret.Type = typeList(prop.IsNullable, "array")
+		for _, t := range prop.TupleItems {
+			item := itemSchema(t)
+			item.XObservedCount = t.Count
+			ret.PrefixItems = append(ret.PrefixItems, item)
+		}
+	case mongoHelper.IsPolymorphic(prop):
+		if isArrayUnion(*prop) {
+			ret.Type = typeList(prop.IsNullable, "array")
+			ret.Items = &JsonSchema{}
+			setUnion(ret.Items, prop)
+		} else {
+			setUnion(&ret, prop)
+		}
+	case prop.IsArray:
+		ret.Type = typeList(prop.IsNullable, "array")
+		ret.Items = arrayItemsSchema(mongoHelper.ObservedType{
+			ValueType:       prop.ValueType,
+			BsonType:        prop.BsonType,
+			Format:          prop.Format,
+			IsComplex:       prop.IsComplex,
+			ArrayDimensions: prop.ArrayDimensions,
+		})
+	default:
+		ret.XBsonType = prop.BsonType
+		ret.Format = prop.Format
+		if prop.IsEnum {
+			ret.Enum = enumValues(prop)
+		}
+		if prop.IsComplex {
+			if prop.IsNullable {
+				ret.AnyOf = []*JsonSchema{{Ref: refPrefix() + prop.ValueType}, {Type: TypeList{mongoHelper.NULL}}}
+			} else {
+				ret.Ref = refPrefix() + prop.ValueType
+			}
+		} else {
+			ret.Type = typeList(prop.IsNullable, prop.ValueType)
+		}
+	}
+	return &ret
+}
+
+// sets the types of a polymorphic attribute, simple types are listed in the type keyword,
+// otherwise every observed type is an entry of 'anyOf'
+func setUnion(s *JsonSchema, prop *mongoHelper.BasicElemInfo) {
+	nullable := unionNullable(*prop)
+	s.XBsonTypes = bsonTypes(*prop)
+	if scalarTypes := scalarUnionTypes(*prop); scalarTypes != nil {
+		s.Type = typeList(nullable, scalarTypes...)
+		return
+	}
+	for _, t := range unionItemTypes(*prop) {
+		var entry *JsonSchema
+		if t.IsArray {
+			entry = &JsonSchema{Type: TypeList{"array"}, Items: arrayItemsSchema(t)}
+		} else {
+			entry = itemSchema(t)
+		}
+		entry.XObservedCount = t.Count
+		s.AnyOf = append(s.AnyOf, entry)
+	}
+	if nullable {
+		s.AnyOf = append(s.AnyOf, &JsonSchema{Type: TypeList{mongoHelper.NULL}})
+	}
+}
+
+// schema of the items of an array with the given dimensions, nested arrays are wrapped
+func arrayItemsSchema(t mongoHelper.ObservedType) *JsonSchema {
+	ret := itemSchema(t)
+	for range nestedArrayLevels(t.ArrayDimensions) {
+		ret = &JsonSchema{Type: TypeList{"array"}, Items: ret}
+	}
+	return ret
+}
+
+func itemSchema(t mongoHelper.ObservedType) *JsonSchema {
+	ret := JsonSchema{
+		XBsonType: t.BsonType,
+		Format:    t.Format,
+	}
+	if t.IsComplex {
+		ret.Ref = refPrefix() + t.ValueType
+	} else {
+		ret.Type = TypeList{t.ValueType}
+	}
+	return &ret
+}
+
+func enumValues(prop *mongoHelper.BasicElemInfo) []any {
+	ret := make([]any, 0, len(prop.DistinctValues)+1)
+	for _, v := range prop.DistinctValues {
+		if prop.ValueType == mongoHelper.INT {
+			ret = append(ret, json.Number(v))
+		} else {
+			ret = append(ret, v)
+		}
+	}
+	if prop.IsNullable {
+		ret = append(ret, nil)
+	}
+	return ret
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func setValueStats(s *JsonSchema, stats *mongoHelper.ValueStats) {
+	if stats == nil {
+		return
+	}
+	var keywords ValueKeywords
+	var observed ObservedValues
+	// NaN and infinite values can't be serialized as JSON numbers
+	if stats.MinNumber.IsSet && isFinite(stats.MinNumber.Value) {
+		keywords.Minimum = &stats.MinNumber.Value
+	}
+	if stats.MaxNumber.IsSet && isFinite(stats.MaxNumber.Value) {
+		keywords.Maximum = &stats.MaxNumber.Value
+	}
+	if stats.MinDate.IsSet {
+		observed.FormatMinimum = stats.MinDate.Value.Format(time.RFC3339Nano)
+	}
+	if stats.MaxDate.IsSet {
+		observed.FormatMaximum = stats.MaxDate.Value.Format(time.RFC3339Nano)
+	}
+	if stats.MinLength.IsSet {
+		keywords.MinLength = &stats.MinLength.Value
+	}
+	if stats.MaxLength.IsSet {
+		keywords.MaxLength = &stats.MaxLength.Value
+	}
+	if stats.MinItems.IsSet {
+		keywords.MinItems = &stats.MinItems.Value
+	}
+	if stats.MaxItems.IsSet {
+		keywords.MaxItems = &stats.MaxItems.Value
+	}
+	if observed != (ObservedValues{}) {
+		s.ObservedValues = &observed
+	}
+	if keywords == (ValueKeywords{}) {
+		return
+	}
+	if StatsAsExtensions {
+		observed.Minimum, observed.Maximum = keywords.Minimum, keywords.Maximum
+		observed.MinLength, observed.MaxLength = keywords.MinLength, keywords.MaxLength
+		observed.MinItems, observed.MaxItems = keywords.MinItems, keywords.MaxItems
+		s.ObservedValues = &observed
+	} else {
+		s.ValueKeywords = &keywords
+	}
+}
+
+// writes the data as indented JSON to a file in the output dir or to stdout
+func writeJsonOutput(data any, fileExt string, database string, collection string, outputDir string) error {
+	jsonData, err := marshalJson(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %v", fileExt, err)
+	}
+	var indented bytes.Buffer
+	if err = json.Indent(&indented, jsonData, "", "  "); err != nil {
+		return fmt.Errorf("failed to indent %s: %v", fileExt, err)
+	}
+	indented.WriteByte('\n')
+	return writeTextOutput(indented.String(), fileExt, database, collection, outputDir)
+}
+
+// writes the text to a file in the output dir or to stdout
+func writeTextOutput(text string, fileExt string, database string, collection string, outputDir string) error {
+	if outputDir == "stdout" {
+		fmt.Print(text)
+		return nil
+	}
+	outputFile, err := utils.CreateOutputFile(outputDir, fileExt, database, collection)
+	if err != nil {
+		return fmt.Errorf("failed to create %s file: %v", fileExt, err)
+	}
+	defer outputFile.Close()
+	if _, err = outputFile.WriteString(text); err != nil {
+		return fmt.Errorf("failed to write %s: %v", fileExt, err)
+	}
+	return nil
+}
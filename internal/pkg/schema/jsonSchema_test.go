@@ -0,0 +1,174 @@
+package schema
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func jsonSchemaTestDocs() []bson.D {
+	return []bson.D{
+		{{Key: "name", Value: "x"}, {Key: "a<b&c", Value: "d"}, {Key: "sub", Value: bson.D{{Key: "v", Value: int32(1)}}}, {Key: "mixed", Value: "s"}, {Key: "point", Value: bson.A{"a", int32(1)}}, {Key: "amount", Value: 2.5}},
+		{{Key: "name", Value: nil}, {Key: "sub", Value: nil}, {Key: "mixed", Value: int32(1)}, {Key: "point", Value: bson.A{"b", int32(2)}}, {Key: "amount", Value: int32(7)}},
+		{{Key: "name", Value: "yz"}, {Key: "sub", Value: bson.D{{Key: "v", Value: int32(2)}}}, {Key: "mixed", Value: nil}, {Key: "point", Value: bson.A{"c", int32(3)}}, {Key: "amount", Value: -1.5}},
+	}
+}
+
+// creates the JSON schema of the test documents and returns it as serialized JSON and as generic map
+func newTestJsonSchema(t *testing.T, draft string) (string, map[string]any) {
+	oldDraft := SchemaDraft
+	SchemaDraft = draft
+	defer func() { SchemaDraft = oldDraft }()
+	mainType, otherComplexTypes := guessTestSchema(t, "orders", "", jsonSchemaTestDocs())
+	mainType.Description = `Orders of "shop" & <co>`
+	jsonData, err := marshalJson(NewJsonSchema("shop", "orders", mainType, otherComplexTypes))
+	require.Nil(t, err)
+	var parsed map[string]any
+	require.Nil(t, json.Unmarshal(jsonData, &parsed))
+	return string(jsonData), parsed
+}
+
+func testJsonSchemaProp(t *testing.T, schema map[string]any, name string) map[string]any {
+	props, ok := schema["properties"].(map[string]any)
+	require.True(t, ok)
+	prop, ok := props[name].(map[string]any)
+	require.True(t, ok, "missing property: %s", name)
+	return prop
+}
+
+func TestNewJsonSchemaEscaping(t *testing.T) {
+	jsonStr, parsed := newTestJsonSchema(t, DRAFT_07)
+	// HTML characters are kept as they are, quotes are escaped
+	assert.Contains(t, jsonStr, `"a<b&c":{`)
+	assert.Contains(t, jsonStr, `"description":"Orders of \"shop\" & <co>"`)
+	assert.NotContains(t, jsonStr, `\u003c`)
+	assert.NotContains(t, jsonStr, `\u0026`)
+	assert.Equal(t, `Orders of "shop" & <co>`, parsed["description"])
+	assert.Equal(t, 0.3333, testJsonSchemaProp(t, parsed, "a<b&c")["x-presence-ratio"])
+}
+
+func TestNewJsonSchemaNullable(t *testing.T) {
+	_, parsed := newTestJsonSchema(t, DRAFT_07)
+	assert.Equal(t, []any{"name", "sub", "mixed", "point", "amount"}, parsed["required"])
+
+	assert.Equal(t, []any{"string", "null"}, testJsonSchemaProp(t, parsed, "name")["type"])
+
+	// nullable references can't have a type, so null is an alternative of 'anyOf'
+	sub := testJsonSchemaProp(t, parsed, "sub")
+	assert.Nil(t, sub["type"])
+	assert.Equal(t, []any{
+		map[string]any{"$ref": "#/definitions/Sub"},
+		map[string]any{"type": "null"},
+	}, sub["anyOf"])
+
+	mixed := testJsonSchemaProp(t, parsed, "mixed")
+	assert.Equal(t, []any{"string", "integer", "null"}, mixed["type"])
+	assert.Equal(t, []any{"string", "int"}, mixed["x-bson-types"])
+
+	definitions, ok := parsed["definitions"].(map[string]any)
+	require.True(t, ok)
+	assert.Contains(t, definitions, "Sub")
+	assert.Nil(t, parsed["$defs"])
+}
+
+func TestNewJsonSchemaTuples(t *testing.T) {
+	_, parsed := newTestJsonSchema(t, DRAFT_2020_12)
+	assert.Equal(t, "urn:mongodb:shop:orders", parsed["$id"])
+	assert.Equal(t, false, parsed["unevaluatedProperties"])
+	point := testJsonSchemaProp(t, parsed, "point")
+	assert.Equal(t, "array", point["type"])
+	assert.Nil(t, point["items"])
+	assert.Equal(t, []any{
+		map[string]any{"x-observed-count": 3.0, "x-bson-type": "string", "type": "string"},
+		map[string]any{"x-observed-count": 3.0, "x-bson-type": "int", "format": "int32", "type": "integer"},
+	}, point["prefixItems"])
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/Sub"}, testJsonSchemaProp(t, parsed, "sub")["anyOf"].([]any)[0])
+
+	// draft-07 knows no 'prefixItems', the tuple is a normal array of a union type
+	_, parsed = newTestJsonSchema(t, DRAFT_07)
+	point = testJsonSchemaProp(t, parsed, "point")
+	assert.Nil(t, point["prefixItems"])
+	items, ok := point["items"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, []any{"string", "integer"}, items["type"])
+}
+
+func TestNewJsonSchemaStats(t *testing.T) {
+	_, parsed := newTestJsonSchema(t, DRAFT_07)
+	name := testJsonSchemaProp(t, parsed, "name")
+	assert.Equal(t, 1.0, name["minLength"])
+	assert.Equal(t, 2.0, name["maxLength"])
+	amount := testJsonSchemaProp(t, parsed, "amount")
+	assert.Equal(t, -1.5, amount["minimum"])
+	assert.Equal(t, 7.0, amount["maximum"])
+	point := testJsonSchemaProp(t, parsed, "point")
+	assert.Equal(t, 2.0, point["minItems"])
+	assert.Equal(t, 2.0, point["maxItems"])
+
+	StatsAsExtensions = true
+	defer func() { StatsAsExtensions = false }()
+	_, parsed = newTestJsonSchema(t, DRAFT_07)
+	amount = testJsonSchemaProp(t, parsed, "amount")
+	assert.Nil(t, amount["minimum"])
+	assert.Nil(t, amount["maximum"])
+	assert.Equal(t, -1.5, amount["x-observed-minimum"])
+	assert.Equal(t, 7.0, amount["x-observed-maximum"])
+	assert.Equal(t, 2.0, testJsonSchemaProp(t, parsed, "name")["x-observed-maxLength"])
+}
+
+func TestSetValueStatsNonFiniteNumbers(t *testing.T) {
+	var stats mongoHelper.ValueStats
+	stats.MinNumber.Set(math.Inf(-1))
+	stats.MaxNumber.Set(math.NaN())
+	var s JsonSchema
+	setValueStats(&s, &stats)
+	assert.Nil(t, s.ValueKeywords)
+
+	stats.MaxNumber.Set(3)
+	setValueStats(&s, &stats)
+	require.NotNil(t, s.ValueKeywords)
+	assert.Nil(t, s.ValueKeywords.Minimum)
+	assert.Equal(t, 3.0, *s.ValueKeywords.Maximum)
+	_, err := marshalJson(&s)
+	assert.Nil(t, err)
+}
+
+func TestSetValueStatsDates(t *testing.T) {
+	var stats mongoHelper.ValueStats
+	stats.MinDate.Set(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	stats.MaxDate.Set(time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC))
+	stats.MaxLength.Set(3)
+	var s JsonSchema
+	setValueStats(&s, &stats)
+	// the date range is no validation keyword, so it is always an extension
+	parsed := make(map[string]any)
+	data, err := marshalJson(&s)
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(data, &parsed))
+	assert.Equal(t, map[string]any{
+		"maxLength":                3.0,
+		"x-observed-formatMinimum": "2024-01-02T03:04:05Z",
+		"x-observed-formatMaximum": "2024-02-03T04:05:06Z",
+	}, parsed)
+
+	StatsAsExtensions = true
+	defer func() { StatsAsExtensions = false }()
+	s = JsonSchema{}
+	setValueStats(&s, &stats)
+	assert.Nil(t, s.ValueKeywords)
+	require.NotNil(t, s.ObservedValues)
+	assert.Equal(t, "2024-01-02T03:04:05Z", s.ObservedValues.FormatMinimum)
+	assert.Equal(t, int64(3), *s.ObservedValues.MaxLength)
+}
+
+func TestWriteJsonOutputError(t *testing.T) {
+	err := writeJsonOutput(math.NaN(), "schema.json", "shop", "orders", t.TempDir())
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,64 @@
+package schema
+
+var mermaidTemplateStr = `---
+title: "Storage model for database: {{ .Database }}, collection: {{ .Collection }}"
+---
+classDiagram
+  class {{ MermaidName .MainType.Name }} {
+  {{- range $index, $prop := .MainType.Properties }}
+  {{- if IsPolymorphic $prop }}
+    {{ MermaidText $prop.AttribName }}: {{ MermaidText (UnionTypeName $prop) }}{{ if $prop.IsNullable }}?{{ end }}
+  {{- else if $prop.IsEnum }}
+    {{ MermaidText $prop.AttribName }}: {{ EnumTypeName $.MainType.Name $prop }}{{ if $prop.IsNullable }}?{{ end }}
+  {{- else }}
+    {{ MermaidText $prop.AttribName }}: {{ $prop.ValueType }}{{ ArrayMarker $prop.ArrayDimensions }}{{ if $prop.IsNullable }}?{{ end }}
+  {{- end }}
+  {{- end }}
+  }
+{{- range .MainType.Comments }}
+  note for {{ MermaidName $.MainType.Name }} {{ JsonString . }}
+{{- end }}
+{{- range $index, $type := .OtherComplexTypes }}
+  {{- if $type.IsDictionary }}
+  class {{ MermaidName $type.Name }} {
+    <<Map>>
+    {{- if ne $type.DictKeyFormat "" }}
+    keyFormat: {{ $type.DictKeyFormat }}
+    {{- end }}
+    {{- if $type.DictValue }}
+    valueType: {{ MermaidText (UnionTypeName $type.DictValue) }}{{ if $type.DictValue.IsNullable }}?{{ end }}
+    {{- end }}
+  }
+    {{- if and $type.DictValue $type.DictValue.IsComplex }}
+  {{ MermaidName $type.Name }} .. {{ MermaidName $type.DictValueType }}
+    {{- end }}
+  {{- else }}
+  class {{ MermaidName $type.Name }} {
+  {{- range $index, $prop := OwnProperties $type $.MainType }}
+  {{- if IsPolymorphic $prop }}
+    {{ MermaidText $prop.AttribName }}: {{ MermaidText (UnionTypeName $prop) }}{{ if $prop.IsNullable }}?{{ end }}
+  {{- else if $prop.IsEnum }}
+    {{ MermaidText $prop.AttribName }}: {{ EnumTypeName $type.Name $prop }}{{ if $prop.IsNullable }}?{{ end }}
+  {{- else }}
+    {{ MermaidText $prop.AttribName }}: {{ $prop.ValueType }}{{ ArrayMarker $prop.ArrayDimensions }}{{ if $prop.IsNullable }}?{{ end }}
+  {{- end }}
+  {{- end }}
+  }
+    {{- if ne $type.BaseType "" }}
+  {{ MermaidName $type.BaseType }} <|-- {{ MermaidName $type.Name }}
+    {{- end }}
+  {{- end }}
+{{- end }}
+{{- range $index, $enum := .Enums }}
+  class {{ MermaidName $enum.Name }} {
+    <<enumeration>>
+  {{- range $enum.Values }}
+    {{ MermaidText . }}
+  {{- end }}
+  }
+  {{ MermaidName $enum.Owner }} --> {{ MermaidName $enum.Name }}
+{{- end }}
+{{- range $index, $type := .Relations }}
+  {{ MermaidName $type.Start }} *-- {{ MermaidName $type.End }}
+{{- end }}
+`
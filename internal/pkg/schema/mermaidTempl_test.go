@@ -0,0 +1,18 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMermaid(t *testing.T) {
+	outputDir := t.TempDir()
+	mainType, otherComplexTypes := goldenTestSchema(t)
+	require.Nil(t, WriteMermaid("shop", "orders", mainType, otherComplexTypes, outputDir))
+	assertGolden(t, "orders.schema.mmd", readTestOutput(t, outputDir, "shop_orders.schema.mmd"))
+
+	schemas, _ := linkedTestSchemas(t)
+	require.Nil(t, WriteMermaid("shop", "customers", schemas[0].MainType, schemas[0].OtherComplexTypes, outputDir))
+	assertGolden(t, "customers.schema.mmd", readTestOutput(t, outputDir, "shop_customers.schema.mmd"))
+}
@@ -0,0 +1,434 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+
+	"gopkg.in/yaml.v3"
+)
+
+// type names are derived from the attribute names, name clashes get a numeric suffix. The suffixes
+// depend on the order of the processed documents.
+const NAMING_SHORT = "short"
+
+// type names are derived from the shortest unique end of the attribute path of the type
+const NAMING_PATH = "path"
+
+var NamingModes = []string{NAMING_SHORT, NAMING_PATH}
+
+// strategy that is used to name the complex types, the default doesn't depend on the order of the documents
+var TypeNaming = NAMING_PATH
+
+// Naming override for an attribute path like 'orders.items.price'. The first element of the
+// path is the collection name. If the name is set and the attribute references a complex type,
+// then this type is renamed. For the path that consists only of the collection name, the
+// settings are applied to the main type
+type NamingOverride struct {
+	Name        string `json:"name,omitempty" yaml:"name,omitempty"`
+	Title       string `json:"title,omitempty" yaml:"title,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// naming overrides per attribute path
+type NamingOverrides map[string]NamingOverride
+
+func CheckTypeNaming(naming string) error {
+	if !slices.Contains(NamingModes, naming) {
+		return fmt.Errorf("unknown type naming '%s', possible values are: %v", naming, NamingModes)
+	}
+	return nil
+}
+
+// Loads the naming overrides from a YAML (.yaml, .yml) or JSON file
+func LoadNamingOverrides(fileName string) (NamingOverrides, error) {
+	bytes, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("can't read naming file: %v", err)
+	}
+	var ret NamingOverrides
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(bytes, &ret)
+	default:
+		err = json.Unmarshal(bytes, &ret)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't parse naming file (%s): %v", fileName, err)
+	}
+	return ret, nil
+}
+
+// Applies the configured naming strategy and the naming overrides to the types of a collection.
+// It needs to be called after all types are reduced.
+func ApplyNaming(collectionName string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, overrides NamingOverrides) {
+	if TypeNaming == NAMING_PATH {
+		renameTypes(pathTypeNames(mainType, otherComplexTypes), mainType, otherComplexTypes)
+	}
+	renameTypes(identifierTypeNames(mainType, otherComplexTypes), mainType, otherComplexTypes)
+	paths := make([]string, 0, len(overrides))
+	for p := range overrides {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+	for _, p := range paths {
+		applyNamingOverride(p, overrides[p], collectionName, mainType, otherComplexTypes)
+	}
+}
+
+func applyNamingOverride(path string, override NamingOverride, collectionName string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) {
+	if path == collectionName {
+		if override.Title != "" {
+			mainType.Title = override.Title
+		}
+		if override.Description != "" {
+			mainType.Description = override.Description
+		}
+		if (override.Name != "") && (override.Name != mainType.Name) {
+			renameTypes(map[string]string{mainType.Name: override.Name}, mainType, otherComplexTypes)
+		}
+		return
+	}
+	prop := resolvePath(path, collectionName, mainType, otherComplexTypes)
+	if prop == nil {
+		if strings.HasPrefix(path, collectionName+".") {
+			log.Printf("[%s] naming override: can't resolve path '%s'", collectionName, path)
+		}
+		return
+	}
+	if override.Title != "" {
+		prop.Title = override.Title
+	}
+	if override.Description != "" {
+		prop.Description = override.Description
+	}
+	if override.Name != "" {
+		typeName := referencedTypeName(prop)
+		if typeName == "" {
+			log.Printf("[%s] naming override: attribute '%s' references no complex type, name is ignored", collectionName, path)
+		} else if typeName != override.Name {
+			renameTypes(map[string]string{typeName: override.Name}, mainType, otherComplexTypes)
+		}
+	}
+}
+
+// returns the name of the complex type that the attribute references, for polymorphic
+// attributes the first observed complex type is taken
+func referencedTypeName(prop *mongoHelper.BasicElemInfo) string {
+	if prop.IsComplex {
+		return prop.ValueType
+	}
+	for _, t := range prop.Types {
+		if t.IsComplex {
+			return t.ValueType
+		}
+	}
+	return ""
+}
+
+func complexTypeIndex(name string, otherComplexTypes []mongoHelper.ComplexType) int {
+	return slices.IndexFunc(otherComplexTypes, func(t mongoHelper.ComplexType) bool {
+		return t.Name == name
+	})
+}
+
+func propIndex(attribName string, complexType *mongoHelper.ComplexType) int {
+	return slices.IndexFunc(complexType.Properties, func(p mongoHelper.BasicElemInfo) bool {
+		return p.AttribName == attribName
+	})
+}
+
+// Resolves an attribute path like 'orders.items.price' to the attribute. The attributes of variants
+// are found over the path of the main type. For dictionaries the path element after the dictionary
+// attribute is the key, any key selects the value type of the dictionary.
+func resolvePath(path string, collectionName string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) *mongoHelper.BasicElemInfo {
+	parts := strings.Split(path, ".")
+	if (len(parts) < 2) || (parts[0] != collectionName) {
+		return nil
+	}
+	candidates := []*mongoHelper.ComplexType{mainType}
+	for i := range otherComplexTypes {
+		if (otherComplexTypes[i].BaseType != "") && (otherComplexTypes[i].BaseType == mainType.Name) {
+			candidates = append(candidates, &otherComplexTypes[i])
+		}
+	}
+	for i := 1; i < len(parts); i++ {
+		var prop *mongoHelper.BasicElemInfo
+		for _, c := range candidates {
+			if index := propIndex(parts[i], c); index != -1 {
+				prop = &c.Properties[index]
+				break
+			}
+		}
+		if prop == nil {
+			return nil
+		}
+		if i == len(parts)-1 {
+			return prop
+		}
+		index := complexTypeIndex(referencedTypeName(prop), otherComplexTypes)
+		if index == -1 {
+			return nil
+		}
+		if otherComplexTypes[index].IsDictionary {
+			// skip the key
+			i++
+			if i == len(parts)-1 {
+				return nil
+			}
+			index = complexTypeIndex(otherComplexTypes[index].DictValueType, otherComplexTypes)
+			if index == -1 {
+				return nil
+			}
+		}
+		candidates = []*mongoHelper.ComplexType{&otherComplexTypes[index]}
+	}
+	return nil
+}
+
+// Renames the types and all references to them in one pass, so that names can also be swapped
+func renameTypes(names map[string]string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) {
+	renameProp := func(p *mongoHelper.BasicElemInfo) {
+		if newName, ok := names[p.ValueType]; ok && p.IsComplex {
+			p.ValueType = newName
+		}
+		for j := range p.Types {
+			if newName, ok := names[p.Types[j].ValueType]; ok && p.Types[j].IsComplex {
+				p.Types[j].ValueType = newName
+			}
+		}
+		for j := range p.TupleItems {
+			if newName, ok := names[p.TupleItems[j].ValueType]; ok && p.TupleItems[j].IsComplex {
+				p.TupleItems[j].ValueType = newName
+			}
+		}
+	}
+	renameType := func(t *mongoHelper.ComplexType) {
+		if newName, ok := names[t.Name]; ok {
+			t.Name = newName
+		}
+		if newName, ok := names[t.BaseType]; ok {
+			t.BaseType = newName
+		}
+		for i := range t.Properties {
+			renameProp(&t.Properties[i])
+		}
+		if t.DictValue != nil {
+			renameProp(t.DictValue)
+			t.DictValueType = t.DictValue.ValueType
+		}
+	}
+	renameType(mainType)
+	for i := range otherComplexTypes {
+		renameType(&otherComplexTypes[i])
+	}
+	for oldName, newName := range names {
+		if (oldName != newName) && (typeNameCount(newName, mainType, otherComplexTypes) > 1) {
+			log.Printf("type name is used more than once after renaming '%s' to '%s'", oldName, newName)
+		}
+	}
+}
+
+func typeNameCount(name string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) int {
+	ret := 0
+	if mainType.Name == name {
+		ret++
+	}
+	for _, t := range otherComplexTypes {
+		if t.Name == name {
+			ret++
+		}
+	}
+	return ret
+}
+
+func typeNamePart(s string) string {
+	if s == "" {
+		return s
+	}
+	runes := []rune(s)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func isIdentifierChar(r rune) bool {
+	return ((r >= 'a') && (r <= 'z')) || ((r >= 'A') && (r <= 'Z')) || ((r >= '0') && (r <= '9')) || (r == '_')
+}
+
+// The type names are used as identifiers in the created code (go, typescript, protobuf, avro), so only
+// ASCII letters, digits and '_' are kept. The other characters separate words, e.g. 'x-tag' -> 'XTag'.
+func typeIdentifier(s string) string {
+	ret := ""
+	for _, w := range strings.FieldsFunc(s, func(r rune) bool { return !isIdentifierChar(r) }) {
+		ret += typeNamePart(w)
+	}
+	if ret == "" {
+		return "X"
+	}
+	if (ret[0] >= '0') && (ret[0] <= '9') {
+		return "X" + ret
+	}
+	return ret
+}
+
+// returns the new names of the types, whose names are no valid identifiers
+func identifierTypeNames(mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) map[string]string {
+	used := []string{mainType.Name}
+	for _, t := range otherComplexTypes {
+		used = append(used, t.Name)
+	}
+	invalid := slices.DeleteFunc(slices.Clone(used), func(n string) bool { return typeIdentifier(n) == n })
+	slices.Sort(invalid)
+	ret := make(map[string]string)
+	for _, n := range invalid {
+		name := typeIdentifier(n)
+		for i := 2; slices.Contains(used, name); i++ {
+			name = fmt.Sprintf("%s%d", typeIdentifier(n), i)
+		}
+		used = append(used, name)
+		ret[n] = name
+	}
+	return ret
+}
+
+// Collects the attribute path of every complex type, that is reachable from the main type. The
+// attributes are visited in a sorted order and level by level, so the first (shortest) path of
+// a type doesn't depend on the order of the processed documents. The paths of the types, that are
+// only reachable over a variant, start with the discriminator value of the variant.
+func typePaths(mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) map[string][]string {
+	type queueEntry struct {
+		complexType *mongoHelper.ComplexType
+		path        []string
+	}
+	ret := make(map[string][]string)
+	queue := []queueEntry{{mainType, []string{}}}
+	for i := range otherComplexTypes {
+		if (otherComplexTypes[i].BaseType != "") && (otherComplexTypes[i].BaseType == mainType.Name) {
+			variant := otherComplexTypes[i].DiscriminatorValue
+			if variant == "" {
+				variant = otherComplexTypes[i].Name
+			}
+			queue = append(queue, queueEntry{&otherComplexTypes[i], []string{variant}})
+		}
+	}
+	visit := func(typeName string, path []string) {
+		if _, ok := ret[typeName]; ok || (typeName == mainType.Name) {
+			return
+		}
+		index := complexTypeIndex(typeName, otherComplexTypes)
+		if (index == -1) || (otherComplexTypes[index].BaseType != "") {
+			return
+		}
+		ret[typeName] = path
+		queue = append(queue, queueEntry{&otherComplexTypes[index], path})
+	}
+	for len(queue) > 0 {
+		e := queue[0]
+		queue = queue[1:]
+		if e.complexType.IsDictionary {
+			// the value type is named after the dictionary, e.g. 'LabelsValue'
+			path := slices.Clone(e.path)
+			if len(path) == 0 {
+				path = append(path, "value")
+			} else {
+				path[len(path)-1] += "Value"
+			}
+			visit(e.complexType.DictValueType, path)
+			continue
+		}
+		props := slices.Clone(e.complexType.Properties)
+		slices.SortFunc(props, func(a, b mongoHelper.BasicElemInfo) int {
+			return strings.Compare(a.AttribName, b.AttribName)
+		})
+		for _, p := range props {
+			path := append(slices.Clone(e.path), p.AttribName)
+			if p.IsComplex {
+				visit(p.ValueType, path)
+			}
+			for _, t := range p.Types {
+				if t.IsComplex {
+					visit(t.ValueType, path)
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// Builds deterministic type names out of the attribute paths of the types. Every type gets the
+// shortest end of its path, that is unique over all types, e.g. 'Address' or 'BillingAddress'.
+func pathTypeNames(mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) map[string]string {
+	paths := typePaths(mainType, otherComplexTypes)
+	typeNames := make([]string, 0, len(paths))
+	for n := range paths {
+		typeNames = append(typeNames, n)
+	}
+	// different types can have the same path, e.g. for polymorphic attributes, then the type name decides
+	slices.SortFunc(typeNames, func(a, b string) int {
+		if c := strings.Compare(strings.Join(paths[a], "."), strings.Join(paths[b], ".")); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	// names that are not touched by the renaming
+	reserved := []string{mainType.Name}
+	for _, t := range otherComplexTypes {
+		if _, ok := paths[t.Name]; !ok {
+			reserved = append(reserved, t.Name)
+		}
+	}
+	suffixLen := make(map[string]int)
+	candidate := func(typeName string) string {
+		path := paths[typeName]
+		l := min(suffixLen[typeName], len(path))
+		name := ""
+		for _, p := range path[len(path)-l:] {
+			name += typeIdentifier(p)
+		}
+		return name
+	}
+	for _, n := range typeNames {
+		suffixLen[n] = 1
+	}
+	for {
+		changed := false
+		byName := make(map[string][]string)
+		for _, n := range typeNames {
+			c := candidate(n)
+			byName[c] = append(byName[c], n)
+		}
+		for c, names := range byName {
+			if (len(names) < 2) && !slices.Contains(reserved, c) {
+				continue
+			}
+			for _, n := range names {
+				if suffixLen[n] < len(paths[n]) {
+					suffixLen[n]++
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	ret := make(map[string]string)
+	used := slices.Clone(reserved)
+	for _, n := range typeNames {
+		name := candidate(n)
+		// only in case of equal full paths, e.g. 'aB.c' and 'a.bC'
+		for i := 2; slices.Contains(used, name); i++ {
+			name = fmt.Sprintf("%s%d", candidate(n), i)
+		}
+		used = append(used, name)
+		ret[n] = name
+	}
+	return ret
+}
@@ -0,0 +1,128 @@
+package schema
+
+import (
+	"testing"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// main type with two variants, that have sub-documents with the same attribute name but different types
+func variantTestTypes() (mongoHelper.ComplexType, []mongoHelper.ComplexType) {
+	mainType := mongoHelper.ComplexType{
+		Name:          "Vehicles",
+		Discriminator: "type",
+		Properties: []mongoHelper.BasicElemInfo{
+			{AttribName: "type", ValueType: "string", BsonType: "string"},
+			{AttribName: "address", ValueType: "Address", IsComplex: true},
+		},
+	}
+	otherComplexTypes := []mongoHelper.ComplexType{
+		{Name: "Address", Properties: []mongoHelper.BasicElemInfo{{AttribName: "street", ValueType: "string", BsonType: "string"}}},
+		{Name: "VehiclesCar", BaseType: "Vehicles", DiscriminatorValue: "car", Properties: []mongoHelper.BasicElemInfo{
+			{AttribName: "type", ValueType: "string", BsonType: "string"},
+			{AttribName: "details", ValueType: "Details", IsComplex: true},
+		}},
+		{Name: "VehiclesBike", BaseType: "Vehicles", DiscriminatorValue: "bike", Properties: []mongoHelper.BasicElemInfo{
+			{AttribName: "type", ValueType: "string", BsonType: "string"},
+			{AttribName: "details", ValueType: "Details2", IsComplex: true},
+			{AttribName: "owner", ValueType: "Owner", IsComplex: true},
+		}},
+		{Name: "Details", Properties: []mongoHelper.BasicElemInfo{{AttribName: "wheels", ValueType: "integer", BsonType: "int"}}},
+		{Name: "Details2", Properties: []mongoHelper.BasicElemInfo{{AttribName: "bell", ValueType: "boolean", BsonType: "bool"}}},
+		{Name: "Owner", Properties: []mongoHelper.BasicElemInfo{{AttribName: "name", ValueType: "string", BsonType: "string"}}},
+	}
+	return mainType, otherComplexTypes
+}
+
+func TestPathTypeNamesVariants(t *testing.T) {
+	mainType, otherComplexTypes := variantTestTypes()
+	expected := map[string]string{
+		"Address":  "Address",
+		"Details":  "CarDetails",
+		"Details2": "BikeDetails",
+		"Owner":    "Owner",
+	}
+	// the types are collected in maps, a repeated call has to give the same names every time
+	for i := 0; i < 200; i++ {
+		assert.Equal(t, expected, pathTypeNames(&mainType, otherComplexTypes))
+	}
+}
+
+func TestPathTypeNamesSamePath(t *testing.T) {
+	// polymorphic attribute with two complex types, both types have the same path
+	mainType := mongoHelper.ComplexType{
+		Name: "Orders",
+		Properties: []mongoHelper.BasicElemInfo{
+			{AttribName: "payment", ValueType: "Payment", IsComplex: true, Types: []mongoHelper.ObservedType{
+				{ValueType: "Payment", IsComplex: true},
+				{ValueType: "Payment2", IsComplex: true},
+			}},
+		},
+	}
+	otherComplexTypes := []mongoHelper.ComplexType{
+		{Name: "Payment2", Properties: []mongoHelper.BasicElemInfo{{AttribName: "iban", ValueType: "string", BsonType: "string"}}},
+		{Name: "Payment", Properties: []mongoHelper.BasicElemInfo{{AttribName: "card", ValueType: "string", BsonType: "string"}}},
+	}
+	expected := map[string]string{
+		"Payment":  "Payment",
+		"Payment2": "Payment2",
+	}
+	for i := 0; i < 200; i++ {
+		assert.Equal(t, expected, pathTypeNames(&mainType, otherComplexTypes))
+	}
+}
+
+func TestApplyNamingPath(t *testing.T) {
+	saved := TypeNaming
+	TypeNaming = NAMING_PATH
+	defer func() { TypeNaming = saved }()
+
+	mainType, otherComplexTypes := variantTestTypes()
+	ApplyNaming("vehicles", &mainType, otherComplexTypes, nil)
+	names := make([]string, 0)
+	for _, t := range otherComplexTypes {
+		names = append(names, t.Name)
+	}
+	assert.Equal(t, []string{"Address", "VehiclesCar", "VehiclesBike", "CarDetails", "BikeDetails", "Owner"}, names)
+	assert.Equal(t, "CarDetails", otherComplexTypes[1].Properties[1].ValueType)
+	assert.Equal(t, "BikeDetails", otherComplexTypes[2].Properties[1].ValueType)
+}
+
+func TestTypeIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"Address":      "Address",
+		"order_items":  "Order_items",
+		"X-tag":        "XTag",
+		"a.b.c":        "ABC",
+		"$meta":        "Meta",
+		"2fa":          "X2fa",
+		"$$":           "X",
+		"größe in cm":  "GrEInCm",
+		"items-2.sub$": "Items2Sub",
+	}
+	for name, expected := range tests {
+		assert.Equal(t, expected, typeIdentifier(name), name)
+	}
+}
+
+// type names out of attribute names with special characters are valid identifiers with every naming
+func TestApplyNamingIdentifiers(t *testing.T) {
+	saved := TypeNaming
+	defer func() { TypeNaming = saved }()
+	for _, naming := range NamingModes {
+		TypeNaming = naming
+		mainType, otherComplexTypes := guessTestSchema(t, "my-orders", "", []bson.D{
+			{{Key: "x-tag", Value: bson.D{{Key: "a", Value: int32(1)}}}, {Key: "$meta", Value: bson.D{{Key: "b", Value: "x"}}}, {Key: "xTag", Value: bson.D{{Key: "c", Value: true}}}},
+		})
+		names := []string{mainType.Name}
+		for _, ct := range otherComplexTypes {
+			names = append(names, ct.Name)
+		}
+		assert.Equal(t, "MyOrders", mainType.Name, naming)
+		assert.ElementsMatch(t, []string{"MyOrders", "XTag", "XTag2", "Meta"}, names, naming)
+		assert.Equal(t, "Meta", mainType.Properties[1].ValueType, naming)
+	}
+}
@@ -0,0 +1,290 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+)
+
+const openApiRefPrefix = "#/components/schemas/"
+
+type OpenApiDocument struct {
+	OpenApi    string            `json:"openapi"`
+	Info       OpenApiInfo       `json:"info"`
+	Components OpenApiComponents `json:"components"`
+}
+
+type OpenApiInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type OpenApiComponents struct {
+	Schemas OrderedMap[*JsonSchema] `json:"schemas"`
+}
+
+type openApiCollection struct {
+	name              string
+	mainType          *mongoHelper.ComplexType
+	otherComplexTypes []mongoHelper.ComplexType
+}
+
+// Collects the guessed types of the collections, to write one OpenAPI document per database. The
+// collections are processed in parallel, so the types are added under a lock.
+type OpenApiCollector struct {
+	mutex       sync.Mutex
+	collections map[string][]openApiCollection
+}
+
+func NewOpenApiCollector() *OpenApiCollector {
+	return &OpenApiCollector{collections: make(map[string][]openApiCollection)}
+}
+
+func (c *OpenApiCollector) Add(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.collections[database] = append(c.collections[database], openApiCollection{
+		name:              collection,
+		mainType:          mainType,
+		otherComplexTypes: otherComplexTypes,
+	})
+}
+
+// writes one OpenAPI document for every database with collected types, the file is named '<db>_all.openapi.json'
+func (c *OpenApiCollector) Print(outputDir string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	databases := make([]string, 0, len(c.collections))
+	for db := range c.collections {
+		databases = append(databases, db)
+	}
+	slices.Sort(databases)
+	errs := make([]error, 0)
+	for _, db := range databases {
+		if err := writeJsonOutput(newOpenApiDocument(db, c.collections[db]), "openapi.json", db, "all", outputDir); err != nil {
+			errs = append(errs, fmt.Errorf("[%s] %v", db, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Creates an OpenAPI 3.1 document that contains the types of all given collections as components.
+// Types with the same structure are only contained once, also if they are used in different collections.
+func newOpenApiDocument(database string, collections []openApiCollection) *OpenApiDocument {
+	collections = slices.Clone(collections)
+	slices.SortFunc(collections, func(a, b openApiCollection) int {
+		return strings.Compare(a.name, b.name)
+	})
+	builder := componentsBuilder{
+		schemas:      make(OrderedMap[*JsonSchema], 0),
+		names:        make([]string, 0),
+		structure:    make(map[string]string),
+		observations: make(map[string]string),
+	}
+	for _, c := range collections {
+		builder.addCollection(database, c)
+	}
+	return &OpenApiDocument{
+		OpenApi: "3.1.0",
+		Info: OpenApiInfo{
+			Title:       fmt.Sprintf("Storage model for database: %s", database),
+			Description: fmt.Sprintf("Types of %d collections, guessed from the stored documents", len(collections)),
+			Version:     "0.0.0",
+		},
+		Components: OpenApiComponents{Schemas: builder.schemas},
+	}
+}
+
+type componentsBuilder struct {
+	schemas OrderedMap[*JsonSchema]
+	// already used component names
+	names []string
+	// component names by the JSON of the structure of the schemas, to find types with the same structure
+	structure map[string]string
+	// JSON of the components including the observations by component name
+	observations map[string]string
+}
+
+// Types of different collections are merged into the same component, if they have the same structure. The
+// enums and value keywords of one collection would reject the observed values of the other collection, so
+// the observations are removed from the component, if they differ.
+func (b *componentsBuilder) mergeInto(componentName string, observations string) {
+	if b.observations[componentName] == observations {
+		return
+	}
+	for i := range b.schemas {
+		if b.schemas[i].Key == componentName {
+			b.schemas[i].Value = structureOnly(b.schemas[i].Value)
+		}
+	}
+}
+
+// returns the type name, if it's not used yet, otherwise the type name prefixed with the collection
+// name. If that is also used, a number is appended.
+func (b *componentsBuilder) uniqueName(typeName string, collection string) string {
+	ret := typeName
+	if slices.Contains(b.names, ret) {
+		ret = goIdentifier(collection) + typeName
+	}
+	for i := 2; slices.Contains(b.names, ret); i++ {
+		ret = goIdentifier(collection) + typeName + strconv.Itoa(i)
+	}
+	b.names = append(b.names, ret)
+	return ret
+}
+
+func referencedTypeNames(s *JsonSchema) []string {
+	ret := make([]string, 0)
+	walkSchema(s, func(c *JsonSchema) {
+		if name, found := strings.CutPrefix(c.Ref, refPrefix()); found && !slices.Contains(ret, name) {
+			ret = append(ret, name)
+		}
+	})
+	return ret
+}
+
+func rewriteRefs(s *JsonSchema, componentNames map[string]string) {
+	rewrite := func(ref string) string {
+		name, found := strings.CutPrefix(ref, refPrefix())
+		if !found {
+			return ref
+		}
+		if componentName, ok := componentNames[name]; ok {
+			name = componentName
+		}
+		return openApiRefPrefix + name
+	}
+	walkSchema(s, func(c *JsonSchema) {
+		if c.Ref != "" {
+			c.Ref = rewrite(c.Ref)
+		}
+		if c.Discriminator != nil {
+			for i := range c.Discriminator.Mapping {
+				c.Discriminator.Mapping[i].Value = rewrite(c.Discriminator.Mapping[i].Value)
+			}
+		}
+	})
+}
+
+// returns a copy of the schema without the observations of the analysed documents, like presence
+// ratios, value statistics and enum values. Types of different collections that only differ in these
+// observations have the same structure.
+func structureOnly(s *JsonSchema) *JsonSchema {
+	if s == nil {
+		return nil
+	}
+	ret := *s
+	ret.XPresenceRatio = ""
+	ret.XObservedCount = 0
+	ret.ValueKeywords = nil
+	ret.ObservedValues = nil
+	ret.Enum = nil
+	ret.Items = structureOnly(s.Items)
+	ret.AdditionalProperties = structureOnly(s.AdditionalProperties)
+	ret.AnyOf = structureOnlyList(s.AnyOf)
+	ret.PrefixItems = structureOnlyList(s.PrefixItems)
+	ret.OneOf = structureOnlyList(s.OneOf)
+	ret.Properties = structureOnlyMap(s.Properties)
+	ret.Definitions = structureOnlyMap(s.Definitions)
+	ret.Defs = structureOnlyMap(s.Defs)
+	return &ret
+}
+
+func structureOnlyList(l []*JsonSchema) []*JsonSchema {
+	if l == nil {
+		return nil
+	}
+	ret := make([]*JsonSchema, 0, len(l))
+	for _, s := range l {
+		ret = append(ret, structureOnly(s))
+	}
+	return ret
+}
+
+func structureOnlyMap(m *OrderedMap[*JsonSchema]) *OrderedMap[*JsonSchema] {
+	if m == nil {
+		return nil
+	}
+	ret := make(OrderedMap[*JsonSchema], 0, len(*m))
+	for _, e := range *m {
+		ret.Add(e.Key, structureOnly(e.Value))
+	}
+	return &ret
+}
+
+// Adds the types of one collection. The referenced types are added first, so the references of a
+// type are already resolved to the component names, before its structure is compared to the
+// existing components. Types that are part of a cycle are never merged.
+func (b *componentsBuilder) addCollection(database string, c openApiCollection) {
+	typeSchemas := make(map[string]*JsonSchema)
+	typeNames := []string{c.mainType.Name}
+	mainSchema := mainTypeSchema(database, c.name, c.mainType, c.otherComplexTypes)
+	mainSchema.XCollection = c.name
+	typeSchemas[c.mainType.Name] = mainSchema
+	for i := range c.otherComplexTypes {
+		t := &c.otherComplexTypes[i]
+		typeSchemas[t.Name] = complexTypeSchema(t)
+		typeNames = append(typeNames, t.Name)
+	}
+
+	componentNames := make(map[string]string)
+	inProgress := make(map[string]bool)
+	done := make(map[string]bool)
+	var add func(typeName string)
+	add = func(typeName string) {
+		if done[typeName] {
+			return
+		}
+		if inProgress[typeName] {
+			if _, ok := componentNames[typeName]; !ok {
+				componentNames[typeName] = b.uniqueName(typeName, c.name)
+			}
+			return
+		}
+		s, ok := typeSchemas[typeName]
+		if !ok {
+			log.Printf("[%s:%s] openapi: can't find complex type '%s'", database, c.name, typeName)
+			return
+		}
+		inProgress[typeName] = true
+		for _, ref := range referencedTypeNames(s) {
+			add(ref)
+		}
+		delete(inProgress, typeName)
+		done[typeName] = true
+
+		rewriteRefs(s, componentNames)
+		key, err := marshalJson(structureOnly(s))
+		if err != nil {
+			log.Printf("[%s:%s] openapi: failed to marshal type '%s': %v", database, c.name, typeName, err)
+			return
+		}
+		observations, err := marshalJson(s)
+		if err != nil {
+			log.Printf("[%s:%s] openapi: failed to marshal type '%s': %v", database, c.name, typeName, err)
+			return
+		}
+		_, partOfCycle := componentNames[typeName]
+		if existing, ok := b.structure[string(key)]; ok && !partOfCycle {
+			componentNames[typeName] = existing
+			b.mergeInto(existing, string(observations))
+			return
+		}
+		if !partOfCycle {
+			componentNames[typeName] = b.uniqueName(typeName, c.name)
+		}
+		b.structure[string(key)] = componentNames[typeName]
+		b.observations[componentNames[typeName]] = string(observations)
+		b.schemas.Add(componentNames[typeName], s)
+	}
+	for _, n := range typeNames {
+		add(n)
+	}
+}
@@ -0,0 +1,120 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func openApiTestCollection(t *testing.T, name string, docs []bson.D) openApiCollection {
+	mainType, otherComplexTypes := guessTestSchema(t, name, "", docs)
+	return openApiCollection{name: name, mainType: mainType, otherComplexTypes: otherComplexTypes}
+}
+
+func componentNames(doc *OpenApiDocument) []string {
+	ret := make([]string, 0)
+	for _, e := range doc.Components.Schemas {
+		ret = append(ret, e.Key)
+	}
+	return ret
+}
+
+func TestOpenApiSharedTypes(t *testing.T) {
+	// the addresses have the same structure and optional attributes, but different presence ratios, statistics and enum values
+	customers := openApiTestCollection(t, "customers", []bson.D{
+		{{Key: "name", Value: "Ann"}, {Key: "address", Value: bson.D{{Key: "street", Value: "Main Street 1"}, {Key: "country", Value: "DE"}, {Key: "zip", Value: int32(12345)}}}},
+		{{Key: "name", Value: "Bob"}, {Key: "address", Value: bson.D{{Key: "street", Value: "Side Road 22"}, {Key: "country", Value: "DE"}}}},
+	})
+	suppliers := openApiTestCollection(t, "suppliers", []bson.D{
+		{{Key: "company", Value: "ACME"}, {Key: "address", Value: bson.D{{Key: "street", Value: "Long Way 3"}, {Key: "country", Value: "FR"}, {Key: "zip", Value: int32(99)}}}},
+		{{Key: "company", Value: "Corp"}, {Key: "address", Value: bson.D{{Key: "street", Value: "Short Way 4"}, {Key: "country", Value: "FR"}, {Key: "zip", Value: int32(7)}}}},
+		{{Key: "company", Value: "Inc"}, {Key: "address", Value: bson.D{{Key: "street", Value: "X"}, {Key: "country", Value: "FR"}}}},
+	})
+	// same type name, but different structure
+	plants := openApiTestCollection(t, "plants", []bson.D{
+		{{Key: "address", Value: bson.D{{Key: "street", Value: "Main Street 1"}, {Key: "city", Value: "Berlin"}}}},
+	})
+	customers.otherComplexTypes[0].Properties[1].IsEnum = true
+	customers.otherComplexTypes[0].Properties[1].DistinctValues = []string{"DE"}
+	suppliers.otherComplexTypes[0].Properties[1].IsEnum = true
+	suppliers.otherComplexTypes[0].Properties[1].DistinctValues = []string{"FR"}
+
+	doc := newOpenApiDocument("shop", []openApiCollection{suppliers, plants, customers})
+	assert.Equal(t, []string{"Address", "Customers", "PlantsAddress", "Plants", "Suppliers"}, componentNames(doc))
+	for _, e := range doc.Components.Schemas {
+		switch e.Key {
+		case "Customers", "Suppliers":
+			require.NotNil(t, e.Value.Properties)
+			address := (*e.Value.Properties)[len(*e.Value.Properties)-1]
+			assert.Equal(t, "address", address.Key)
+			assert.Equal(t, openApiRefPrefix+"Address", address.Value.Ref)
+		case "Plants":
+			assert.Equal(t, openApiRefPrefix+"PlantsAddress", (*e.Value.Properties)[0].Value.Ref)
+		}
+	}
+}
+
+func componentSchema(t *testing.T, doc *OpenApiDocument, name string) *JsonSchema {
+	for _, e := range doc.Components.Schemas {
+		if e.Key == name {
+			return e.Value
+		}
+	}
+	require.Fail(t, "component not found", name)
+	return nil
+}
+
+func schemaProperty(t *testing.T, s *JsonSchema, name string) *JsonSchema {
+	require.NotNil(t, s.Properties)
+	for _, e := range *s.Properties {
+		if e.Key == name {
+			return e.Value
+		}
+	}
+	require.Fail(t, "property not found", name)
+	return nil
+}
+
+func TestOpenApiMergedObservations(t *testing.T) {
+	oldEnumMinSamples := EnumMinSamples
+	EnumMinSamples = 2
+	defer func() { EnumMinSamples = oldEnumMinSamples }()
+	// the shipments have the same structure, but different status values and weights
+	orders := openApiTestCollection(t, "orders", []bson.D{
+		{{Key: "shipment", Value: bson.D{{Key: "status", Value: "open"}, {Key: "weight", Value: 1.5}}}},
+		{{Key: "shipment", Value: bson.D{{Key: "status", Value: "closed"}, {Key: "weight", Value: 2.5}}}},
+		{{Key: "shipment", Value: bson.D{{Key: "status", Value: "closed"}, {Key: "weight", Value: 2.5}}}},
+	})
+	returns := openApiTestCollection(t, "returns", []bson.D{
+		{{Key: "shipment", Value: bson.D{{Key: "status", Value: "lost"}, {Key: "weight", Value: 10.5}}}},
+		{{Key: "shipment", Value: bson.D{{Key: "status", Value: "delivered"}, {Key: "weight", Value: 20.5}}}},
+		{{Key: "shipment", Value: bson.D{{Key: "status", Value: "delivered"}, {Key: "weight", Value: 20.5}}}},
+	})
+	// same observations as the orders
+	archive := openApiTestCollection(t, "archive", []bson.D{
+		{{Key: "shipment", Value: bson.D{{Key: "status", Value: "open"}, {Key: "weight", Value: 1.5}}}},
+		{{Key: "shipment", Value: bson.D{{Key: "status", Value: "closed"}, {Key: "weight", Value: 2.5}}}},
+		{{Key: "shipment", Value: bson.D{{Key: "status", Value: "closed"}, {Key: "weight", Value: 2.5}}}},
+	})
+
+	doc := newOpenApiDocument("shop", []openApiCollection{orders, archive})
+	assert.Equal(t, []string{"Shipment", "Archive", "Orders"}, componentNames(doc))
+	status := schemaProperty(t, componentSchema(t, doc, "Shipment"), "status")
+	assert.Equal(t, []any{"closed", "open"}, status.Enum)
+	require.NotNil(t, schemaProperty(t, componentSchema(t, doc, "Shipment"), "weight").ValueKeywords)
+
+	doc = newOpenApiDocument("shop", []openApiCollection{orders, returns})
+	assert.Equal(t, []string{"Shipment", "Orders", "Returns"}, componentNames(doc))
+	shipment := componentSchema(t, doc, "Shipment")
+	status = schemaProperty(t, shipment, "status")
+	assert.Nil(t, status.Enum)
+	assert.Equal(t, TypeList{"string"}, status.Type)
+	weight := schemaProperty(t, shipment, "weight")
+	assert.Nil(t, weight.ValueKeywords)
+	assert.Equal(t, []string{"status", "weight"}, shipment.Required)
+	for _, name := range []string{"Orders", "Returns"} {
+		assert.Equal(t, openApiRefPrefix+"Shipment", schemaProperty(t, componentSchema(t, doc, name), "shipment").Ref)
+	}
+}
@@ -0,0 +1,98 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+)
+
+const schemaRawFileExt = "schema-raw.json"
+
+// Schema of one collection, like it was persisted with 'print_raw_schema_base'
+type PersistedSchema struct {
+	Database          string
+	Collection        string
+	MainType          *mongoHelper.ComplexType
+	OtherComplexTypes []mongoHelper.ComplexType
+}
+
+// Loads all '*.schema-raw.json' files of the directory, sorted by database and collection. Files of
+// older versions don't contain the database and collection, for them the names are taken from the
+// file name '<db>_<collection>.schema-raw.json', if it contains only one '_'.
+func LoadPersistedSchemas(schemaDir string) ([]PersistedSchema, error) {
+	files, err := filepath.Glob(filepath.Join(schemaDir, "*."+schemaRawFileExt))
+	if err != nil {
+		return nil, fmt.Errorf("error while searching schema files in %s: %v", schemaDir, err)
+	}
+	ret := make([]PersistedSchema, 0)
+	for _, f := range files {
+		s, err := loadPersistedSchema(f)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, s)
+	}
+	slices.SortFunc(ret, func(a, b PersistedSchema) int {
+		if c := strings.Compare(a.Database, b.Database); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Collection, b.Collection)
+	})
+	return ret, nil
+}
+
+func loadPersistedSchema(file string) (PersistedSchema, error) {
+	var ret PersistedSchema
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return ret, fmt.Errorf("error while reading schema file (%s): %v", file, err)
+	}
+	var schemaRaw mongoHelper.SchemaRaw
+	if err := json.Unmarshal(bytes, &schemaRaw); err != nil {
+		return ret, fmt.Errorf("error while parsing schema file (%s): %v", file, err)
+	}
+	if schemaRaw.MainType == nil {
+		return ret, fmt.Errorf("schema file (%s) contains no main type", file)
+	}
+	ret.Database = schemaRaw.Database
+	ret.Collection = schemaRaw.Collection
+	if (ret.Database == "") || (ret.Collection == "") {
+		// the file name is only unambiguous, if neither the database nor the collection contain a '_'
+		name := strings.TrimSuffix(filepath.Base(file), "."+schemaRawFileExt)
+		parts := strings.Split(name, "_")
+		if (len(parts) != 2) || (parts[0] == "") || (parts[1] == "") {
+			return ret, fmt.Errorf("schema file (%s) contains no database and collection and they can't be taken unambiguously from the file name, please persist the schema again", file)
+		}
+		ret.Database = parts[0]
+		ret.Collection = parts[1]
+	}
+	ret.MainType = schemaRaw.MainType
+	if schemaRaw.OtherComplexTypes != nil {
+		ret.OtherComplexTypes = *schemaRaw.OtherComplexTypes
+	} else {
+		ret.OtherComplexTypes = make([]mongoHelper.ComplexType, 0)
+	}
+	return ret, nil
+}
+
+// returns the attribute with the given name from the main type or one of its variants
+func (s *PersistedSchema) findAttribute(attribName string) *mongoHelper.BasicElemInfo {
+	for i := range s.MainType.Properties {
+		if s.MainType.Properties[i].AttribName == attribName {
+			return &s.MainType.Properties[i]
+		}
+	}
+	for _, v := range variants(s.OtherComplexTypes) {
+		for i := range v.Properties {
+			if v.Properties[i].AttribName == attribName {
+				return &v.Properties[i]
+			}
+		}
+	}
+	return nil
+}
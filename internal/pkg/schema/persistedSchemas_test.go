@@ -0,0 +1,46 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPersistedSchemas(t *testing.T) {
+	dir := t.TempDir()
+	writeSchema := func(fileName string, content string) {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, fileName), []byte(content), 0644))
+	}
+	// the embedded names are used, also if they contain a '_'
+	writeSchema("my_db_my_coll.schema-raw.json", `{"database": "my_db", "collection": "my_coll", "mainType": {"name": "MyColl"}}`)
+	// older files without names, the file name is unambiguous
+	writeSchema("shop_orders.schema-raw.json", `{"mainType": {"name": "Orders"}}`)
+
+	schemas, err := LoadPersistedSchemas(dir)
+	require.Nil(t, err)
+	require.Len(t, schemas, 2)
+	assert.Equal(t, "my_db", schemas[0].Database)
+	assert.Equal(t, "my_coll", schemas[0].Collection)
+	assert.Equal(t, "shop", schemas[1].Database)
+	assert.Equal(t, "orders", schemas[1].Collection)
+	assert.NotNil(t, schemas[1].OtherComplexTypes)
+
+	// 'my_db_orders' could be the collection 'db_orders' of the database 'my' or the collection 'orders' of 'my_db'
+	writeSchema("my_db_orders.schema-raw.json", `{"mainType": {"name": "Orders"}}`)
+	_, err = LoadPersistedSchemas(dir)
+	assert.ErrorContains(t, err, "my_db_orders.schema-raw.json")
+}
+
+func TestLoadPersistedSchemasErrors(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "shop_orders.schema-raw.json"), []byte(`{}`), 0644))
+	_, err := LoadPersistedSchemas(dir)
+	assert.ErrorContains(t, err, "contains no main type")
+
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "shop_orders.schema-raw.json"), []byte(`{"mainType": `), 0644))
+	_, err = LoadPersistedSchemas(dir)
+	assert.ErrorContains(t, err, "error while parsing schema file")
+}
@@ -0,0 +1,271 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+)
+
+// package of the generated protobuf messages
+var ProtoPackage = "model"
+
+const protoImportTimestamp = "google/protobuf/timestamp.proto"
+const protoImportStruct = "google/protobuf/struct.proto"
+const protoValue = "google.protobuf.Value"
+const protoListValue = "google.protobuf.ListValue"
+
+type protoTypeMapping struct {
+	protoType  string
+	importPath string
+}
+
+// protobuf types for the BSON types. ObjectIds and decimals are transported in their string
+// representation, because protobuf has no types for them. Binary uuids keep their 16 raw bytes
+// like in the avro schemas.
+var protoBsonTypes = map[string]protoTypeMapping{
+	"objectId":  {"string", ""},
+	"date":      {"google.protobuf.Timestamp", protoImportTimestamp},
+	"decimal":   {"string", ""},
+	"timestamp": {"uint64", ""},
+	"binData":   {"bytes", ""},
+	"int":       {"int32", ""},
+	"long":      {"int64", ""},
+	"double":    {"double", ""},
+	"bool":      {"bool", ""},
+	"string":    {"string", ""},
+}
+
+var protoScalarTypes = []string{"double", "float", "int32", "int64", "uint32", "uint64", "sint32", "sint64",
+	"fixed32", "fixed64", "sfixed32", "sfixed64", "bool", "string", "bytes"}
+
+type protoGenerator struct {
+	imports           []string
+	otherComplexTypes []mongoHelper.ComplexType
+	// names of the wrapper messages for nested arrays and dictionaries, protobuf doesn't support
+	// repeated repeated fields and maps as values of maps or repeated fields
+	wrapperNames []string
+	wrappers     strings.Builder
+	sb           strings.Builder
+}
+
+func (g *protoGenerator) addImport(importPath string) {
+	if (importPath != "") && !slices.Contains(g.imports, importPath) {
+		g.imports = append(g.imports, importPath)
+	}
+}
+
+// google.protobuf.Value is used for values without a fitting protobuf type
+func (g *protoGenerator) anyValue() string {
+	g.addImport(protoImportStruct)
+	return protoValue
+}
+
+// creates a protobuf field name out of an attribute name, e.g. 'orderItems' -> 'order_items'
+func protoFieldName(attribName string) string {
+	ret := snakeCase(attribName)
+	if ret == "" {
+		return "field"
+	}
+	if unicode.IsDigit([]rune(ret)[0]) {
+		return "f_" + ret
+	}
+	return ret
+}
+
+func (g *protoGenerator) addWrapper(name string, definition func() string) {
+	if slices.Contains(g.wrapperNames, name) {
+		return
+	}
+	g.wrapperNames = append(g.wrapperNames, name)
+	g.wrappers.WriteString(definition())
+}
+
+// returns the message that wraps the given number of array levels around the item type
+func (g *protoGenerator) listWrapper(itemType string, levels uint) string {
+	if levels == 0 {
+		return itemType
+	}
+	inner := g.listWrapper(itemType, levels-1)
+	segments := strings.Split(inner, ".")
+	name := goIdentifier(segments[len(segments)-1]) + "List"
+	g.addWrapper(name, func() string {
+		return fmt.Sprintf("\nmessage %s {\n  repeated %s values = 1;\n}\n", name, inner)
+	})
+	return name
+}
+
+// returns the message that wraps the map of a dictionary type
+func (g *protoGenerator) dictWrapper(dictType *mongoHelper.ComplexType) string {
+	name := goIdentifier(dictType.Name)
+	g.addWrapper(name, func() string {
+		return fmt.Sprintf("\nmessage %s {\n  %s values = 1;\n}\n", name, g.mapType(dictType))
+	})
+	return name
+}
+
+func (g *protoGenerator) mapType(dictType *mongoHelper.ComplexType) string {
+	valueType := g.anyValue()
+	if dictType.DictValue != nil {
+		var dims uint
+		valueType, dims = g.propType(dictType.DictValue, false)
+		valueType = g.listWrapper(valueType, dims)
+	}
+	return fmt.Sprintf("map<string, %s>", valueType)
+}
+
+// protobuf type of a single, not array value
+func (g *protoGenerator) baseType(t mongoHelper.ObservedType) string {
+	if t.IsComplex {
+		complexType, err := getComplexTypeByName(t.ValueType, g.otherComplexTypes)
+		if err == nil && complexType.IsDictionary {
+			return g.dictWrapper(complexType)
+		}
+		return goIdentifier(t.ValueType)
+	}
+	if m, ok := protoBsonTypes[t.BsonType]; ok {
+		g.addImport(m.importPath)
+		return m.protoType
+	}
+	return g.anyValue()
+}
+
+// common protobuf type of the observed types of a polymorphic attribute, numbers are widened, all
+// other mixtures end in google.protobuf.Value
+func (g *protoGenerator) unionType(types []mongoHelper.ObservedType) (string, uint) {
+	dims := types[0].ArrayDimensions
+	bsonTypes := make([]string, 0)
+	for _, t := range types {
+		if (t.ArrayDimensions != dims) || t.IsComplex {
+			return g.anyValue(), 0
+		}
+		if !slices.Contains(bsonTypes, t.BsonType) {
+			bsonTypes = append(bsonTypes, t.BsonType)
+		}
+	}
+	switch {
+	case !slices.ContainsFunc(bsonTypes, func(s string) bool { return (s != "int") && (s != "long") }):
+		return "int64", dims
+	case !slices.ContainsFunc(bsonTypes, func(s string) bool { return (s != "int") && (s != "long") && (s != "double") }):
+		return "double", dims
+	}
+	return g.anyValue(), 0
+}
+
+// returns the protobuf type of the attribute and the number of array dimensions. Dictionaries are
+// returned as map type, if 'allowMap' is set and the attribute isn't an array.
+func (g *protoGenerator) propType(prop *mongoHelper.BasicElemInfo, allowMap bool) (string, uint) {
+	switch {
+	case mongoHelper.IsTuple(prop):
+		g.addImport(protoImportStruct)
+		return protoListValue, 0
+	case mongoHelper.IsPolymorphic(prop):
+		if isArrayUnion(*prop) {
+			itemType, dims := g.unionType(unionItemTypes(*prop))
+			return itemType, dims + 1
+		}
+		return g.unionType(prop.Types)
+	case prop.IsArray && (prop.BsonType == mongoHelper.EMPTY_ARRAY_BSON_TYPE):
+		return g.anyValue(), prop.ArrayDimensions
+	case (prop.BsonType == mongoHelper.NULL) && !prop.IsComplex:
+		return g.anyValue(), 0
+	case allowMap && prop.IsComplex && (prop.ArrayDimensions == 0):
+		complexType, err := getComplexTypeByName(prop.ValueType, g.otherComplexTypes)
+		if err == nil && complexType.IsDictionary {
+			return g.mapType(complexType), 0
+		}
+	}
+	return g.baseType(mongoHelper.ObservedType{
+		ValueType: prop.ValueType,
+		BsonType:  prop.BsonType,
+		Format:    prop.Format,
+		IsComplex: prop.IsComplex,
+	}), prop.ArrayDimensions
+}
+
+// The fields are numbered in the order of the sorted attribute names, so the numbers don't depend on
+// the order of the sampled documents. Added or removed attributes renumber the following fields, that
+// breaks the compatibility with messages of older schemas.
+func (g *protoGenerator) writeMessage(complexType *mongoHelper.ComplexType, props []flatProperty, doc ...string) {
+	props = slices.Clone(props)
+	slices.SortStableFunc(props, func(a, b flatProperty) int {
+		return strings.Compare(a.prop.AttribName, b.prop.AttribName)
+	})
+	g.sb.WriteString("\n")
+	writeLineComment(&g.sb, "", doc...)
+	writeLineComment(&g.sb, "", complexType.Title, complexType.Description)
+	g.sb.WriteString(fmt.Sprintf("message %s {\n", goIdentifier(complexType.Name)))
+	fieldNames := make([]string, 0)
+	for i, f := range props {
+		p := f.prop
+		fieldName := protoFieldName(p.AttribName)
+		for j := 2; slices.Contains(fieldNames, fieldName); j++ {
+			fieldName = protoFieldName(p.AttribName) + "_" + strconv.Itoa(j)
+		}
+		fieldNames = append(fieldNames, fieldName)
+
+		protoType, dims := g.propType(p, true)
+		label := ""
+		switch {
+		case dims > 0:
+			label = "repeated "
+			protoType = g.listWrapper(protoType, dims-1)
+		case (f.optional || p.IsNullable) && slices.Contains(protoScalarTypes, protoType):
+			label = "optional "
+		}
+		option := ""
+		if fieldName != p.AttribName {
+			option = fmt.Sprintf(" [json_name = %s]", strconv.Quote(p.AttribName))
+		}
+		writeLineComment(&g.sb, "  ", p.Title, p.Description, p.Comment)
+		if p.IsEnum {
+			writeLineComment(&g.sb, "  ", "possible values: "+strings.Join(p.DistinctValues, ", "))
+		}
+		g.sb.WriteString(fmt.Sprintf("  %s%s %s = %d%s;\n", label, protoType, fieldName, i+1, option))
+	}
+	g.sb.WriteString("}\n")
+}
+
+// Creates proto3 messages for the main type and the other complex types of a collection. Dictionaries
+// are rendered as maps and the variants of a discriminated collection are merged into the main message.
+func Protobuf(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) string {
+	g := protoGenerator{imports: make([]string, 0), otherComplexTypes: otherComplexTypes, wrapperNames: make([]string, 0)}
+	mainDoc := fmt.Sprintf("%s is the storage model for database: %s, collection: %s", goIdentifier(mainType.Name), database, collection)
+	if mainType.Discriminator != "" {
+		mainDoc += fmt.Sprintf("\nThe attribute '%s' distinguishes the variants, the attributes of the variants are optional", mainType.Discriminator)
+	}
+	g.writeMessage(mainType, flatProperties(mainType, otherComplexTypes), mainDoc)
+	for i := range otherComplexTypes {
+		t := &otherComplexTypes[i]
+		if t.IsDictionary || (t.BaseType != "") {
+			continue
+		}
+		g.writeMessage(t, flatProperties(t, otherComplexTypes))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("// Code generated by schemaguesser. DO NOT EDIT.\n")
+	sb.WriteString("// The fields are numbered by their sorted names, added or removed attributes change the numbers,\n")
+	sb.WriteString("// that breaks the compatibility with messages of older versions of this file.\n\n")
+	sb.WriteString("syntax = \"proto3\";\n")
+	if ProtoPackage != "" {
+		sb.WriteString(fmt.Sprintf("\npackage %s;\n", ProtoPackage))
+	}
+	if len(g.imports) > 0 {
+		slices.Sort(g.imports)
+		sb.WriteString("\n")
+		for _, i := range g.imports {
+			sb.WriteString(fmt.Sprintf("import %s;\n", strconv.Quote(i)))
+		}
+	}
+	sb.WriteString(g.sb.String())
+	sb.WriteString(g.wrappers.String())
+	return sb.String()
+}
+
+func PrintProtobuf(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) error {
+	return writeTextOutput(Protobuf(database, collection, mainType, otherComplexTypes), "proto", database, collection, outputDir)
+}
@@ -0,0 +1,76 @@
+package schema
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// documents with nested arrays, arrays of dictionaries, dictionaries of dictionaries and attributes
+// with arrays and logical types of different types
+func wrapperTestDocs() []bson.D {
+	when := primitive.NewDateTimeFromTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	u := uuid.MustParse("0b8f2d5e-6a4c-4f1e-9b3a-2c7d8e9f0a1b")
+	binUuid := primitive.Binary{Subtype: 4, Data: u[:]}
+	return []bson.D{
+		{
+			{Key: "_id", Value: int32(1)},
+			{Key: "matrix", Value: bson.A{bson.A{int32(1), int32(2)}, bson.A{int32(3)}}},
+			{Key: "history", Value: bson.A{bson.D{{Key: "0b8f2d5e-6a4c-4f1e-9b3a-2c7d8e9f0a1b", Value: 1.5}}}},
+			{Key: "labels", Value: bson.D{{Key: "1c9a3e6f-7b5d-4a2f-8c4b-3d8e9f0a1b2c", Value: bson.D{{Key: "2d0b4f7a-8c6e-4b3a-9d5c-4e9f0a1b2c3d", Value: "a"}}}}},
+			{Key: "mixed", Value: "x"},
+			{Key: "when", Value: when},
+			{Key: "code", Value: "3e1c5a8b-9d7f-4c4b-8e6d-5f0a1b2c3d4e"},
+			{Key: "binId", Value: binUuid},
+		},
+		{
+			{Key: "_id", Value: int32(2)},
+			{Key: "matrix", Value: bson.A{bson.A{int32(4)}}},
+			{Key: "history", Value: bson.A{bson.D{{Key: "4f2d6b9c-0e8a-4d5c-9f7e-6a1b2c3d4e5f", Value: 2.5}}}},
+			{Key: "labels", Value: bson.D{{Key: "5a3e7c0d-1f9b-4e6d-8a8f-7b2c3d4e5f6a", Value: bson.D{{Key: "6b4f8d1e-2a0c-4f7e-9b9a-8c3d4e5f6a7b", Value: "b"}}}}},
+			{Key: "mixed", Value: bson.A{int32(1)}},
+			{Key: "when", Value: int64(5)},
+			{Key: "code", Value: "7c5a9e2f-3b1d-4a8f-8c0b-9d4e5f6a7b8c"},
+			{Key: "binId", Value: binUuid},
+		},
+		{
+			{Key: "_id", Value: int32(3)},
+			{Key: "matrix", Value: bson.A{}},
+			{Key: "history", Value: bson.A{}},
+			{Key: "labels", Value: bson.D{}},
+			{Key: "mixed", Value: bson.A{"a"}},
+			{Key: "when", Value: "yesterday"},
+			{Key: "binId", Value: binUuid},
+		},
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	mainType, otherComplexTypes := goldenTestSchema(t)
+	assertGolden(t, "orders.proto", Protobuf("shop", "orders", mainType, otherComplexTypes))
+
+	mainType, otherComplexTypes = guessTestSchema(t, "metrics", "", wrapperTestDocs())
+	assertGolden(t, "metrics.proto", Protobuf("shop", "metrics", mainType, otherComplexTypes))
+}
+
+// the field numbers don't depend on the order of the documents and attributes
+func TestProtobufFieldNumbers(t *testing.T) {
+	docs := wrapperTestDocs()
+	mainType, otherComplexTypes := guessTestSchema(t, "metrics", "", docs)
+	expected := Protobuf("shop", "metrics", mainType, otherComplexTypes)
+
+	reversed := make([]bson.D, 0, len(docs))
+	for _, d := range docs {
+		d = slices.Clone(d)
+		slices.Reverse(d)
+		reversed = append(reversed, d)
+	}
+	slices.Reverse(reversed)
+	mainType, otherComplexTypes = guessTestSchema(t, "metrics", "", reversed)
+	assert.Equal(t, expected, Protobuf("shop", "metrics", mainType, otherComplexTypes))
+}
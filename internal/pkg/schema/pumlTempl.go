@@ -9,9 +9,15 @@ footer Created with https://github.com/OkieOth/mschemaguesser
 
 class "**{{ .MainType.Name }}**" as {{ .MainType.Name }} #FFFFFF {
   {{ range $index, $prop := .MainType.Properties -}}
+  {{- if IsPolymorphic $prop }}
+  {{- $prop.AttribName }}: {{ UnionTypeName $prop }}{{ if $prop.IsNullable }}?{{ end }} <color:DarkOrange>(polymorphic)</color>
+  {{- else if $prop.IsEnum }}
+  {{- $prop.AttribName }}: {{ EnumTypeName $.MainType.Name $prop }}{{ if $prop.IsNullable }}?{{ end }}<color:grey>    // {{ $prop.BsonType }}</color>
+  {{- else }}
   {{- $prop.AttribName }}: {{ $prop.ValueType }}
-  {{- if $prop.IsArray }}[]{{ end }}
+  {{- ArrayMarker $prop.ArrayDimensions }}{{ if $prop.IsNullable }}?{{ end }}
   {{- if not $prop.IsComplex }}<color:grey>    // {{ $prop.BsonType }}</color>{{ end }}
+  {{- end }}
   {{ end -}}
 }
 
@@ -27,24 +33,49 @@ end note
 {{- range $index, $type := .OtherComplexTypes -}}
   {{ if $type.IsDictionary -}}
 class "**{{ $type.Name }}**" as {{ $type.Name }} <<Map>> #FFFFFF {
-  valueType: {{ $type.DictValueType }}
+  {{ if ne $type.DictKeyFormat "" -}}
+  keyFormat: {{ $type.DictKeyFormat }}
+  {{ end -}}
+  {{ if $type.DictValue -}}
+  valueType: {{ UnionTypeName $type.DictValue }}{{ if $type.DictValue.IsNullable }}?{{ end }}
+  {{- end }}
 } 
-
+{{ if and $type.DictValue $type.DictValue.IsComplex }}
 {{ $type.Name }} .. {{ $type.DictValueType }}
-
+{{ end }}
   {{ else }}
 class "**{{ $type.Name }}**" as {{ $type.Name }} #FFFFFF {
-  {{ range $index, $prop := $type.Properties -}}
+  {{ range $index, $prop := OwnProperties $type $.MainType -}}
+  {{- if IsPolymorphic $prop }}
+  {{- $prop.AttribName }}: {{ UnionTypeName $prop }}{{ if $prop.IsNullable }}?{{ end }} <color:DarkOrange>(polymorphic)</color>
+  {{- else if $prop.IsEnum }}
+  {{- $prop.AttribName }}: {{ EnumTypeName $type.Name $prop }}{{ if $prop.IsNullable }}?{{ end }}<color:grey>    // {{ $prop.BsonType }}</color>
+  {{- else }}
   {{- $prop.AttribName }}: {{ $prop.ValueType }}
-  {{- if $prop.IsArray }}[]{{ end }} 
+  {{- ArrayMarker $prop.ArrayDimensions }}{{ if $prop.IsNullable }}?{{ end }}
   {{- if not $prop.IsComplex }}<color:grey>    // {{ $prop.BsonType }}</color>{{ end }}
+  {{- end }}
   {{ end -}}
 }  
+    {{- if ne $type.BaseType "" }}
 
+{{ $type.BaseType }} <|-- {{ $type.Name }}
+    {{- end }}
   {{- end }}
 
 
 
+{{ end }}
+
+{{- range $index, $enum := .Enums }}
+class "**{{ $enum.Name }}**" as {{ $enum.Name }} <<enumeration>> #FFFFFF {
+{{- range $enum.Values }}
+  {{ PumlText . }}
+{{- end }}
+}
+
+{{ $enum.Owner }} --> {{ $enum.Name }}
+
 {{ end }}
 
 {{- range $index, $type := .Relations -}}
@@ -0,0 +1,229 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const DIFF_FORMAT_TEXT = "text"
+const DIFF_FORMAT_JSON = "json"
+
+var DiffFormats = []string{DIFF_FORMAT_TEXT, DIFF_FORMAT_JSON}
+
+// changes of the presence ratio of an attribute are only reported, if they are greater than this value
+var DiffPresenceThreshold = 0.1
+
+func CheckDiffFormat(format string) error {
+	if !slices.Contains(DiffFormats, format) {
+		return fmt.Errorf("unknown diff format '%s', possible values are: %v", format, DiffFormats)
+	}
+	return nil
+}
+
+type DiffCollectionName struct {
+	Database   string `json:"database"`
+	Collection string `json:"collection"`
+}
+
+type DiffField struct {
+	Path     string  `json:"path"`
+	Variant  string  `json:"variant,omitempty"`
+	Type     string  `json:"type"`
+	Presence float64 `json:"presence"`
+}
+
+type DiffFieldChange struct {
+	Path        string  `json:"path"`
+	Variant     string  `json:"variant,omitempty"`
+	OldType     string  `json:"oldType,omitempty"`
+	NewType     string  `json:"newType,omitempty"`
+	OldPresence float64 `json:"oldPresence,omitempty"`
+	NewPresence float64 `json:"newPresence,omitempty"`
+}
+
+type CollectionDiff struct {
+	Database        string            `json:"database"`
+	Collection      string            `json:"collection"`
+	AddedFields     []DiffField       `json:"addedFields,omitempty"`
+	RemovedFields   []DiffField       `json:"removedFields,omitempty"`
+	TypeChanges     []DiffFieldChange `json:"typeChanges,omitempty"`
+	PresenceChanges []DiffFieldChange `json:"presenceChanges,omitempty"`
+}
+
+type SchemaDiff struct {
+	AddedCollections   []DiffCollectionName `json:"addedCollections"`
+	RemovedCollections []DiffCollectionName `json:"removedCollections"`
+	ChangedCollections []CollectionDiff     `json:"changedCollections"`
+}
+
+func (d *SchemaDiff) HasChanges() bool {
+	return (len(d.AddedCollections) > 0) || (len(d.RemovedCollections) > 0) || (len(d.ChangedCollections) > 0)
+}
+
+func (d *CollectionDiff) hasChanges() bool {
+	return (len(d.AddedFields) > 0) || (len(d.RemovedFields) > 0) || (len(d.TypeChanges) > 0) || (len(d.PresenceChanges) > 0)
+}
+
+func findPersistedSchema(schemas []PersistedSchema, database string, collection string) *PersistedSchema {
+	for i := range schemas {
+		if (schemas[i].Database == database) && (schemas[i].Collection == collection) {
+			return &schemas[i]
+		}
+	}
+	return nil
+}
+
+func diffPresence(f *DictField) float64 {
+	return presence(f.OccurrenceCount, f.SampleCount)
+}
+
+func newDiffField(f *DictField) DiffField {
+	return DiffField{
+		Path:     f.Path,
+		Variant:  f.Variant,
+		Type:     f.Type,
+		Presence: diffPresence(f),
+	}
+}
+
+// the attributes are compared with their full path, attributes of variants additionally by their variant
+func findDictField(fields []DictField, path string, variant string) *DictField {
+	for i := range fields {
+		if (fields[i].Path == path) && (fields[i].Variant == variant) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+func diffCollection(oldSchema *PersistedSchema, newSchema *PersistedSchema) CollectionDiff {
+	ret := CollectionDiff{
+		Database:   newSchema.Database,
+		Collection: newSchema.Collection,
+	}
+	oldFields := dictFields(oldSchema)
+	newFields := dictFields(newSchema)
+	for i := range newFields {
+		nf := &newFields[i]
+		of := findDictField(oldFields, nf.Path, nf.Variant)
+		if of == nil {
+			ret.AddedFields = append(ret.AddedFields, newDiffField(nf))
+			continue
+		}
+		// the names of the complex types can change while the structure is the same, changes of the structure
+		// are reported for the nested attributes
+		if of.StructureType != nf.StructureType {
+			ret.TypeChanges = append(ret.TypeChanges, DiffFieldChange{
+				Path:    nf.Path,
+				Variant: nf.Variant,
+				OldType: of.Type,
+				NewType: nf.Type,
+			})
+		}
+		// schemas of older versions contain no occurrence counts, their presence is 0 and can't be compared
+		oldPresence := diffPresence(of)
+		newPresence := diffPresence(nf)
+		if (oldPresence > 0) && (newPresence > 0) && (math.Abs(newPresence-oldPresence) > DiffPresenceThreshold) {
+			ret.PresenceChanges = append(ret.PresenceChanges, DiffFieldChange{
+				Path:        nf.Path,
+				Variant:     nf.Variant,
+				OldPresence: oldPresence,
+				NewPresence: newPresence,
+			})
+		}
+	}
+	for i := range oldFields {
+		of := &oldFields[i]
+		if findDictField(newFields, of.Path, of.Variant) == nil {
+			ret.RemovedFields = append(ret.RemovedFields, newDiffField(of))
+		}
+	}
+	return ret
+}
+
+// Compares the persisted schemas of two runs. Collections are identified by database and collection name,
+// their attributes by the full attribute path.
+func NewSchemaDiff(oldSchemas []PersistedSchema, newSchemas []PersistedSchema) SchemaDiff {
+	ret := SchemaDiff{
+		AddedCollections:   make([]DiffCollectionName, 0),
+		RemovedCollections: make([]DiffCollectionName, 0),
+		ChangedCollections: make([]CollectionDiff, 0),
+	}
+	for i := range newSchemas {
+		ns := &newSchemas[i]
+		oldSchema := findPersistedSchema(oldSchemas, ns.Database, ns.Collection)
+		if oldSchema == nil {
+			ret.AddedCollections = append(ret.AddedCollections, DiffCollectionName{Database: ns.Database, Collection: ns.Collection})
+			continue
+		}
+		if d := diffCollection(oldSchema, ns); d.hasChanges() {
+			ret.ChangedCollections = append(ret.ChangedCollections, d)
+		}
+	}
+	for _, s := range oldSchemas {
+		if findPersistedSchema(newSchemas, s.Database, s.Collection) == nil {
+			ret.RemovedCollections = append(ret.RemovedCollections, DiffCollectionName{Database: s.Database, Collection: s.Collection})
+		}
+	}
+	return ret
+}
+
+func diffFieldName(path string, variant string) string {
+	if variant == "" {
+		return path
+	}
+	return fmt.Sprintf("%s [%s]", path, variant)
+}
+
+func diffPresenceStr(presence float64) string {
+	return strconv.FormatFloat(presence, 'f', -1, 64)
+}
+
+// human readable report of the differences
+func (d *SchemaDiff) Text() string {
+	if !d.HasChanges() {
+		return "No differences found\n"
+	}
+	var sb strings.Builder
+	for _, c := range d.AddedCollections {
+		sb.WriteString(fmt.Sprintf("+ collection %s.%s\n", c.Database, c.Collection))
+	}
+	for _, c := range d.RemovedCollections {
+		sb.WriteString(fmt.Sprintf("- collection %s.%s\n", c.Database, c.Collection))
+	}
+	for _, c := range d.ChangedCollections {
+		sb.WriteString(fmt.Sprintf("~ collection %s.%s\n", c.Database, c.Collection))
+		for _, f := range c.AddedFields {
+			sb.WriteString(fmt.Sprintf("    + %s: %s (presence: %s)\n", diffFieldName(f.Path, f.Variant), f.Type, diffPresenceStr(f.Presence)))
+		}
+		for _, f := range c.RemovedFields {
+			sb.WriteString(fmt.Sprintf("    - %s: %s (presence: %s)\n", diffFieldName(f.Path, f.Variant), f.Type, diffPresenceStr(f.Presence)))
+		}
+		for _, f := range c.TypeChanges {
+			sb.WriteString(fmt.Sprintf("    ~ %s: type %s -> %s\n", diffFieldName(f.Path, f.Variant), f.OldType, f.NewType))
+		}
+		for _, f := range c.PresenceChanges {
+			sb.WriteString(fmt.Sprintf("    ~ %s: presence %s -> %s\n", diffFieldName(f.Path, f.Variant), diffPresenceStr(f.OldPresence), diffPresenceStr(f.NewPresence)))
+		}
+	}
+	return sb.String()
+}
+
+// machine readable report of the differences
+func (d *SchemaDiff) Json() (string, error) {
+	jsonData, err := marshalJson(d)
+	if err != nil {
+		return "", fmt.Errorf("error while serializing the schema diff: %v", err)
+	}
+	var indented bytes.Buffer
+	if err = json.Indent(&indented, jsonData, "", "  "); err != nil {
+		return "", fmt.Errorf("error while indenting the schema diff: %v", err)
+	}
+	indented.WriteByte('\n')
+	return indented.String(), nil
+}
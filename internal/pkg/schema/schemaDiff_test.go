@@ -0,0 +1,213 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func diffTestSchema(t *testing.T, database string, collection string, docs []bson.D) PersistedSchema {
+	mainType, otherComplexTypes := guessTestSchema(t, collection, "", docs)
+	return PersistedSchema{
+		Database:          database,
+		Collection:        collection,
+		MainType:          mainType,
+		OtherComplexTypes: otherComplexTypes,
+	}
+}
+
+func TestNewSchemaDiff(t *testing.T) {
+	orders := []bson.D{
+		{{Key: "_id", Value: int32(1)}, {Key: "customer", Value: bson.D{{Key: "name", Value: "Ann"}, {Key: "email", Value: "ann@example.com"}}}, {Key: "total", Value: int32(10)}, {Key: "note", Value: "a"}},
+		{{Key: "_id", Value: int32(2)}, {Key: "customer", Value: bson.D{{Key: "name", Value: "Bob"}, {Key: "email", Value: "bob@example.com"}}}, {Key: "total", Value: int32(20)}, {Key: "note", Value: "b"}},
+	}
+	ordersNewFieldAndType := []bson.D{
+		{{Key: "_id", Value: int32(1)}, {Key: "customer", Value: bson.D{{Key: "name", Value: "Ann"}, {Key: "phone", Value: "123"}}}, {Key: "total", Value: "10"}, {Key: "note", Value: "a"}},
+		{{Key: "_id", Value: int32(2)}, {Key: "customer", Value: bson.D{{Key: "name", Value: "Bob"}, {Key: "phone", Value: "456"}}}, {Key: "total", Value: "20"}, {Key: "note", Value: "b"}},
+	}
+	// 'note' is only contained in half of the documents
+	ordersLessNotes := []bson.D{
+		{{Key: "_id", Value: int32(1)}, {Key: "customer", Value: bson.D{{Key: "name", Value: "Ann"}, {Key: "email", Value: "ann@example.com"}}}, {Key: "total", Value: int32(10)}, {Key: "note", Value: "a"}},
+		{{Key: "_id", Value: int32(2)}, {Key: "customer", Value: bson.D{{Key: "name", Value: "Bob"}, {Key: "email", Value: "bob@example.com"}}}, {Key: "total", Value: int32(20)}},
+	}
+	products := []bson.D{{{Key: "_id", Value: "p1"}}}
+
+	tests := []struct {
+		name       string
+		oldSchemas []PersistedSchema
+		newSchemas []PersistedSchema
+		threshold  float64
+		expected   SchemaDiff
+		text       string
+	}{
+		{
+			name:       "no changes",
+			oldSchemas: []PersistedSchema{diffTestSchema(t, "shop", "orders", orders)},
+			newSchemas: []PersistedSchema{diffTestSchema(t, "shop", "orders", orders)},
+			threshold:  0.1,
+			expected:   SchemaDiff{AddedCollections: []DiffCollectionName{}, RemovedCollections: []DiffCollectionName{}, ChangedCollections: []CollectionDiff{}},
+			text:       "No differences found\n",
+		},
+		{
+			name:       "added and removed collections",
+			oldSchemas: []PersistedSchema{diffTestSchema(t, "shop", "orders", orders)},
+			newSchemas: []PersistedSchema{diffTestSchema(t, "shop", "products", products)},
+			threshold:  0.1,
+			expected: SchemaDiff{
+				AddedCollections:   []DiffCollectionName{{Database: "shop", Collection: "products"}},
+				RemovedCollections: []DiffCollectionName{{Database: "shop", Collection: "orders"}},
+				ChangedCollections: []CollectionDiff{},
+			},
+			text: "+ collection shop.products\n- collection shop.orders\n",
+		},
+		{
+			name:       "added and removed fields and type changes",
+			oldSchemas: []PersistedSchema{diffTestSchema(t, "shop", "orders", orders)},
+			newSchemas: []PersistedSchema{diffTestSchema(t, "shop", "orders", ordersNewFieldAndType)},
+			threshold:  0.1,
+			expected: SchemaDiff{
+				AddedCollections:   []DiffCollectionName{},
+				RemovedCollections: []DiffCollectionName{},
+				ChangedCollections: []CollectionDiff{{
+					Database:      "shop",
+					Collection:    "orders",
+					AddedFields:   []DiffField{{Path: "customer.phone", Type: "string", Presence: 1}},
+					RemovedFields: []DiffField{{Path: "customer.email", Type: "string", Presence: 1}},
+					TypeChanges:   []DiffFieldChange{{Path: "total", OldType: "int", NewType: "string"}},
+				}},
+			},
+			text: "~ collection shop.orders\n" +
+				"    + customer.phone: string (presence: 1)\n" +
+				"    - customer.email: string (presence: 1)\n" +
+				"    ~ total: type int -> string\n",
+		},
+		{
+			name:       "presence changes",
+			oldSchemas: []PersistedSchema{diffTestSchema(t, "shop", "orders", orders)},
+			newSchemas: []PersistedSchema{diffTestSchema(t, "shop", "orders", ordersLessNotes)},
+			threshold:  0.1,
+			expected: SchemaDiff{
+				AddedCollections:   []DiffCollectionName{},
+				RemovedCollections: []DiffCollectionName{},
+				ChangedCollections: []CollectionDiff{{
+					Database:        "shop",
+					Collection:      "orders",
+					PresenceChanges: []DiffFieldChange{{Path: "note", OldPresence: 1, NewPresence: 0.5}},
+				}},
+			},
+			text: "~ collection shop.orders\n    ~ note: presence 1 -> 0.5\n",
+		},
+		{
+			name:       "presence changes below the threshold",
+			oldSchemas: []PersistedSchema{diffTestSchema(t, "shop", "orders", orders)},
+			newSchemas: []PersistedSchema{diffTestSchema(t, "shop", "orders", ordersLessNotes)},
+			threshold:  0.5,
+			expected:   SchemaDiff{AddedCollections: []DiffCollectionName{}, RemovedCollections: []DiffCollectionName{}, ChangedCollections: []CollectionDiff{}},
+			text:       "No differences found\n",
+		},
+	}
+	defer func() { DiffPresenceThreshold = 0.1 }()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			DiffPresenceThreshold = test.threshold
+			diff := NewSchemaDiff(test.oldSchemas, test.newSchemas)
+			assert.Equal(t, test.expected, diff)
+			assert.Equal(t, test.text != "No differences found\n", diff.HasChanges())
+			assert.Equal(t, test.text, diff.Text())
+
+			jsonStr, err := diff.Json()
+			require.Nil(t, err)
+			var parsed SchemaDiff
+			require.Nil(t, json.Unmarshal([]byte(jsonStr), &parsed))
+			assert.Equal(t, test.expected, parsed)
+		})
+	}
+}
+
+func TestSchemaDiffPresenceWithoutCounts(t *testing.T) {
+	oldSchema := diffTestSchema(t, "shop", "orders", []bson.D{
+		{{Key: "_id", Value: int32(1)}, {Key: "note", Value: "a"}},
+		{{Key: "_id", Value: int32(2)}, {Key: "note", Value: "b"}},
+	})
+	newSchema := diffTestSchema(t, "shop", "orders", []bson.D{
+		{{Key: "_id", Value: int32(1)}, {Key: "note", Value: "a"}},
+		{{Key: "_id", Value: int32(2)}},
+	})
+	// schemas of older versions contain no sample count
+	oldSchema.MainType.SampleCount = 0
+	diff := NewSchemaDiff([]PersistedSchema{oldSchema}, []PersistedSchema{newSchema})
+	assert.False(t, diff.HasChanges())
+}
+
+// the names of the complex types can change between runs without a change of the structure
+func TestSchemaDiffRenamedTypes(t *testing.T) {
+	docs := []bson.D{
+		{{Key: "_id", Value: int32(1)}, {Key: "address", Value: bson.D{{Key: "city", Value: "Berlin"}}}, {Key: "items", Value: bson.A{bson.D{{Key: "sku", Value: "a"}}}}},
+	}
+	oldSchema := diffTestSchema(t, "shop", "orders", docs)
+	newSchema := diffTestSchema(t, "shop", "orders", docs)
+	renameTypes(map[string]string{"Address": "Address2", "Items": "Items2"}, newSchema.MainType, newSchema.OtherComplexTypes)
+	diff := NewSchemaDiff([]PersistedSchema{oldSchema}, []PersistedSchema{newSchema})
+	assert.False(t, diff.HasChanges(), diff.Text())
+
+	// a complex type that becomes a string is still a type change
+	changed := diffTestSchema(t, "shop", "orders", []bson.D{
+		{{Key: "_id", Value: int32(1)}, {Key: "address", Value: "Berlin"}, {Key: "items", Value: bson.A{bson.D{{Key: "sku", Value: "a"}}}}},
+	})
+	diff = NewSchemaDiff([]PersistedSchema{oldSchema}, []PersistedSchema{changed})
+	require.Len(t, diff.ChangedCollections, 1)
+	assert.Equal(t, []DiffFieldChange{{Path: "address", OldType: "Address", NewType: "string"}}, diff.ChangedCollections[0].TypeChanges)
+	assert.Equal(t, "address.city", diff.ChangedCollections[0].RemovedFields[0].Path)
+}
+
+func TestSchemaDiffJson(t *testing.T) {
+	diff := SchemaDiff{
+		AddedCollections:   []DiffCollectionName{{Database: "shop", Collection: "products"}},
+		RemovedCollections: []DiffCollectionName{},
+		ChangedCollections: []CollectionDiff{{
+			Database:        "shop",
+			Collection:      "orders",
+			AddedFields:     []DiffField{{Path: "items[].sku", Variant: "Online (type=online)", Type: "string", Presence: 0.75}},
+			PresenceChanges: []DiffFieldChange{{Path: "note", OldPresence: 1, NewPresence: 0.5}},
+		}},
+	}
+	jsonStr, err := diff.Json()
+	require.Nil(t, err)
+	assert.Equal(t, `{
+  "addedCollections": [
+    {
+      "database": "shop",
+      "collection": "products"
+    }
+  ],
+  "removedCollections": [],
+  "changedCollections": [
+    {
+      "database": "shop",
+      "collection": "orders",
+      "addedFields": [
+        {
+          "path": "items[].sku",
+          "variant": "Online (type=online)",
+          "type": "string",
+          "presence": 0.75
+        }
+      ],
+      "presenceChanges": [
+        {
+          "path": "note",
+          "oldPresence": 1,
+          "newPresence": 0.5
+        }
+      ]
+    }
+  ]
+}
+`, jsonStr)
+	assert.Equal(t, "+ collection shop.products\n~ collection shop.orders\n"+
+		"    + items[].sku [Online (type=online)]: string (presence: 0.75)\n"+
+		"    ~ note: presence 1 -> 0.5\n", diff.Text())
+}
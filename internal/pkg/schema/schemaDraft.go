@@ -0,0 +1,64 @@
+package schema
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+)
+
+const DRAFT_07 = "07"
+const DRAFT_2020_12 = "2020-12"
+
+var SchemaDrafts = []string{DRAFT_07, DRAFT_2020_12}
+
+// JSON schema draft of the generated schemas
+var SchemaDraft = DRAFT_07
+
+// optional base URI for the '$id' of the 2020-12 schemas, without it an URN is used
+var SchemaIdBase string
+
+func CheckSchemaDraft(draft string) error {
+	if !slices.Contains(SchemaDrafts, draft) {
+		return fmt.Errorf("unknown schema draft '%s', possible values are: %v", draft, SchemaDrafts)
+	}
+	return nil
+}
+
+func isDraft2020() bool {
+	return SchemaDraft == DRAFT_2020_12
+}
+
+func schemaUri() string {
+	if isDraft2020() {
+		return "https://json-schema.org/draft/2020-12/schema"
+	}
+	return "http://json-schema.org/draft-07/schema#"
+}
+
+// keyword that contains the definitions of the other complex types
+func defsKeyword() string {
+	if isDraft2020() {
+		return "$defs"
+	}
+	return "definitions"
+}
+
+func refPrefix() string {
+	return "#/" + defsKeyword() + "/"
+}
+
+// '$id' of the schema for one collection
+func schemaId(database string, collection string) string {
+	if SchemaIdBase == "" {
+		return fmt.Sprintf("urn:mongodb:%s:%s", url.PathEscape(database), url.PathEscape(collection))
+	}
+	return fmt.Sprintf("%s/%s/%s.schema.json", strings.TrimSuffix(SchemaIdBase, "/"), url.PathEscape(database), url.PathEscape(collection))
+}
+
+// tuples are only rendered with 'prefixItems' of the 2020-12 draft
+func isTuple(prop mongoHelper.BasicElemInfo) bool {
+	return isDraft2020() && mongoHelper.IsTuple(&prop)
+}
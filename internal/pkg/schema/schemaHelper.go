@@ -1,14 +1,20 @@
 package schema
 
 import (
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"okieoth/schemaguesser/internal/pkg/mongoHelper"
 	"okieoth/schemaguesser/internal/pkg/utils"
 	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
+	"strings"
 	"text/template"
 	"unicode"
 )
@@ -25,14 +31,39 @@ func NewTypeRelation(start string, end string) TypeRelation {
 	}
 }
 
+type EnumType struct {
+	Name   string
+	Owner  string
+	Values []string
+}
+
 type PumlTemplateInput struct {
 	Database          string
 	Collection        string
 	MainType          *mongoHelper.ComplexType
 	Relations         []TypeRelation
 	OtherComplexTypes []mongoHelper.ComplexType
+	Enums             []EnumType
 }
 
+// min number of observed values of an attribute before it can be considered as enum
+var EnumMinSamples int64 = 50
+
+// if set, the observed value statistics are rendered as 'x-observed-*' extensions instead
+// of JSON schema validation keywords
+var StatsAsExtensions bool
+
+// min number of keys of a complex type, before it's considered as dictionary because of
+// the sparse usage of its keys. 0 disables this check
+var DictMinKeys = 20
+
+// max average ratio of the keys of a type, that are used per document, to consider it as dictionary
+const dictMaxKeyUsage = 0.2
+
+// min similarity (shared attributes / all attributes) of two complex types, before they are merged
+// to one type. With 1.0 only types with the same attributes are merged
+var TypeSimilarity = 1.0
+
 type TemplateInput struct {
 	Database          string
 	Collection        string
@@ -48,6 +79,147 @@ func lastIndexTypes(array []mongoHelper.ComplexType) int {
 	return len(array) - 1
 }
 
+// returns the names of the attributes that were found in every processed document of the type
+func requiredProps(complexType *mongoHelper.ComplexType) []string {
+	ret := make([]string, 0)
+	if complexType.SampleCount == 0 {
+		return ret
+	}
+	for _, p := range complexType.Properties {
+		if p.OccurrenceCount >= complexType.SampleCount {
+			ret = append(ret, p.AttribName)
+		}
+	}
+	return ret
+}
+
+func isOptional(prop mongoHelper.BasicElemInfo, sampleCount int64) bool {
+	return (sampleCount > 0) && (prop.OccurrenceCount < sampleCount)
+}
+
+// ratio of the processed documents that contain the attribute, rounded to 4 decimal places
+func presenceRatio(prop mongoHelper.BasicElemInfo, sampleCount int64) string {
+	return strconv.FormatFloat(presence(prop.OccurrenceCount, sampleCount), 'f', -1, 64)
+}
+
+func presence(occurrenceCount int64, sampleCount int64) float64 {
+	if sampleCount == 0 {
+		return 0
+	}
+	return math.Round(float64(occurrenceCount)/float64(sampleCount)*10000) / 10000
+}
+
+// adds the observations of the source type to the target type, it's used when two types are merged
+// Attributes that only exist in the source type are added to the target, because the sample count
+// of the target grows, they are optional afterwards.
+func mergeObservations(target *mongoHelper.ComplexType, source *mongoHelper.ComplexType) {
+	target.SampleCount += source.SampleCount
+	for _, sp := range source.Properties {
+		index := slices.IndexFunc(target.Properties, func(p mongoHelper.BasicElemInfo) bool {
+			return p.AttribName == sp.AttribName
+		})
+		if index == -1 {
+			target.Properties = append(target.Properties, mongoHelper.CopyProperty(&sp))
+		} else {
+			mongoHelper.MergeProperty(&target.Properties[index], &sp)
+		}
+	}
+}
+
+func isPolymorphic(prop mongoHelper.BasicElemInfo) bool {
+	return mongoHelper.IsPolymorphic(&prop)
+}
+
+// returns true if all observed types of the attribute are arrays, in that case the union
+// is rendered on the level of the array items
+func isArrayUnion(prop mongoHelper.BasicElemInfo) bool {
+	if len(prop.Types) == 0 {
+		return false
+	}
+	for _, t := range prop.Types {
+		if !t.IsArray {
+			return false
+		}
+	}
+	return true
+}
+
+// returns the observed types that build the union, for array unions the types of the items
+func unionItemTypes(prop mongoHelper.BasicElemInfo) []mongoHelper.ObservedType {
+	if !isArrayUnion(prop) {
+		return prop.Types
+	}
+	ret := make([]mongoHelper.ObservedType, 0)
+	for _, t := range prop.Types {
+		t.ArrayDimensions--
+		t.IsArray = t.ArrayDimensions > 0
+		ret = append(ret, t)
+	}
+	return ret
+}
+
+// one entry for every nested array level below the first array dimension
+func nestedArrayLevels(arrayDimensions uint) []int {
+	ret := make([]int, 0)
+	for i := uint(1); i < arrayDimensions; i++ {
+		ret = append(ret, int(i))
+	}
+	return ret
+}
+
+func arrayMarker(arrayDimensions uint) string {
+	return strings.Repeat("[]", int(arrayDimensions))
+}
+
+// returns the distinct JSON types of a polymorphic attribute, in case that all observed (item) types
+// are simple (no complex types and no arrays). Otherwise nil is returned.
+func scalarUnionTypes(prop mongoHelper.BasicElemInfo) []string {
+	ret := make([]string, 0)
+	for _, t := range unionItemTypes(prop) {
+		if t.IsComplex || t.IsArray {
+			return nil
+		}
+		if !slices.Contains(ret, t.ValueType) {
+			ret = append(ret, t.ValueType)
+		}
+	}
+	return ret
+}
+
+// null is added to the types of the union, if it isn't an array union. For array unions
+// the null belongs to the array itself and not to the items
+func unionNullable(prop mongoHelper.BasicElemInfo) bool {
+	return prop.IsNullable && !isArrayUnion(prop)
+}
+
+func bsonTypes(prop mongoHelper.BasicElemInfo) []string {
+	ret := make([]string, 0)
+	for _, t := range prop.Types {
+		if !slices.Contains(ret, t.BsonType) {
+			ret = append(ret, t.BsonType)
+		}
+	}
+	return ret
+}
+
+// type name that is used in the diagrams, for polymorphic attributes all observed types are listed
+func unionTypeName(prop mongoHelper.BasicElemInfo) string {
+	if !mongoHelper.IsPolymorphic(&prop) {
+		return prop.ValueType + arrayMarker(prop.ArrayDimensions)
+	}
+	names := make([]string, 0)
+	for _, t := range unionItemTypes(prop) {
+		n := t.ValueType + arrayMarker(t.ArrayDimensions)
+		if !slices.Contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	if isArrayUnion(prop) {
+		return "(" + strings.Join(names, " | ") + ")[]"
+	}
+	return strings.Join(names, " | ")
+}
+
 func getComplexTypeByName(name string, otherComplexTypes []mongoHelper.ComplexType) (*mongoHelper.ComplexType, error) {
 	var complexType mongoHelper.ComplexType
 	for _, e := range otherComplexTypes {
@@ -71,22 +243,77 @@ func containsProp(propName string, t *mongoHelper.ComplexType) bool {
 func complexTypesAreNotTheSame(lastTypeInst *mongoHelper.ComplexType, currentTypeInfo *mongoHelper.BasicElemInfo, otherComplexTypes []mongoHelper.ComplexType) bool {
 	currentTypeInst, err := getComplexTypeByName(currentTypeInfo.ValueType, otherComplexTypes)
 	if err != nil {
-		fmt.Sprintf("Error while try to resolve name to complex type: %v\n", err)
+		log.Printf("Error while try to resolve name to complex type: %v\n", err)
+		return false
+	}
+	// point where it's decided if the type is the same
+	return !typesAreSimilar(lastTypeInst, currentTypeInst, otherComplexTypes)
+}
+
+// returns true if both attributes can be merged without creating a polymorphic attribute. Attributes
+// that contain only null values are compatible to all other attributes
+func propsAreCompatible(p1, p2 *mongoHelper.BasicElemInfo, otherComplexTypes []mongoHelper.ComplexType) bool {
+	if (p1.ValueType == mongoHelper.NULL) || (p2.ValueType == mongoHelper.NULL) {
+		return true
+	}
+	if (p1.IsComplex != p2.IsComplex) || (p1.ArrayDimensions != p2.ArrayDimensions) {
 		return false
 	}
-	var missingAttribCount int
-	for _, p := range currentTypeInst.Properties {
-		if !containsProp(p.AttribName, lastTypeInst) {
-			missingAttribCount += 1
+	if !p1.IsComplex {
+		return p1.ValueType == p2.ValueType
+	}
+	if p1.ValueType == p2.ValueType {
+		return true
+	}
+	c1, err := getComplexTypeByName(p1.ValueType, otherComplexTypes)
+	if err != nil {
+		log.Printf("propsAreCompatible: error while resolve complex type (1): %v", err)
+		return false
+	}
+	c2, err := getComplexTypeByName(p2.ValueType, otherComplexTypes)
+	if err != nil {
+		log.Printf("propsAreCompatible: error while resolve complex type (2): %v", err)
+		return false
+	}
+	return typesAreSimilar(c1, c2, otherComplexTypes)
+}
+
+// Jaccard similarity of the attributes of both types: the number of attributes with the same name
+// and a compatible type, divided by the number of all different attribute names of both types
+func typeSimilarity(t1, t2 *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) float64 {
+	shared := 0
+	for i := range t1.Properties {
+		for j := range t2.Properties {
+			if t1.Properties[i].AttribName == t2.Properties[j].AttribName {
+				if propsAreCompatible(&t1.Properties[i], &t2.Properties[j], otherComplexTypes) {
+					shared++
+				}
+				break
+			}
+		}
+	}
+	all := len(t1.Properties)
+	for _, p := range t2.Properties {
+		if !containsProp(p.AttribName, t1) {
+			all++
 		}
 	}
+	if all == 0 {
+		return 1
+	}
+	return float64(shared) / float64(all)
+}
 
-	// point where it's decided if the type is the same
-	if missingAttribCount > (len(lastTypeInst.Properties) / 4) {
-		return true
-	} else {
+// returns true if both types can be merged to one type. As long as the TypeSimilarity
+// is 1.0 the stricter typesAreEqual is used
+func typesAreSimilar(t1, t2 *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) bool {
+	if (TypeSimilarity >= 1) || t1.IsDictionary || t2.IsDictionary {
+		return typesAreEqual(t1, t2, otherComplexTypes)
+	}
+	if (t1.BaseType != "") || (t2.BaseType != "") {
 		return false
 	}
+	return typeSimilarity(t1, t2, otherComplexTypes) >= TypeSimilarity
 }
 
 func checkForSameTypesOfAllProps_old(complexType mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) bool {
@@ -120,6 +347,10 @@ func checkForSameTypesOfAllProps_old(complexType mongoHelper.ComplexType, otherC
 }
 
 func checkForSameTypesOfAllProps(complexType mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) bool {
+	if complexType.BaseType != "" {
+		// variants of a discriminated type are no dictionaries
+		return false
+	}
 	var lastType string
 	for _, p := range complexType.Properties {
 		if !p.IsComplex {
@@ -186,6 +417,10 @@ func containsPropWithSameType(propToFind *mongoHelper.BasicElemInfo, t *mongoHel
 }
 
 func typesAreEqual(t1, t2 *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) bool {
+	if (t1.BaseType != "") || (t2.BaseType != "") {
+		// variants of a discriminated type are kept, even if they look the same
+		return false
+	}
 	if t1.IsDictionary && t2.IsDictionary {
 		return t1.DictValueType == t2.DictValueType
 	} else {
@@ -204,17 +439,17 @@ func typesAreEqual(t1, t2 *mongoHelper.ComplexType, otherComplexTypes []mongoHel
 
 func replaceAllTypeReferences(typeNameToReplace string, typeNameReplacement string, otherComplexTypes []mongoHelper.ComplexType, mainType *mongoHelper.ComplexType) {
 	for i, t := range otherComplexTypes {
-		for j, p := range t.Properties {
-			if p.ValueType == typeNameToReplace {
-				(otherComplexTypes)[i].Properties[j].ValueType = typeNameReplacement
-			}
+		for j := range t.Properties {
+			mongoHelper.ReplaceTypeReference(&otherComplexTypes[i].Properties[j], typeNameToReplace, typeNameReplacement)
 		}
-	}
-	for j, p := range mainType.Properties {
-		if p.ValueType == typeNameToReplace {
-			mainType.Properties[j].ValueType = typeNameReplacement
+		if t.DictValue != nil {
+			mongoHelper.ReplaceTypeReference(otherComplexTypes[i].DictValue, typeNameToReplace, typeNameReplacement)
+			otherComplexTypes[i].DictValueType = otherComplexTypes[i].DictValue.ValueType
 		}
 	}
+	for j := range mainType.Properties {
+		mongoHelper.ReplaceTypeReference(&mainType.Properties[j], typeNameToReplace, typeNameReplacement)
+	}
 }
 
 func removeUnneededTypes(typesToRemove []string, otherComplexTypes []mongoHelper.ComplexType, mainType *mongoHelper.ComplexType) []mongoHelper.ComplexType {
@@ -241,6 +476,10 @@ func removeTrailingDigits(name string) string {
 
 func removeDigitsFromTypeNames(mainType *mongoHelper.ComplexType, complexTypes []mongoHelper.ComplexType) []mongoHelper.ComplexType {
 	for i, t := range complexTypes {
+		if t.BaseType != "" {
+			// variant names are derived from the discriminator values, digits there are intended
+			continue
+		}
 		trimmedName := removeTrailingDigits(t.Name)
 		if trimmedName == "" {
 			trimmedName = mongoHelper.GetNewTypeName("Type", complexTypes)
@@ -250,16 +489,12 @@ func removeDigitsFromTypeNames(mainType *mongoHelper.ComplexType, complexTypes [
 				if ct.Name == t.Name {
 					continue
 				}
-				for k, p := range ct.Properties {
-					if p.ValueType == t.Name {
-						complexTypes[j].Properties[k].ValueType = trimmedName
-					}
+				for k := range ct.Properties {
+					mongoHelper.ReplaceTypeReference(&complexTypes[j].Properties[k], t.Name, trimmedName)
 				}
 			}
-			for k, p := range mainType.Properties {
-				if p.ValueType == t.Name {
-					mainType.Properties[k].ValueType = trimmedName
-				}
+			for k := range mainType.Properties {
+				mongoHelper.ReplaceTypeReference(&mainType.Properties[k], t.Name, trimmedName)
 			}
 			complexTypes[i].Name = trimmedName
 		}
@@ -277,9 +512,10 @@ func ReduceDoubleTypesByName(otherComplexTypes []mongoHelper.ComplexType) []mong
 			if e2.TypeReduced {
 				continue
 			}
-			if e1.Name == e2.Name {
+			if e1.Name == e2.Name && e1.BaseType == "" && e2.BaseType == "" {
 				typesToRemove = append(typesToRemove, e2.LongName)
 				otherComplexTypes[i+j+1].TypeReduced = true
+				mergeObservations(&otherComplexTypes[i], &e2)
 			}
 		}
 	}
@@ -302,9 +538,10 @@ func ReduceTypes(mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHel
 			if e2.TypeReduced {
 				continue
 			}
-			if typesAreEqual(&e1, &e2, otherComplexTypes) {
+			if typesAreSimilar(&e1, &e2, otherComplexTypes) {
 				typesToRemove = append(typesToRemove, e2.LongName)
 				otherComplexTypes[i+j+1].TypeReduced = true
+				mergeObservations(&otherComplexTypes[i], &e2)
 				replaceAllTypeReferences(e2.Name, e1.Name, otherComplexTypes, mainType)
 			}
 		}
@@ -312,26 +549,56 @@ func ReduceTypes(mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHel
 	return removeUnneededTypes(typesToRemove, otherComplexTypes, mainType)
 }
 
-func GuessDicts(otherComplexTypes []mongoHelper.ComplexType) []mongoHelper.ComplexType {
+// Finds complex types that are used as dictionaries. Candidates are types where all attributes reference
+// the same complex type, types where all keys have the same format (uuid, objectId hex, date, number) and types
+// with a lot of keys, where every document only uses a few of them. In any case the values of all keys need
+// to be of the same kind. The complex value types of a dictionary are merged to one type.
+func GuessDicts(mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) []mongoHelper.ComplexType {
 	var typesToRemove []string
 	for i := range otherComplexTypes {
 		e := otherComplexTypes[i]
-		if checkForSameTypesOfAllProps(e, otherComplexTypes) {
-			var typeNameToUse string
-			for i, p := range e.Properties {
-				e.UsedKeys = append(e.UsedKeys, p.AttribName)
-				if i == 0 {
-					typeNameToUse = p.ValueType
-				} else {
-					if p.ValueType != typeNameToUse {
-						typesToRemove = append(typesToRemove, p.ValueType)
-					}
-				}
+		if e.IsDictionary || (len(e.Properties) == 0) || !notInTypesToRemove(e.Name, typesToRemove) {
+			continue
+		}
+		keys := make([]string, 0, len(e.Properties))
+		for _, p := range e.Properties {
+			keys = append(keys, p.AttribName)
+		}
+		keyFormat := mongoHelper.GuessKeyFormat(keys)
+		if !checkForSameTypesOfAllProps(e, otherComplexTypes) && (keyFormat == "") && !hasKeyExplosion(&e) {
+			continue
+		}
+		if (e.BaseType != "") || !dictValuesAreCompatible(e.Properties) {
+			continue
+		}
+		var valueTypeName string
+		for _, p := range e.Properties {
+			if !p.IsComplex {
+				continue
+			}
+			if valueTypeName == "" {
+				valueTypeName = p.ValueType
+			} else if p.ValueType != valueTypeName {
+				mergeDictValueType(valueTypeName, p.ValueType, mainType, otherComplexTypes)
+				typesToRemove = append(typesToRemove, p.ValueType)
 			}
-			otherComplexTypes[i].Properties = make([]mongoHelper.BasicElemInfo, 0)
-			otherComplexTypes[i].IsDictionary = true
-			otherComplexTypes[i].DictValueType = typeNameToUse
 		}
+		if (valueTypeName != "") && (keyFormat != "") {
+			// the value type got its name from one of the keys
+			renameDictValueType(e.Name, valueTypeName, keys, mainType, otherComplexTypes)
+		}
+		// the attributes are changed by the merge of the value types
+		dictValue := mongoHelper.CopyProperty(&otherComplexTypes[i].Properties[0])
+		for j := 1; j < len(otherComplexTypes[i].Properties); j++ {
+			mongoHelper.MergeProperty(&dictValue, &otherComplexTypes[i].Properties[j])
+		}
+		dictValue.AttribName = ""
+		otherComplexTypes[i].UsedKeys = keys
+		otherComplexTypes[i].Properties = make([]mongoHelper.BasicElemInfo, 0)
+		otherComplexTypes[i].IsDictionary = true
+		otherComplexTypes[i].DictKeyFormat = keyFormat
+		otherComplexTypes[i].DictValue = &dictValue
+		otherComplexTypes[i].DictValueType = dictValue.ValueType
 	}
 	var ret []mongoHelper.ComplexType
 	for _, t := range otherComplexTypes {
@@ -342,93 +609,325 @@ func GuessDicts(otherComplexTypes []mongoHelper.ComplexType) []mongoHelper.Compl
 	return ret
 }
 
-func PrintSchema(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) {
-	input := TemplateInput{
-		MainType:          mainType,
-		OtherComplexTypes: otherComplexTypes,
-		Database:          database,
+// A type with many keys, where every document only contains a small part of them, looks like a dictionary
+func hasKeyExplosion(complexType *mongoHelper.ComplexType) bool {
+	if (DictMinKeys <= 0) || (len(complexType.Properties) < DictMinKeys) || (complexType.SampleCount == 0) {
+		return false
+	}
+	var occurrences int64
+	for _, p := range complexType.Properties {
+		occurrences += p.OccurrenceCount
+	}
+	keysPerDoc := float64(occurrences) / float64(complexType.SampleCount)
+	return keysPerDoc <= float64(len(complexType.Properties))*dictMaxKeyUsage
+}
+
+// the values of a dictionary need all to be complex types or simple values of the same type, with the
+// same array dimensions. Attributes that only contain null values are ignored
+func dictValuesAreCompatible(props []mongoHelper.BasicElemInfo) bool {
+	var first *mongoHelper.BasicElemInfo
+	for i := range props {
+		p := &props[i]
+		if p.ValueType == mongoHelper.NULL {
+			continue
+		}
+		if mongoHelper.IsPolymorphic(p) {
+			return false
+		}
+		if first == nil {
+			first = p
+			continue
+		}
+		if (p.IsComplex != first.IsComplex) || (p.ArrayDimensions != first.ArrayDimensions) {
+			return false
+		}
+		if !p.IsComplex && (p.ValueType != first.ValueType) {
+			return false
+		}
+	}
+	return first != nil
+}
 
-		Collection: collection,
+// merges the observations of the second value type of a dictionary into the first one
+func mergeDictValueType(targetName string, sourceName string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) {
+	targetIndex := complexTypeIndex(targetName, otherComplexTypes)
+	sourceIndex := complexTypeIndex(sourceName, otherComplexTypes)
+	if (targetIndex == -1) || (sourceIndex == -1) {
+		log.Printf("mergeDictValueType: can't resolve value types: %s, %s", targetName, sourceName)
+		return
 	}
-	printTemplateBase("schema.tmpl", schemaTemplateStr, "schema.json", database, collection, &input, outputDir)
+	mergeObservations(&otherComplexTypes[targetIndex], &otherComplexTypes[sourceIndex])
+	replaceAllTypeReferences(sourceName, targetName, otherComplexTypes, mainType)
 }
 
-func PersistSchemaBase(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) {
+func renameDictValueType(dictName string, valueTypeName string, keys []string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) {
+	trimmedName := removeTrailingDigits(valueTypeName)
+	if !slices.ContainsFunc(keys, func(k string) bool {
+		return removeTrailingDigits(typeNamePart(k)) == trimmedName
+	}) {
+		return
+	}
+	newName := mongoHelper.GetNewTypeName(dictName+"Value", otherComplexTypes)
+	renameTypes(map[string]string{valueTypeName: newName}, mainType, otherComplexTypes)
+}
+
+// Marks string and integer attributes with a small number of distinct values as enums
+func GuessEnums(mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) {
+	guessEnumsForType(mainType)
+	for i := range otherComplexTypes {
+		if !otherComplexTypes[i].IsDictionary {
+			guessEnumsForType(&otherComplexTypes[i])
+		}
+	}
+}
+
+func guessEnumsForType(complexType *mongoHelper.ComplexType) {
+	for i := range complexType.Properties {
+		p := &complexType.Properties[i]
+		if p.IsArray || p.IsComplex || p.TooManyValues || mongoHelper.IsPolymorphic(p) {
+			continue
+		}
+		if (p.ValueType != mongoHelper.STRING) && (p.ValueType != mongoHelper.INT) {
+			continue
+		}
+		// null values don't count as samples
+		var valueCount int64
+		for _, t := range p.Types {
+			valueCount += t.Count
+		}
+		if (len(p.DistinctValues) == 0) || (valueCount < EnumMinSamples) {
+			continue
+		}
+		if int64(len(p.DistinctValues)) >= valueCount {
+			// every value was only seen once, that's no hint for an enum
+			continue
+		}
+		p.IsEnum = true
+		if p.ValueType == mongoHelper.INT {
+			slices.SortFunc(p.DistinctValues, func(a, b string) int {
+				i1, _ := strconv.ParseInt(a, 10, 64)
+				i2, _ := strconv.ParseInt(b, 10, 64)
+				return cmp.Compare(i1, i2)
+			})
+		} else {
+			slices.Sort(p.DistinctValues)
+		}
+	}
+}
+
+// returns the variants of a discriminated main type
+func variants(otherComplexTypes []mongoHelper.ComplexType) []mongoHelper.ComplexType {
+	ret := make([]mongoHelper.ComplexType, 0)
+	for _, t := range otherComplexTypes {
+		if t.BaseType != "" {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+// returns the variants that have a discriminator value
+func discriminatorMapping(otherComplexTypes []mongoHelper.ComplexType) []mongoHelper.ComplexType {
+	ret := make([]mongoHelper.ComplexType, 0)
+	for _, t := range variants(otherComplexTypes) {
+		if t.DiscriminatorValue != "" {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+// returns the attributes of the type, for variants without the attributes that are inherited from the main type
+func ownProperties(complexType mongoHelper.ComplexType, mainType *mongoHelper.ComplexType) []mongoHelper.BasicElemInfo {
+	if (complexType.BaseType == "") || (complexType.BaseType != mainType.Name) {
+		return complexType.Properties
+	}
+	ret := make([]mongoHelper.BasicElemInfo, 0)
+	for _, p := range complexType.Properties {
+		if !containsProp(p.AttribName, mainType) {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+// attribute of a record in a wire format, that doesn't support inheritance
+type flatProperty struct {
+	prop     *mongoHelper.BasicElemInfo
+	optional bool
+}
+
+// returns the attributes of the type for wire formats without inheritance (protobuf, avro). The own
+// attributes of the variants of a discriminated main type are added as optional attributes, so the
+// main type covers all documents of the collection.
+func flatProperties(complexType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) []flatProperty {
+	ret := make([]flatProperty, 0)
+	for i := range complexType.Properties {
+		p := &complexType.Properties[i]
+		ret = append(ret, flatProperty{prop: p, optional: isOptional(*p, complexType.SampleCount)})
+	}
+	if complexType.Discriminator == "" {
+		return ret
+	}
+	for _, v := range variants(otherComplexTypes) {
+		own := ownProperties(v, complexType)
+		for i := range own {
+			p := &own[i]
+			if !slices.ContainsFunc(ret, func(f flatProperty) bool { return f.prop.AttribName == p.AttribName }) {
+				ret = append(ret, flatProperty{prop: p, optional: true})
+			}
+		}
+	}
+	return ret
+}
+
+// returns the string as quoted and escaped JSON string
+func jsonString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// name of the enumeration class in the diagrams
+func enumTypeName(typeName string, prop mongoHelper.BasicElemInfo) string {
+	if len(prop.AttribName) == 0 {
+		return typeName
+	}
+	runes := []rune(prop.AttribName)
+	return typeName + string(unicode.ToUpper(runes[0])) + string(runes[1:])
+}
+
+// adds the enums of the given attributes, for variants only their own attributes are given
+func addEnums(enums []EnumType, complexType *mongoHelper.ComplexType, props []mongoHelper.BasicElemInfo) []EnumType {
+	for _, p := range props {
+		if p.IsEnum {
+			enums = append(enums, EnumType{
+				Name:   enumTypeName(complexType.Name, p),
+				Owner:  complexType.Name,
+				Values: p.DistinctValues,
+			})
+		}
+	}
+	return enums
+}
+
+func PrintSchema(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) error {
+	return writeJsonOutput(NewJsonSchema(database, collection, mainType, otherComplexTypes), "schema.json", database, collection, outputDir)
+}
+
+func PersistSchemaBase(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) error {
 	schemaRaw := mongoHelper.SchemaRaw{
+		Database:          database,
+		Collection:        collection,
 		MainType:          mainType,
 		OtherComplexTypes: &otherComplexTypes,
 	}
 
 	jsonData, err := json.MarshalIndent(schemaRaw, "", "  ")
 	if err != nil {
-		log.Printf("[%s:%s] error, failed to marshall schema raw data: %v", database, collection, err)
+		return fmt.Errorf("failed to marshal schema raw data: %v", err)
 	}
+	return writeTextOutput(string(jsonData)+"\n", schemaRawFileExt, database, collection, outputDir)
+}
 
-	if outputDir == "stdout" {
-		fmt.Println(jsonData)
-	} else {
-		outputFile, err := utils.CreateOutputFile(outputDir, "schema-raw.json", database, collection)
-		if err != nil {
-			panic(err)
+func addTypeRelations(typeRelations []TypeRelation, typeName string, prop *mongoHelper.BasicElemInfo) []TypeRelation {
+	addRelation := func(endType string) {
+		if !slices.ContainsFunc(typeRelations, func(v TypeRelation) bool {
+			return (v.Start == typeName) && (v.End == endType)
+		}) {
+			typeRelations = append(typeRelations, NewTypeRelation(typeName, endType))
 		}
-		defer outputFile.Close()
-
-		_, err = outputFile.Write(jsonData)
-		if err != nil {
-			log.Printf("[%s:%s] error, failed to write schema raw data to file: %v", database, collection, err)
+	}
+	if prop.IsComplex {
+		addRelation(prop.ValueType)
+	}
+	for _, t := range prop.Types {
+		if t.IsComplex {
+			addRelation(t.ValueType)
 		}
 	}
+	return typeRelations
+}
+
+func WritePlantUml(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) error {
+	input := newPumlTemplateInput(database, collection, mainType, otherComplexTypes)
+	return printTemplateBase("plantuml.tmpl", pumlTemplateStr, "schema.puml", database, collection, &input, outputDir)
 }
 
-func WritePlantUml(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) {
+func WriteMermaid(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) error {
+	input := newPumlTemplateInput(database, collection, mainType, otherComplexTypes)
+	return printTemplateBase("mermaid.tmpl", mermaidTemplateStr, "schema.mmd", database, collection, &input, outputDir)
+}
+
+func WriteGraphviz(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) error {
+	input := newPumlTemplateInput(database, collection, mainType, otherComplexTypes)
+	return printTemplateBase("graphviz.tmpl", graphvizTemplateStr, "schema.dot", database, collection, &input, outputDir)
+}
+
+func newPumlTemplateInput(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) PumlTemplateInput {
 	typeRelations := make([]TypeRelation, 0)
-	for _, e := range mainType.Properties {
-		if (e.IsComplex) && (!slices.ContainsFunc(typeRelations, func(v TypeRelation) bool {
-			return (v.Start == mainType.Name) && (v.End == e.ValueType)
-		})) {
-			typeRelations = append(typeRelations, NewTypeRelation(mainType.Name, e.ValueType))
-		}
+	for i := range mainType.Properties {
+		typeRelations = addTypeRelations(typeRelations, mainType.Name, &mainType.Properties[i])
 	}
 
 	for _, eo := range otherComplexTypes {
-		for _, ep := range eo.Properties {
-			if (ep.IsComplex) && (!slices.ContainsFunc(typeRelations, func(v TypeRelation) bool {
-				return (v.Start == eo.Name) && (v.End == ep.ValueType)
-			})) {
-				typeRelations = append(typeRelations, NewTypeRelation(eo.Name, ep.ValueType))
-			}
+		// inherited attributes are already covered by the relations of the base type
+		props := ownProperties(eo, mainType)
+		for i := range props {
+			typeRelations = addTypeRelations(typeRelations, eo.Name, &props[i])
 		}
 	}
 
-	input := PumlTemplateInput{
+	enums := addEnums(make([]EnumType, 0), mainType, mainType.Properties)
+	for i := range otherComplexTypes {
+		enums = addEnums(enums, &otherComplexTypes[i], ownProperties(otherComplexTypes[i], mainType))
+	}
+
+	return PumlTemplateInput{
 		MainType:          mainType,
 		OtherComplexTypes: otherComplexTypes,
 		Relations:         typeRelations,
+		Enums:             enums,
 		Database:          database,
 		Collection:        collection,
 	}
-	printTemplateBase("plantuml.tmpl", pumlTemplateStr, "schema.puml", database, collection, &input, outputDir)
 }
 
-func printTemplateBase(templateName string, templateStr string, fileExt string, database string, collection string, input interface{}, outputDir string) {
-	tmpl := template.Must(template.New(templateName).Funcs(template.FuncMap{
-		"LastIndexProps": lastIndexProps, "LastIndexTypes": lastIndexTypes,
-	}).Parse(templateStr))
+func printTemplateBase(templateName string, templateStr string, fileExt string, database string, collection string, input interface{}, outputDir string) error {
+	tmpl, err := template.New(templateName).Funcs(templateFuncs()).Parse(templateStr)
+	if err != nil {
+		return fmt.Errorf("failed to parse template '%s': %v", templateName, err)
+	}
+	if outputDir == "stdout" {
+		return executeTemplate(tmpl, os.Stdout, input)
+	}
+	outputFile, err := utils.CreateOutputFile(outputDir, fileExt, database, collection)
+	if err != nil {
+		return fmt.Errorf("failed to create %s file: %v", fileExt, err)
+	}
+	defer outputFile.Close()
+	return executeTemplate(tmpl, outputFile, input)
+}
 
+// like printTemplateBase, but for outputs that aren't bound to a collection, e.g. 'index.html'
+func printTemplateToFile(templateName string, templateStr string, fileName string, input interface{}, outputDir string) error {
+	tmpl, err := template.New(templateName).Funcs(templateFuncs()).Parse(templateStr)
+	if err != nil {
+		return fmt.Errorf("failed to parse template '%s': %v", templateName, err)
+	}
 	if outputDir == "stdout" {
-		err := tmpl.Execute(os.Stdout, input)
-		if err != nil {
-			panic(err)
-		}
-	} else {
-		outputFile, err := utils.CreateOutputFile(outputDir, fileExt, database, collection)
-		if err != nil {
-			panic(err)
-		}
-		defer outputFile.Close()
-		err = tmpl.Execute(outputFile, input)
-		if err != nil {
-			panic(err)
-		}
+		return executeTemplate(tmpl, os.Stdout, input)
+	}
+	outputFile, err := os.Create(filepath.Join(outputDir, fileName))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", fileName, err)
+	}
+	defer outputFile.Close()
+	return executeTemplate(tmpl, outputFile, input)
+}
+
+func executeTemplate(tmpl *template.Template, w io.Writer, input interface{}) error {
+	if err := tmpl.Execute(w, input); err != nil {
+		return fmt.Errorf("failed to render template '%s': %v", tmpl.Name(), err)
 	}
+	return nil
 }
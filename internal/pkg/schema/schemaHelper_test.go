@@ -0,0 +1,178 @@
+package schema
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	linkshelper "okieoth/schemaguesser/internal/pkg/linksHelper"
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// guesses the schema of the documents in the same steps like 'get schema' does. Documents of type
+// bson.D keep the order of their attributes, that's needed for golden outputs
+func guessTestSchema[D bson.M | bson.D](t *testing.T, collName string, discriminator string, docs []D) (*mongoHelper.ComplexType, []mongoHelper.ComplexType) {
+	var mainType mongoHelper.ComplexType
+	otherComplexTypes := make([]mongoHelper.ComplexType, 0)
+	raws := make([]bson.Raw, 0)
+	for _, d := range docs {
+		b, err := bson.Marshal(d)
+		require.Nil(t, err)
+		raws = append(raws, b)
+	}
+	var err error
+	if discriminator != "" {
+		otherComplexTypes, err = mongoHelper.ProcessBsonWithDiscriminator(raws, collName, discriminator, &mainType, otherComplexTypes)
+		require.Nil(t, err)
+	} else {
+		for _, b := range raws {
+			otherComplexTypes, err = mongoHelper.ProcessBson(b, collName, &mainType, otherComplexTypes)
+			require.Nil(t, err)
+		}
+	}
+	otherComplexTypes = ReduceTypes(&mainType, otherComplexTypes)
+	otherComplexTypes = GuessDicts(&mainType, otherComplexTypes)
+	otherComplexTypes = ReduceDoubleTypesByName(otherComplexTypes)
+	GuessEnums(&mainType, otherComplexTypes)
+	ApplyNaming(collName, &mainType, otherComplexTypes, nil)
+	return &mainType, otherComplexTypes
+}
+
+var updateGolden = flag.Bool("update", false, "rewrites the golden files in resources/golden")
+
+// compares the output with the content of the golden file in 'resources/golden'. With '-update' the
+// golden file is written instead
+func assertGolden(t *testing.T, fileName string, actual string) {
+	goldenFile := filepath.Join("..", "..", "..", "resources", "golden", fileName)
+	if *updateGolden {
+		require.Nil(t, os.MkdirAll(filepath.Dir(goldenFile), 0755))
+		require.Nil(t, os.WriteFile(goldenFile, []byte(actual), 0644))
+	}
+	expected, err := os.ReadFile(goldenFile)
+	require.Nil(t, err)
+	assert.Equal(t, string(expected), actual)
+}
+
+// documents of a collection with variants, nested types, a dictionary, a tuple, optional, nullable
+// and polymorphic attributes, enums and some bson types
+func goldenTestDocs() []bson.D {
+	id1, _ := primitive.ObjectIDFromHex("65a1b2c3d4e5f60718293a4b")
+	id2, _ := primitive.ObjectIDFromHex("65a1b2c3d4e5f60718293a4c")
+	amount, _ := primitive.ParseDecimal128("12.50")
+	created := primitive.NewDateTimeFromTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	return []bson.D{
+		{
+			{Key: "_id", Value: id1},
+			{Key: "type", Value: "online"},
+			{Key: "status", Value: "open"},
+			{Key: "customer", Value: bson.D{{Key: "name", Value: "Ann"}, {Key: "email", Value: "ann@example.com"}, {Key: "x-tag", Value: "vip"}}},
+			{Key: "amount", Value: amount},
+			{Key: "created", Value: created},
+			{Key: "items", Value: bson.A{bson.D{{Key: "sku", Value: "a-1"}, {Key: "qty", Value: int32(1)}}}},
+			{Key: "position", Value: bson.A{"a", int32(1)}},
+			{Key: "prices", Value: bson.D{
+				{Key: "0b8f2d5e-6a4c-4f1e-9b3a-2c7d8e9f0a1b", Value: 1.5},
+				{Key: "1c9a3e6f-7b5d-4a2f-8c4b-3d8e9f0a1b2c", Value: 2.5},
+			}},
+			{Key: "note", Value: nil},
+			{Key: "ref", Value: "A-1"},
+			{Key: "url", Value: "https://example.com/a"},
+		},
+		{
+			{Key: "_id", Value: id2},
+			{Key: "type", Value: "store"},
+			{Key: "status", Value: "closed"},
+			{Key: "customer", Value: bson.D{{Key: "name", Value: "Bob"}, {Key: "email", Value: "bob@example.com"}}},
+			{Key: "amount", Value: amount},
+			{Key: "created", Value: created},
+			{Key: "items", Value: bson.A{bson.D{{Key: "sku", Value: "b-2"}, {Key: "qty", Value: int32(2)}}}},
+			{Key: "position", Value: bson.A{"b", int32(2)}},
+			{Key: "prices", Value: bson.D{
+				{Key: "2d0b4f7a-8c6e-4b3a-9d5c-4e9f0a1b2c3d", Value: 3.5},
+			}},
+			{Key: "note", Value: "call first"},
+			{Key: "ref", Value: int32(7)},
+			{Key: "store", Value: bson.D{{Key: "city", Value: "Berlin"}}},
+			{Key: "raw", Value: primitive.Binary{Subtype: 0, Data: []byte{1, 2}}},
+		},
+		{
+			{Key: "_id", Value: id1},
+			{Key: "type", Value: "online"},
+			{Key: "status", Value: "open"},
+			{Key: "customer", Value: bson.D{{Key: "name", Value: "Cid"}, {Key: "email", Value: "cid@example.com"}}},
+			{Key: "amount", Value: amount},
+			{Key: "created", Value: created},
+			{Key: "items", Value: bson.A{}},
+			{Key: "position", Value: bson.A{"c", int32(3)}},
+			{Key: "prices", Value: bson.D{
+				{Key: "3e1c5a8b-9d7f-4c4b-8e6d-5f0a1b2c3d4e", Value: 4.5},
+			}},
+			{Key: "ref", Value: "A-3"},
+			{Key: "url", Value: "https://example.com/c"},
+		},
+	}
+}
+
+// the schema of the golden test documents, with the discriminator 'type'
+func goldenTestSchema(t *testing.T) (*mongoHelper.ComplexType, []mongoHelper.ComplexType) {
+	oldEnumMinSamples := EnumMinSamples
+	EnumMinSamples = 2
+	defer func() { EnumMinSamples = oldEnumMinSamples }()
+	return guessTestSchema(t, "orders", "type", goldenTestDocs())
+}
+
+// reads a file that was written to the output dir of a test
+func readTestOutput(t *testing.T, outputDir string, fileName string) string {
+	content, err := os.ReadFile(filepath.Join(outputDir, fileName))
+	require.Nil(t, err)
+	return string(content)
+}
+
+// schemas of two collections with special characters in attribute names and descriptions, and the
+// links between them and to a collection of another database
+func linkedTestSchemas(t *testing.T) ([]PersistedSchema, []linkshelper.ColRefs) {
+	customersMain, customersOthers := guessTestSchema(t, "customers", "", []bson.D{
+		{{Key: "_id", Value: int32(1)}, {Key: "name (full)", Value: "Ann"}, {Key: "<note>", Value: "a|b"}, {Key: "size~{cm}", Value: 1.5}, {Key: "address", Value: bson.D{{Key: "city", Value: "Berlin"}}}},
+		{{Key: "_id", Value: int32(2)}, {Key: "name (full)", Value: "Bob"}, {Key: "size~{cm}", Value: int32(2)}, {Key: "address", Value: bson.D{{Key: "city", Value: "Paris"}}}},
+	})
+	customersMain.Description = "Customers with *VIP* status | <b>bold</b> & more"
+	customersMain.Properties[1].Description = "Full name_with `ticks`\nand a second line"
+	ordersMain, ordersOthers := guessTestSchema(t, "orders", "", []bson.D{
+		{{Key: "_id", Value: "o1"}, {Key: "customerId", Value: int32(1)}, {Key: "items", Value: bson.A{bson.D{{Key: "productId", Value: "p1"}, {Key: "qty", Value: int32(1)}}}}},
+		{{Key: "_id", Value: "o2"}, {Key: "customerId", Value: int32(2)}, {Key: "items", Value: bson.A{bson.D{{Key: "productId", Value: "p2"}, {Key: "qty", Value: int32(3)}}}}},
+	})
+	schemas := []PersistedSchema{
+		{Database: "shop", Collection: "customers", MainType: customersMain, OtherComplexTypes: customersOthers},
+		{Database: "shop", Collection: "orders", MainType: ordersMain, OtherComplexTypes: ordersOthers},
+	}
+	colRefs := []linkshelper.ColRefs{
+		{Db: "shop", Collection: "orders", AttribRefs: []linkshelper.AttribRef{
+			{AttribStr: "customerId", References: []linkshelper.AttribRefDetails{{Db: "shop", Collection: "customers", Attributes: []string{"_id"}}}},
+			{AttribStr: "items_sub-productId", References: []linkshelper.AttribRefDetails{{Db: "crm", Collection: "products", Attributes: []string{"sku"}}}},
+		}},
+	}
+	return schemas, colRefs
+}
+
+// attributes with int and long values aren't polymorphic, so they are still enum candidates
+func TestGuessEnumsIntAndLong(t *testing.T) {
+	oldEnumMinSamples := EnumMinSamples
+	EnumMinSamples = 2
+	defer func() { EnumMinSamples = oldEnumMinSamples }()
+	mainType, _ := guessTestSchema(t, "test", "", []bson.D{
+		{{Key: "level", Value: int32(1)}},
+		{{Key: "level", Value: int64(2)}},
+		{{Key: "level", Value: int32(1)}},
+	})
+	level := mainType.Properties[0]
+	assert.False(t, mongoHelper.IsPolymorphic(&level))
+	assert.True(t, level.IsEnum)
+	assert.Equal(t, []string{"1", "2"}, level.DistinctValues)
+}
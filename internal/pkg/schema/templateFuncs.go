@@ -0,0 +1,208 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// functions that are available in the built-in and in the user provided templates
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"LastIndexProps": lastIndexProps, "LastIndexTypes": lastIndexTypes,
+		"RequiredProps": requiredProps, "IsOptional": isOptional, "PresenceRatio": presenceRatio,
+		"IsPolymorphic": isPolymorphic, "ScalarUnionTypes": scalarUnionTypes, "BsonTypes": bsonTypes,
+		"UnionTypeName": unionTypeName, "IsArrayUnion": isArrayUnion, "UnionItemTypes": unionItemTypes,
+		"NestedArrayLevels": nestedArrayLevels, "ArrayMarker": arrayMarker,
+		"EnumTypeName": enumTypeName, "UnionNullable": unionNullable,
+		"Variants": variants, "DiscriminatorMapping": discriminatorMapping, "OwnProperties": ownProperties,
+		"JsonString": jsonString,
+		"SchemaUri":  schemaUri, "SchemaId": schemaId, "IsDraft2020": isDraft2020,
+		"DefsKeyword": defsKeyword, "RefPrefix": refPrefix, "IsTuple": isTuple,
+
+		// case conversion
+		"ToUpper": strings.ToUpper, "ToLower": strings.ToLower,
+		"UpperFirst": upperFirst, "LowerFirst": lowerFirst,
+		"CamelCase": camelCase, "PascalCase": pascalCase,
+		"SnakeCase": snakeCase, "ScreamingSnakeCase": screamingSnakeCase, "KebabCase": kebabCase,
+		// string helpers
+		"Join": strings.Join, "Replace": strings.ReplaceAll, "HasPrefix": strings.HasPrefix, "HasSuffix": strings.HasSuffix,
+		// JSON escaping
+		"JsonEscape": jsonEscape, "ToJson": toJson,
+		// type mapping
+		"Dict": dict, "MapType": mapType, "ComplexTypeByName": getComplexTypeByName,
+		// mermaid
+		"MermaidName": mermaidName, "MermaidText": mermaidText, "MermaidErWord": mermaidErWord,
+		// plantuml
+		"PumlText": pumlText,
+		// markdown
+		"MarkdownText": markdownText, "MarkdownCode": markdownCode,
+	}
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	runes := []rune(s)
+	return string(unicode.ToUpper(runes[0])) + string(runes[1:])
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	runes := []rune(s)
+	return string(unicode.ToLower(runes[0])) + string(runes[1:])
+}
+
+// splits names like 'orderItems', 'order_items', 'HTTPServer' or 'order-items2' into words
+func splitWords(s string) []string {
+	ret := make([]string, 0)
+	runes := []rune(s)
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				ret = append(ret, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+		prev := runes[i-1]
+		newWord := unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev))
+		// the last upper case letter of an acronym starts the next word, e.g. 'HTTPServer'
+		if !newWord && unicode.IsUpper(r) && unicode.IsUpper(prev) && (i+1 < len(runes)) && unicode.IsLower(runes[i+1]) {
+			newWord = true
+		}
+		if newWord {
+			ret = append(ret, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start >= 0 {
+		ret = append(ret, string(runes[start:]))
+	}
+	return ret
+}
+
+func camelCase(s string) string {
+	return lowerFirst(pascalCase(s))
+}
+
+func pascalCase(s string) string {
+	var sb strings.Builder
+	for _, w := range splitWords(s) {
+		sb.WriteString(upperFirst(strings.ToLower(w)))
+	}
+	return sb.String()
+}
+
+func snakeCase(s string) string {
+	return strings.ToLower(strings.Join(splitWords(s), "_"))
+}
+
+func screamingSnakeCase(s string) string {
+	return strings.ToUpper(strings.Join(splitWords(s), "_"))
+}
+
+func kebabCase(s string) string {
+	return strings.ToLower(strings.Join(splitWords(s), "-"))
+}
+
+// returns the string escaped for the usage inside of a JSON string, without the quotes
+func jsonEscape(s string) string {
+	quoted := jsonString(s)
+	return quoted[1 : len(quoted)-1]
+}
+
+func toJson(v any) (string, error) {
+	ret, err := marshalJson(v)
+	if err != nil {
+		return "", err
+	}
+	return string(ret), nil
+}
+
+// creates a map out of key value pairs, e.g. to use it as type mapping: Dict "string" "String" "integer" "Long"
+func dict(pairs ...string) (map[string]string, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("Dict needs key value pairs, got an odd number of arguments: %d", len(pairs))
+	}
+	ret := make(map[string]string)
+	for i := 0; i < len(pairs); i += 2 {
+		ret[pairs[i]] = pairs[i+1]
+	}
+	return ret, nil
+}
+
+// maps the type name with the given mapping. Types without a mapping fall back to the
+// entry '*', if it exists, otherwise the type name is returned unchanged
+func mapType(mapping map[string]string, typeName string) string {
+	if ret, ok := mapping[typeName]; ok {
+		return ret
+	}
+	if ret, ok := mapping["*"]; ok {
+		return ret
+	}
+	return typeName
+}
+
+// class names with other characters than letters, digits and '_' are quoted with backticks in mermaid
+func mermaidName(name string) string {
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && (r != '_') {
+			return "`" + strings.ReplaceAll(name, "`", "'") + "`"
+		}
+	}
+	return name
+}
+
+// mermaid treats class members with parentheses as methods and curly braces end the class body,
+// so they are replaced by square brackets. Angle brackets would be rendered as HTML tags, mermaid
+// writes them as entity codes
+var mermaidReplacer = strings.NewReplacer("(", "[", ")", "]", "{", "[", "}", "]", "~", "-", "<", "#lt;", ">", "#gt;")
+
+func mermaidText(s string) string {
+	return mermaidReplacer.Replace(s)
+}
+
+// plantuml treats class members with parentheses as methods, curly braces end the class body and
+// line breaks start a new member, so they are replaced like in mermaid
+var pumlReplacer = strings.NewReplacer("(", "[", ")", "]", "{", "[", "}", "]", "\n", " ", "\r", "")
+
+func pumlText(s string) string {
+	return pumlReplacer.Replace(s)
+}
+
+// pipes end the cells of markdown tables and line breaks end the tables, the other characters are markup
+var markdownReplacer = strings.NewReplacer("|", "\\|", "\n", " ", "\r", "", "<", "&lt;", ">", "&gt;", "*", "\\*", "_", "\\_", "`", "\\`")
+
+func markdownText(s string) string {
+	return markdownReplacer.Replace(s)
+}
+
+// text of a code span in a markdown table, backticks can't be escaped in code spans
+func markdownCode(s string) string {
+	return "`" + strings.NewReplacer("|", "\\|", "\n", " ", "\r", "", "`", "'").Replace(s) + "`"
+}
+
+// attribute names and types of mermaid ER diagrams are words, that can contain letters, digits, '_',
+// '-', '[]' and '()'. Other characters are replaced by '_'
+func mermaidErWord(s string) string {
+	ret := []rune(s)
+	for i, r := range ret {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_-[]()", r) {
+			ret[i] = '_'
+		}
+	}
+	if (len(ret) == 0) || !(unicode.IsLetter(ret[0]) || (ret[0] == '_')) {
+		return "_" + string(ret)
+	}
+	return string(ret)
+}
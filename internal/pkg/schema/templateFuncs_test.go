@@ -0,0 +1,112 @@
+package schema
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func executeTestTemplate(t *testing.T, templateStr string, input any) (string, error) {
+	tmpl, err := template.New("test.tmpl").Funcs(templateFuncs()).Parse(templateStr)
+	require.Nil(t, err)
+	var sb strings.Builder
+	err = tmpl.Execute(&sb, input)
+	return sb.String(), err
+}
+
+func TestCaseConversion(t *testing.T) {
+	tests := []struct {
+		input              string
+		camelCase          string
+		pascalCase         string
+		snakeCase          string
+		screamingSnakeCase string
+		kebabCase          string
+	}{
+		{"orderItems", "orderItems", "OrderItems", "order_items", "ORDER_ITEMS", "order-items"},
+		{"order_items", "orderItems", "OrderItems", "order_items", "ORDER_ITEMS", "order-items"},
+		{"HTTPServer", "httpServer", "HttpServer", "http_server", "HTTP_SERVER", "http-server"},
+		{"order-items2", "orderItems2", "OrderItems2", "order_items2", "ORDER_ITEMS2", "order-items2"},
+		{"item2Count", "item2Count", "Item2Count", "item2_count", "ITEM2_COUNT", "item2-count"},
+		{"_id", "id", "Id", "id", "ID", "id"},
+		{"größeInCm", "größeInCm", "GrößeInCm", "größe_in_cm", "GRÖßE_IN_CM", "größe-in-cm"},
+		{"", "", "", "", "", ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.camelCase, camelCase(test.input), "camelCase: %s", test.input)
+		assert.Equal(t, test.pascalCase, pascalCase(test.input), "pascalCase: %s", test.input)
+		assert.Equal(t, test.snakeCase, snakeCase(test.input), "snakeCase: %s", test.input)
+		assert.Equal(t, test.screamingSnakeCase, screamingSnakeCase(test.input), "screamingSnakeCase: %s", test.input)
+		assert.Equal(t, test.kebabCase, kebabCase(test.input), "kebabCase: %s", test.input)
+	}
+	assert.Equal(t, "ÄBc", upperFirst("äBc"))
+	assert.Equal(t, "äBc", lowerFirst("ÄBc"))
+	assert.Equal(t, "", upperFirst(""))
+
+	out, err := executeTestTemplate(t, `{{ PascalCase . }} {{ SnakeCase . }} {{ UpperFirst . }}`, "orderItems")
+	require.Nil(t, err)
+	assert.Equal(t, "OrderItems order_items OrderItems", out)
+}
+
+func TestJsonEscapeAndToJson(t *testing.T) {
+	// JsonEscape escapes HTML characters like json.Marshal does, ToJson keeps them
+	assert.Equal(t, `say \"hi\"\n\u003cb\u003e \u0026 \\`, jsonEscape("say \"hi\"\n<b> & \\"))
+	assert.Equal(t, "", jsonEscape(""))
+
+	out, err := executeTestTemplate(t, `{"description": "{{ JsonEscape .Text }}", "values": {{ ToJson .Values }}}`, map[string]any{
+		"Text":   `a "b" <c>`,
+		"Values": []any{"x&y", 1, nil},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, `{"description": "a \"b\" \u003cc\u003e", "values": ["x&y",1,null]}`, out)
+
+	_, err = toJson(math.Inf(1))
+	assert.NotNil(t, err)
+}
+
+func TestDictAndMapType(t *testing.T) {
+	mapping, err := dict("string", "String", "integer", "Long")
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"string": "String", "integer": "Long"}, mapping)
+	assert.Equal(t, "Long", mapType(mapping, "integer"))
+	// types without mapping are returned unchanged
+	assert.Equal(t, "boolean", mapType(mapping, "boolean"))
+	mapping["*"] = "Object"
+	assert.Equal(t, "Object", mapType(mapping, "boolean"))
+
+	_, err = dict("string", "String", "integer")
+	assert.NotNil(t, err)
+
+	out, err := executeTestTemplate(t, `{{ $m := Dict "string" "String" "*" "Object" }}{{ range . }}{{ MapType $m . }} {{ end }}`,
+		[]string{"string", "integer"})
+	require.Nil(t, err)
+	assert.Equal(t, "String Object ", out)
+
+	// the error of a wrong number of arguments stops the rendering
+	_, err = executeTestTemplate(t, `{{ $m := Dict "string" }}{{ MapType $m "string" }}`, nil)
+	assert.ErrorContains(t, err, "odd number of arguments")
+}
+
+func TestPrintTemplateErrors(t *testing.T) {
+	outputDir := t.TempDir()
+	err := printTemplateBase("broken.tmpl", `{{ .Missing.Attrib }}`, "txt", "shop", "orders", &DictIndexInput{}, outputDir)
+	assert.ErrorContains(t, err, "failed to render template 'broken.tmpl'")
+
+	err = printTemplateBase("syntax.tmpl", `{{ if }}`, "txt", "shop", "orders", nil, outputDir)
+	assert.ErrorContains(t, err, "failed to parse template 'syntax.tmpl'")
+
+	err = printTemplateToFile("dict.tmpl", `{{ Dict "a" }}`, "index.txt", nil, outputDir)
+	assert.ErrorContains(t, err, "odd number of arguments")
+
+	err = printTemplateToFile("ok.tmpl", `{{ .Title }}`, "index.txt", &DictIndexInput{Title: "Title"}, outputDir)
+	require.Nil(t, err)
+	content, err := os.ReadFile(filepath.Join(outputDir, "index.txt"))
+	require.Nil(t, err)
+	assert.Equal(t, "Title", string(content))
+}
@@ -0,0 +1,363 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+)
+
+// if set, zod schemas are created additionally to the typescript interfaces
+var TsZod bool
+
+// if set, the typescript types use the classes of the 'bson' package (ObjectId, Decimal128, ...) and
+// Date, like the MongoDB node driver returns them. Otherwise the types of the JSON representation are used
+var TsBsonTypes bool
+
+var tsIdentifierRegex = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+type tsTypeMapping struct {
+	tsType  string
+	zodType string
+}
+
+// types of the 'bson' package, for the BSON types without JSON counterpart
+var tsBsonClasses = map[string]tsTypeMapping{
+	"objectId":            {"ObjectId", "z.instanceof(ObjectId)"},
+	"decimal":             {"Decimal128", "z.instanceof(Decimal128)"},
+	"binData":             {"Binary", "z.instanceof(Binary)"},
+	"timestamp":           {"Timestamp", "z.instanceof(Timestamp)"},
+	"javascript":          {"Code", "z.instanceof(Code)"},
+	"javascriptWithScope": {"Code", "z.instanceof(Code)"},
+	"minKey":              {"MinKey", "z.instanceof(MinKey)"},
+	"maxKey":              {"MaxKey", "z.instanceof(MaxKey)"},
+	"dbPointer":           {"DBRef", "z.instanceof(DBRef)"},
+}
+
+// zod checks for the detected string formats
+var zodStringFormats = map[string]string{
+	"uuid":      ".uuid()",
+	"email":     ".email()",
+	"uri":       ".url()",
+	"ipv4":      ".ip({ version: \"v4\" })",
+	"ipv6":      ".ip({ version: \"v6\" })",
+	"date-time": ".datetime({ offset: true })",
+}
+
+type tsGenerator struct {
+	// used classes of the 'bson' package
+	bsonImports []string
+}
+
+func tsPropName(name string) string {
+	if tsIdentifierRegex.MatchString(name) {
+		return name
+	}
+	return jsonString(name)
+}
+
+func (g *tsGenerator) bsonClass(bsonType string) (tsTypeMapping, bool) {
+	if !TsBsonTypes {
+		return tsTypeMapping{}, false
+	}
+	m, ok := tsBsonClasses[bsonType]
+	if ok && !slices.Contains(g.bsonImports, m.tsType) {
+		g.bsonImports = append(g.bsonImports, m.tsType)
+	}
+	return m, ok
+}
+
+// typescript and zod type of a single, not array value
+func (g *tsGenerator) baseType(t mongoHelper.ObservedType) (string, string) {
+	if t.IsComplex {
+		name := goIdentifier(t.ValueType)
+		return name, fmt.Sprintf("z.lazy(() => %sSchema)", name)
+	}
+	if m, ok := g.bsonClass(t.BsonType); ok {
+		return m.tsType, m.zodType
+	}
+	if TsBsonTypes && (t.BsonType == "date") {
+		return "Date", "z.date()"
+	}
+	switch t.ValueType {
+	case mongoHelper.STRING:
+		return "string", "z.string()" + zodStringFormats[t.Format]
+	case mongoHelper.INT:
+		return "number", "z.number().int()"
+	case mongoHelper.NUMBER:
+		return "number", "z.number()"
+	case "boolean":
+		return "boolean", "z.boolean()"
+	case mongoHelper.NULL:
+		return "null", "z.null()"
+	}
+	if t.BsonType == "objectId" {
+		// the JSON representation of an ObjectId
+		return "string", "z.string()"
+	}
+	return "unknown", "z.unknown()"
+}
+
+func tsArray(tsType string, zodType string, dims uint) (string, string) {
+	if (dims > 0) && strings.Contains(tsType, " ") {
+		tsType = "(" + tsType + ")"
+	}
+	for i := uint(0); i < dims; i++ {
+		tsType += "[]"
+		zodType = fmt.Sprintf("z.array(%s)", zodType)
+	}
+	return tsType, zodType
+}
+
+func (g *tsGenerator) observedType(t mongoHelper.ObservedType) (string, string) {
+	tsType, zodType := g.baseType(t)
+	return tsArray(tsType, zodType, t.ArrayDimensions)
+}
+
+func (g *tsGenerator) unionType(types []mongoHelper.ObservedType) (string, string) {
+	tsTypes := make([]string, 0)
+	zodTypes := make([]string, 0)
+	for _, t := range types {
+		tsType, zodType := g.observedType(t)
+		if !slices.Contains(tsTypes, tsType) {
+			tsTypes = append(tsTypes, tsType)
+			zodTypes = append(zodTypes, zodType)
+		}
+	}
+	if len(tsTypes) == 1 {
+		return tsTypes[0], zodTypes[0]
+	}
+	return strings.Join(tsTypes, " | "), fmt.Sprintf("z.union([%s])", strings.Join(zodTypes, ", "))
+}
+
+func enumType(prop *mongoHelper.BasicElemInfo) (string, string) {
+	literals := make([]string, 0)
+	zodLiterals := make([]string, 0)
+	for _, v := range prop.DistinctValues {
+		if prop.ValueType != mongoHelper.INT {
+			v = jsonString(v)
+		}
+		literals = append(literals, v)
+		zodLiterals = append(zodLiterals, fmt.Sprintf("z.literal(%s)", v))
+	}
+	if len(literals) == 1 {
+		return literals[0], zodLiterals[0]
+	}
+	if prop.ValueType != mongoHelper.INT {
+		return strings.Join(literals, " | "), fmt.Sprintf("z.enum([%s])", strings.Join(literals, ", "))
+	}
+	return strings.Join(literals, " | "), fmt.Sprintf("z.union([%s])", strings.Join(zodLiterals, ", "))
+}
+
+// typescript and zod type of an attribute, without the optional marker
+func (g *tsGenerator) propType(prop *mongoHelper.BasicElemInfo) (string, string) {
+	var tsType, zodType string
+	switch {
+	case mongoHelper.IsTuple(prop):
+		tsTypes := make([]string, 0)
+		zodTypes := make([]string, 0)
+		for _, t := range prop.TupleItems {
+			tsType, zodType := g.observedType(t)
+			tsTypes = append(tsTypes, tsType)
+			zodTypes = append(zodTypes, zodType)
+		}
+		tsType = "[" + strings.Join(tsTypes, ", ") + "]"
+		zodType = fmt.Sprintf("z.tuple([%s])", strings.Join(zodTypes, ", "))
+	case mongoHelper.IsPolymorphic(prop):
+		if isArrayUnion(*prop) {
+			itemType, itemZodType := g.unionType(unionItemTypes(*prop))
+			tsType, zodType = tsArray(itemType, itemZodType, 1)
+		} else {
+			tsType, zodType = g.unionType(prop.Types)
+		}
+	case prop.IsArray && (prop.BsonType == mongoHelper.EMPTY_ARRAY_BSON_TYPE):
+		tsType, zodType = tsArray("unknown", "z.unknown()", prop.ArrayDimensions)
+	case prop.IsEnum && (len(prop.DistinctValues) > 0):
+		itemType, itemZodType := enumType(prop)
+		tsType, zodType = tsArray(itemType, itemZodType, prop.ArrayDimensions)
+	default:
+		tsType, zodType = g.observedType(mongoHelper.ObservedType{
+			ValueType:       prop.ValueType,
+			BsonType:        prop.BsonType,
+			Format:          prop.Format,
+			IsComplex:       prop.IsComplex,
+			ArrayDimensions: prop.ArrayDimensions,
+		})
+	}
+	if prop.IsNullable && (tsType != "null") {
+		tsType += " | null"
+		zodType += ".nullable()"
+	}
+	return tsType, zodType
+}
+
+func writeTsDoc(sb *strings.Builder, indent string, lines ...string) {
+	docLines := make([]string, 0)
+	for _, l := range lines {
+		for _, s := range strings.Split(l, "\n") {
+			if s = strings.TrimSpace(s); s != "" {
+				docLines = append(docLines, strings.ReplaceAll(s, "*/", "* /"))
+			}
+		}
+	}
+	switch len(docLines) {
+	case 0:
+	case 1:
+		sb.WriteString(fmt.Sprintf("%s/** %s */\n", indent, docLines[0]))
+	default:
+		sb.WriteString(indent + "/**\n")
+		for _, l := range docLines {
+			sb.WriteString(fmt.Sprintf("%s * %s\n", indent, l))
+		}
+		sb.WriteString(indent + " */\n")
+	}
+}
+
+type tsProp struct {
+	name     string
+	tsType   string
+	zodType  string
+	optional bool
+	doc      []string
+}
+
+func isVariantOf(complexType *mongoHelper.ComplexType, mainType *mongoHelper.ComplexType) bool {
+	return (complexType.BaseType != "") && (complexType.BaseType == mainType.Name)
+}
+
+// Attributes of the type, variants only return their own attributes and narrow the type of the
+// inherited discriminator to their discriminator value
+func (g *tsGenerator) props(complexType *mongoHelper.ComplexType, mainType *mongoHelper.ComplexType) []tsProp {
+	props := complexType.Properties
+	ret := make([]tsProp, 0)
+	if isVariantOf(complexType, mainType) {
+		props = ownProperties(*complexType, mainType)
+		if (complexType.DiscriminatorValue != "") && containsProp(mainType.Discriminator, mainType) {
+			discriminatorValue := jsonString(complexType.DiscriminatorValue)
+			ret = append(ret, tsProp{
+				name:    tsPropName(mainType.Discriminator),
+				tsType:  discriminatorValue,
+				zodType: fmt.Sprintf("z.literal(%s)", discriminatorValue),
+			})
+		}
+	}
+	for i := range props {
+		p := &props[i]
+		tsType, zodType := g.propType(p)
+		ret = append(ret, tsProp{
+			name:     tsPropName(p.AttribName),
+			tsType:   tsType,
+			zodType:  zodType,
+			optional: isOptional(*p, complexType.SampleCount),
+			doc:      []string{p.Title, p.Description, p.Comment},
+		})
+	}
+	return ret
+}
+
+func (g *tsGenerator) writeInterface(sb *strings.Builder, complexType *mongoHelper.ComplexType, mainType *mongoHelper.ComplexType, doc string) {
+	typeName := goIdentifier(complexType.Name)
+	sb.WriteString("\n")
+	writeTsDoc(sb, "", doc, complexType.Title, complexType.Description)
+	if complexType.IsDictionary {
+		valueType := "unknown"
+		if complexType.DictValue != nil {
+			valueType, _ = g.propType(complexType.DictValue)
+		}
+		sb.WriteString(fmt.Sprintf("export type %s = Record<string, %s>;\n", typeName, valueType))
+		return
+	}
+	if isVariantOf(complexType, mainType) {
+		sb.WriteString(fmt.Sprintf("export interface %s extends %s {\n", typeName, goIdentifier(mainType.Name)))
+	} else {
+		sb.WriteString(fmt.Sprintf("export interface %s {\n", typeName))
+	}
+	for _, p := range g.props(complexType, mainType) {
+		writeTsDoc(sb, "  ", p.doc...)
+		optional := ""
+		if p.optional {
+			optional = "?"
+		}
+		sb.WriteString(fmt.Sprintf("  %s%s: %s;\n", p.name, optional, p.tsType))
+	}
+	sb.WriteString("}\n")
+}
+
+func (g *tsGenerator) writeZodSchema(sb *strings.Builder, complexType *mongoHelper.ComplexType, mainType *mongoHelper.ComplexType) {
+	typeName := goIdentifier(complexType.Name)
+	sb.WriteString("\n")
+	if complexType.IsDictionary {
+		valueType := "z.unknown()"
+		if complexType.DictValue != nil {
+			_, valueType = g.propType(complexType.DictValue)
+		}
+		sb.WriteString(fmt.Sprintf("export const %sSchema: z.ZodType<%s> = z.record(z.string(), %s);\n", typeName, typeName, valueType))
+		return
+	}
+	writeShape := func() {
+		for _, p := range g.props(complexType, mainType) {
+			optional := ""
+			if p.optional {
+				optional = ".optional()"
+			}
+			sb.WriteString(fmt.Sprintf("  %s: %s%s,\n", p.name, p.zodType, optional))
+		}
+	}
+	if (complexType == mainType) && (mainType.Discriminator != "") {
+		// the variants extend the shape of the main type
+		sb.WriteString(fmt.Sprintf("const %sShape = {\n", typeName))
+		writeShape()
+		sb.WriteString("};\n\n")
+		sb.WriteString(fmt.Sprintf("export const %sSchema: z.ZodType<%s> = z.object(%sShape);\n", typeName, typeName, typeName))
+		return
+	}
+	sb.WriteString(fmt.Sprintf("export const %sSchema: z.ZodType<%s> = z.object({\n", typeName, typeName))
+	if isVariantOf(complexType, mainType) {
+		sb.WriteString(fmt.Sprintf("  ...%sShape,\n", goIdentifier(mainType.Name)))
+	}
+	writeShape()
+	sb.WriteString("});\n")
+}
+
+// Creates typescript interfaces (and optional zod schemas) for the main type and all other complex types of a collection
+func TypeScript(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) string {
+	g := tsGenerator{bsonImports: make([]string, 0)}
+	var body strings.Builder
+	mainDoc := fmt.Sprintf("Storage model for database: %s, collection: %s", database, collection)
+	g.writeInterface(&body, mainType, mainType, mainDoc)
+	for i := range otherComplexTypes {
+		t := &otherComplexTypes[i]
+		var doc string
+		if t.DiscriminatorValue != "" {
+			doc = fmt.Sprintf("Variant for %s: %s", mainType.Discriminator, t.DiscriminatorValue)
+		}
+		g.writeInterface(&body, t, mainType, doc)
+	}
+	if TsZod {
+		g.writeZodSchema(&body, mainType, mainType)
+		for i := range otherComplexTypes {
+			g.writeZodSchema(&body, &otherComplexTypes[i], mainType)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("// Code generated by schemaguesser. DO NOT EDIT.\n")
+	if len(g.bsonImports) > 0 || TsZod {
+		sb.WriteString("\n")
+	}
+	if len(g.bsonImports) > 0 {
+		slices.Sort(g.bsonImports)
+		sb.WriteString(fmt.Sprintf("import { %s } from \"bson\";\n", strings.Join(g.bsonImports, ", ")))
+	}
+	if TsZod {
+		sb.WriteString("import { z } from \"zod\";\n")
+	}
+	sb.WriteString(body.String())
+	return sb.String()
+}
+
+func PrintTypeScript(database string, collection string, mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType, outputDir string) error {
+	return writeTextOutput(TypeScript(database, collection, mainType, otherComplexTypes), "ts", database, collection, outputDir)
+}
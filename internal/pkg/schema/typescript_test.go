@@ -0,0 +1,28 @@
+package schema
+
+import (
+	"testing"
+)
+
+func TestTypeScript(t *testing.T) {
+	mainType, otherComplexTypes := goldenTestSchema(t)
+	tests := []struct {
+		zod        bool
+		bsonTypes  bool
+		goldenFile string
+	}{
+		{false, false, "orders.ts"},
+		{false, true, "orders_bson.ts"},
+		{true, false, "orders_zod.ts"},
+		{true, true, "orders_zod_bson.ts"},
+	}
+	defer func() {
+		TsZod = false
+		TsBsonTypes = false
+	}()
+	for _, test := range tests {
+		TsZod = test.zod
+		TsBsonTypes = test.bsonTypes
+		assertGolden(t, test.goldenFile, TypeScript("shop", "orders", mainType, otherComplexTypes))
+	}
+}
@@ -0,0 +1,236 @@
+package schema
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+)
+
+var ValidationLevels = []string{"off", "strict", "moderate"}
+var ValidationActions = []string{"error", "warn"}
+
+func CheckValidationOptions(validationLevel string, validationAction string) error {
+	if !slices.Contains(ValidationLevels, validationLevel) {
+		return fmt.Errorf("unknown validation level '%s', possible values are: %v", validationLevel, ValidationLevels)
+	}
+	if !slices.Contains(ValidationActions, validationAction) {
+		return fmt.Errorf("unknown validation action '%s', possible values are: %v", validationAction, ValidationActions)
+	}
+	return nil
+}
+
+// name of the BSON type, like it's used by the 'bsonType' keyword of the MongoDB validators
+func validatorBsonType(bsonType string) string {
+	switch bsonType {
+	case "embeddedDocument - unofficial type":
+		return "object"
+	case mongoHelper.EMPTY_ARRAY_BSON_TYPE, "array type - unofficial type":
+		return "array"
+	}
+	return bsonType
+}
+
+func bsonTypeValue(nullable bool, bsonTypes ...string) interface{} {
+	if nullable && !slices.Contains(bsonTypes, mongoHelper.NULL) {
+		bsonTypes = append(bsonTypes, mongoHelper.NULL)
+	}
+	if len(bsonTypes) == 1 {
+		return bsonTypes[0]
+	}
+	ret := bson.A{}
+	for _, t := range bsonTypes {
+		ret = append(ret, t)
+	}
+	return ret
+}
+
+type validatorBuilder struct {
+	otherComplexTypes []mongoHelper.ComplexType
+	// complex types that are currently rendered, MongoDB validators don't support references
+	// so every type is embedded and recursive types stop at the second occurrence
+	typePath []string
+}
+
+func (b *validatorBuilder) objectSchema(typeName string, nullable bool) bson.D {
+	complexType, err := getComplexTypeByName(typeName, b.otherComplexTypes)
+	if err != nil || slices.Contains(b.typePath, typeName) {
+		if err != nil {
+			log.Printf("validator: can't find complex type '%s'", typeName)
+		}
+		return bson.D{{Key: "bsonType", Value: bsonTypeValue(nullable, "object")}}
+	}
+	b.typePath = append(b.typePath, typeName)
+	defer func() { b.typePath = b.typePath[:len(b.typePath)-1] }()
+	return b.complexTypeSchema(complexType, complexType.Properties, nullable)
+}
+
+func (b *validatorBuilder) complexTypeSchema(complexType *mongoHelper.ComplexType, props []mongoHelper.BasicElemInfo, nullable bool) bson.D {
+	ret := bson.D{{Key: "bsonType", Value: bsonTypeValue(nullable, "object")}}
+	if complexType.IsDictionary {
+		if complexType.DictValue != nil {
+			ret = append(ret, bson.E{Key: "additionalProperties", Value: b.propSchema(complexType.DictValue)})
+		}
+		return ret
+	}
+	required := bson.A{}
+	properties := bson.D{}
+	for i := range props {
+		p := &props[i]
+		if !isOptional(*p, complexType.SampleCount) {
+			required = append(required, p.AttribName)
+		}
+		properties = append(properties, bson.E{Key: p.AttribName, Value: b.propSchema(p)})
+	}
+	if len(required) > 0 {
+		ret = append(ret, bson.E{Key: "required", Value: required})
+	}
+	return append(ret, bson.E{Key: "properties", Value: properties})
+}
+
+func (b *validatorBuilder) observedTypeSchema(t mongoHelper.ObservedType) bson.D {
+	var ret bson.D
+	if t.IsComplex {
+		ret = b.objectSchema(t.ValueType, false)
+	} else {
+		ret = bson.D{{Key: "bsonType", Value: validatorBsonType(t.BsonType)}}
+	}
+	for i := uint(0); i < t.ArrayDimensions; i++ {
+		ret = bson.D{{Key: "bsonType", Value: "array"}, {Key: "items", Value: ret}}
+	}
+	return ret
+}
+
+func (b *validatorBuilder) unionSchema(types []mongoHelper.ObservedType, nullable bool) bson.D {
+	bsonTypes := make([]string, 0)
+	for _, t := range types {
+		if t.IsComplex || t.IsArray {
+			bsonTypes = nil
+			break
+		}
+		if n := validatorBsonType(t.BsonType); !slices.Contains(bsonTypes, n) {
+			bsonTypes = append(bsonTypes, n)
+		}
+	}
+	if bsonTypes != nil {
+		return bson.D{{Key: "bsonType", Value: bsonTypeValue(nullable, bsonTypes...)}}
+	}
+	anyOf := bson.A{}
+	for _, t := range types {
+		anyOf = append(anyOf, b.observedTypeSchema(t))
+	}
+	if nullable {
+		anyOf = append(anyOf, bson.D{{Key: "bsonType", Value: mongoHelper.NULL}})
+	}
+	return bson.D{{Key: "anyOf", Value: anyOf}}
+}
+
+func validatorEnum(prop *mongoHelper.BasicElemInfo) bson.A {
+	ret := bson.A{}
+	for _, v := range prop.DistinctValues {
+		if prop.ValueType == mongoHelper.INT {
+			i, _ := strconv.ParseInt(v, 10, 64)
+			ret = append(ret, i)
+		} else {
+			ret = append(ret, v)
+		}
+	}
+	if prop.IsNullable {
+		ret = append(ret, nil)
+	}
+	return ret
+}
+
+func (b *validatorBuilder) propSchema(prop *mongoHelper.BasicElemInfo) bson.D {
+	var ret bson.D
+	switch {
+	case mongoHelper.IsTuple(prop):
+		items := bson.A{}
+		for _, t := range prop.TupleItems {
+			items = append(items, b.observedTypeSchema(t))
+		}
+		ret = bson.D{{Key: "bsonType", Value: bsonTypeValue(prop.IsNullable, "array")}, {Key: "items", Value: items}}
+	case mongoHelper.IsPolymorphic(prop):
+		if isArrayUnion(*prop) {
+			ret = bson.D{{Key: "bsonType", Value: bsonTypeValue(prop.IsNullable, "array")}, {Key: "items", Value: b.unionSchema(unionItemTypes(*prop), false)}}
+		} else {
+			ret = b.unionSchema(prop.Types, prop.IsNullable)
+		}
+	case prop.IsArray:
+		ret = bson.D{{Key: "bsonType", Value: bsonTypeValue(prop.IsNullable, "array")}}
+		if validatorBsonType(prop.BsonType) != "array" {
+			ret = append(ret, bson.E{Key: "items", Value: b.observedTypeSchema(mongoHelper.ObservedType{
+				ValueType:       prop.ValueType,
+				BsonType:        prop.BsonType,
+				IsComplex:       prop.IsComplex,
+				ArrayDimensions: prop.ArrayDimensions - 1,
+			})})
+		}
+	case prop.IsComplex:
+		ret = b.objectSchema(prop.ValueType, prop.IsNullable)
+	default:
+		ret = bson.D{{Key: "bsonType", Value: bsonTypeValue(prop.IsNullable, validatorBsonType(prop.BsonType))}}
+		if prop.IsEnum {
+			ret = append(ret, bson.E{Key: "enum", Value: validatorEnum(prop)})
+		}
+	}
+	if prop.Title != "" {
+		ret = append(ret, bson.E{Key: "title", Value: prop.Title})
+	}
+	if prop.Description != "" {
+		ret = append(ret, bson.E{Key: "description", Value: prop.Description})
+	}
+	return ret
+}
+
+// Creates a MongoDB validator document ('$jsonSchema' with 'bsonType' keywords) for the collection.
+// The variants of a discriminated collection are added as 'anyOf' alternatives.
+func NewValidator(mainType *mongoHelper.ComplexType, otherComplexTypes []mongoHelper.ComplexType) bson.D {
+	b := validatorBuilder{otherComplexTypes: otherComplexTypes, typePath: []string{mainType.Name}}
+	jsonSchema := b.complexTypeSchema(mainType, mainType.Properties, false)
+	if mainType.Title != "" {
+		jsonSchema = append(jsonSchema, bson.E{Key: "title", Value: mainType.Title})
+	}
+	if mainType.Description != "" {
+		jsonSchema = append(jsonSchema, bson.E{Key: "description", Value: mainType.Description})
+	}
+	if mainType.Discriminator != "" {
+		anyOf := bson.A{}
+		for _, v := range variants(otherComplexTypes) {
+			variantSchema := b.complexTypeSchema(&v, ownProperties(v, mainType), false)
+			if v.DiscriminatorValue != "" {
+				// the properties are always the last entry of an object schema
+				last := len(variantSchema) - 1
+				variantSchema[last].Value = append(bson.D{
+					{Key: mainType.Discriminator, Value: bson.D{{Key: "enum", Value: bson.A{v.DiscriminatorValue}}}},
+				}, variantSchema[last].Value.(bson.D)...)
+			}
+			anyOf = append(anyOf, variantSchema)
+		}
+		if len(anyOf) > 0 {
+			jsonSchema = append(jsonSchema, bson.E{Key: "anyOf", Value: anyOf})
+		}
+	}
+	return bson.D{{Key: "$jsonSchema", Value: jsonSchema}}
+}
+
+// returns the validator as MongoDB Extended JSON (relaxed format)
+func ValidatorJson(validator bson.D) (string, error) {
+	ret, err := bson.MarshalExtJSONIndent(validator, false, false, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(ret) + "\n", nil
+}
+
+func PrintValidator(database string, collection string, validator bson.D, outputDir string) error {
+	validatorJson, err := ValidatorJson(validator)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the validator: %v", err)
+	}
+	return writeTextOutput(validatorJson, "validator.json", database, collection, outputDir)
+}
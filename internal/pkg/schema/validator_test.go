@@ -0,0 +1,122 @@
+package schema
+
+import (
+	"testing"
+
+	"okieoth/schemaguesser/internal/pkg/mongoHelper"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// returns the value of the key in the document
+func validatorTestValue(t *testing.T, doc bson.D, key string) any {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	require.Fail(t, "missing key", key)
+	return nil
+}
+
+func validatorTestProp(t *testing.T, objectSchema bson.D, name string) bson.D {
+	prop, ok := validatorTestValue(t, validatorTestValue(t, objectSchema, "properties").(bson.D), name).(bson.D)
+	require.True(t, ok)
+	return prop
+}
+
+func TestValidatorBsonType(t *testing.T) {
+	tests := []struct {
+		bsonType string
+		expected string
+	}{
+		{"embeddedDocument - unofficial type", "object"},
+		{mongoHelper.EMPTY_ARRAY_BSON_TYPE, "array"},
+		{"array type - unofficial type", "array"},
+		{"objectId", "objectId"},
+		{"decimal", "decimal"},
+		{"binData", "binData"},
+		{"date", "date"},
+		{"int", "int"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, validatorBsonType(test.bsonType), test.bsonType)
+	}
+
+	assert.Equal(t, "string", bsonTypeValue(false, "string"))
+	assert.Equal(t, bson.A{"string", "null"}, bsonTypeValue(true, "string"))
+	assert.Equal(t, bson.A{"string", "int", "null"}, bsonTypeValue(true, "string", "int"))
+	assert.Equal(t, "null", bsonTypeValue(true, "null"))
+}
+
+func TestCheckValidationOptions(t *testing.T) {
+	assert.Nil(t, CheckValidationOptions("strict", "error"))
+	assert.Nil(t, CheckValidationOptions("moderate", "warn"))
+	assert.NotNil(t, CheckValidationOptions("lax", "error"))
+	assert.NotNil(t, CheckValidationOptions("strict", "ignore"))
+}
+
+func TestNewValidator(t *testing.T) {
+	mainType, otherComplexTypes := goldenTestSchema(t)
+	validator := NewValidator(mainType, otherComplexTypes)
+	validatorJson, err := ValidatorJson(validator)
+	require.Nil(t, err)
+	assertGolden(t, "orders.validator.json", validatorJson)
+
+	jsonSchema := validatorTestValue(t, validator, "$jsonSchema").(bson.D)
+	assert.Equal(t, "object", validatorTestValue(t, jsonSchema, "bsonType"))
+	// 'note' is missing in one document, the attributes of the variants are checked in 'anyOf'
+	assert.Equal(t, bson.A{"_id", "type", "status", "customer", "amount", "created", "items", "position", "prices", "ref"},
+		validatorTestValue(t, jsonSchema, "required"))
+
+	assert.Equal(t, "objectId", validatorTestValue(t, validatorTestProp(t, jsonSchema, "_id"), "bsonType"))
+	assert.Equal(t, "decimal", validatorTestValue(t, validatorTestProp(t, jsonSchema, "amount"), "bsonType"))
+	assert.Equal(t, "date", validatorTestValue(t, validatorTestProp(t, jsonSchema, "created"), "bsonType"))
+	assert.Equal(t, bson.A{"string", "int"}, validatorTestValue(t, validatorTestProp(t, jsonSchema, "ref"), "bsonType"))
+
+	// nullable attributes allow null additionally
+	note := validatorTestProp(t, jsonSchema, "note")
+	assert.Equal(t, bson.A{"string", "null"}, validatorTestValue(t, note, "bsonType"))
+
+	status := validatorTestProp(t, jsonSchema, "status")
+	assert.Equal(t, "string", validatorTestValue(t, status, "bsonType"))
+	assert.Equal(t, bson.A{"closed", "open"}, validatorTestValue(t, status, "enum"))
+
+	// embedded types are contained with their own required attributes
+	customer := validatorTestProp(t, jsonSchema, "customer")
+	assert.Equal(t, "object", validatorTestValue(t, customer, "bsonType"))
+	assert.Equal(t, bson.A{"name", "email"}, validatorTestValue(t, customer, "required"))
+
+	prices := validatorTestProp(t, jsonSchema, "prices")
+	assert.Equal(t, bson.D{{Key: "bsonType", Value: "double"}}, validatorTestValue(t, prices, "additionalProperties"))
+
+	anyOf := validatorTestValue(t, jsonSchema, "anyOf").(bson.A)
+	require.Len(t, anyOf, 2)
+	online := anyOf[0].(bson.D)
+	assert.Equal(t, bson.D{{Key: "enum", Value: bson.A{"online"}}}, validatorTestProp(t, online, "type"))
+	assert.Equal(t, bson.A{"url"}, validatorTestValue(t, online, "required"))
+}
+
+func TestNewValidatorNullableTypes(t *testing.T) {
+	mainType, otherComplexTypes := guessTestSchema(t, "people", "", []bson.D{
+		{{Key: "address", Value: bson.D{{Key: "city", Value: "Berlin"}}}, {Key: "tags", Value: bson.A{"a"}}, {Key: "ratings", Value: bson.A{int32(1), 1.5}}},
+		{{Key: "address", Value: nil}, {Key: "tags", Value: nil}, {Key: "ratings", Value: nil}},
+		{{Key: "address", Value: bson.D{{Key: "city", Value: "Paris"}}}, {Key: "tags", Value: bson.A{"b"}}, {Key: "ratings", Value: bson.A{2.5}}},
+	})
+	jsonSchema := validatorTestValue(t, NewValidator(mainType, otherComplexTypes), "$jsonSchema").(bson.D)
+
+	address := validatorTestProp(t, jsonSchema, "address")
+	assert.Equal(t, bson.A{"object", "null"}, validatorTestValue(t, address, "bsonType"))
+	assert.Equal(t, bson.A{"city"}, validatorTestValue(t, address, "required"))
+
+	tags := validatorTestProp(t, jsonSchema, "tags")
+	assert.Equal(t, bson.A{"array", "null"}, validatorTestValue(t, tags, "bsonType"))
+	assert.Equal(t, bson.D{{Key: "bsonType", Value: "string"}}, validatorTestValue(t, tags, "items"))
+
+	// for arrays with different item types the null belongs to the array, not to the items
+	ratings := validatorTestProp(t, jsonSchema, "ratings")
+	assert.Equal(t, bson.A{"array", "null"}, validatorTestValue(t, ratings, "bsonType"))
+	assert.Equal(t, bson.D{{Key: "bsonType", Value: bson.A{"int", "double"}}}, validatorTestValue(t, ratings, "items"))
+}
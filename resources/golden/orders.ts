@@ -0,0 +1,47 @@
+// Code generated by schemaguesser. DO NOT EDIT.
+
+/** Storage model for database: shop, collection: orders */
+export interface Orders {
+  _id: string;
+  type: "online" | "store";
+  status: "closed" | "open";
+  customer: Customer;
+  amount: number;
+  created: string;
+  items: Items[];
+  position: [string, number];
+  prices: Prices;
+  note?: string | null;
+  ref: string | number;
+}
+
+export interface Customer {
+  name: string;
+  email: string;
+  "x-tag"?: string;
+}
+
+export interface Items {
+  sku: string;
+  qty: number;
+}
+
+export type Prices = Record<string, number>;
+
+export interface Store {
+  city: string;
+}
+
+/** Variant for type: online */
+export interface OrdersOnline extends Orders {
+  type: "online";
+  url: string;
+}
+
+/** Variant for type: store */
+export interface OrdersStore extends Orders {
+  type: "store";
+  store: Store;
+  /** Mongodb type binary: subtype=0 */
+  raw: string;
+}
@@ -0,0 +1,95 @@
+// Code generated by schemaguesser. DO NOT EDIT.
+
+import { z } from "zod";
+
+/** Storage model for database: shop, collection: orders */
+export interface Orders {
+  _id: string;
+  type: "online" | "store";
+  status: "closed" | "open";
+  customer: Customer;
+  amount: number;
+  created: string;
+  items: Items[];
+  position: [string, number];
+  prices: Prices;
+  note?: string | null;
+  ref: string | number;
+}
+
+export interface Customer {
+  name: string;
+  email: string;
+  "x-tag"?: string;
+}
+
+export interface Items {
+  sku: string;
+  qty: number;
+}
+
+export type Prices = Record<string, number>;
+
+export interface Store {
+  city: string;
+}
+
+/** Variant for type: online */
+export interface OrdersOnline extends Orders {
+  type: "online";
+  url: string;
+}
+
+/** Variant for type: store */
+export interface OrdersStore extends Orders {
+  type: "store";
+  store: Store;
+  /** Mongodb type binary: subtype=0 */
+  raw: string;
+}
+
+const OrdersShape = {
+  _id: z.string(),
+  type: z.enum(["online", "store"]),
+  status: z.enum(["closed", "open"]),
+  customer: z.lazy(() => CustomerSchema),
+  amount: z.number(),
+  created: z.string().datetime({ offset: true }),
+  items: z.array(z.lazy(() => ItemsSchema)),
+  position: z.tuple([z.string(), z.number().int()]),
+  prices: z.lazy(() => PricesSchema),
+  note: z.string().nullable().optional(),
+  ref: z.union([z.string(), z.number().int()]),
+};
+
+export const OrdersSchema: z.ZodType<Orders> = z.object(OrdersShape);
+
+export const CustomerSchema: z.ZodType<Customer> = z.object({
+  name: z.string(),
+  email: z.string().email(),
+  "x-tag": z.string().optional(),
+});
+
+export const ItemsSchema: z.ZodType<Items> = z.object({
+  sku: z.string(),
+  qty: z.number().int(),
+});
+
+export const PricesSchema: z.ZodType<Prices> = z.record(z.string(), z.number());
+
+export const StoreSchema: z.ZodType<Store> = z.object({
+  city: z.string(),
+});
+
+export const OrdersOnlineSchema: z.ZodType<OrdersOnline> = z.object({
+  ...OrdersShape,
+  type: z.literal("online"),
+  url: z.string().url(),
+});
+
+export const OrdersStoreSchema: z.ZodType<OrdersStore> = z.object({
+  ...OrdersShape,
+  type: z.literal("store"),
+  store: z.lazy(() => StoreSchema),
+  raw: z.string(),
+});